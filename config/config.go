@@ -1,24 +1,31 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
 	"github.com/ilyakaznacheev/cleanenv"
 	"time"
 )
 
 type Config struct {
-	HTTP   HTTP
-	Log    Log
-	PG     PG
-	Redis  Redis
-	JWT    JWT
-	Hasher Hasher
-	TestPG TestPG
+	HTTP     HTTP
+	Log      Log
+	PG       PG
+	Redis    Redis
+	JWT      JWT
+	Hasher   Hasher
+	Password Password
+	TOTP     TOTP
+	SignIn   SignIn
+	Mailer   Mailer
+	TestPG   TestPG
 }
 
 type (
 	HTTP struct {
 		Port string `env-required:"true" env:"HTTP_PORT"`
+		// PublicURL is external address of the api, used in links sent by email
+		PublicURL string `env-default:"http://localhost:8080" env:"PUBLIC_URL"`
 	}
 	Log struct {
 		Level  string `env-required:"true" env:"LOG_LEVEL"`
@@ -33,12 +40,40 @@ type (
 		MaxPoolSize int    `env-required:"true" env:"REDIS_MAX_POOL_SIZE"`
 	}
 	JWT struct {
-		SignKey  string        `env-required:"true" env:"JWT_SIGN_KEY"`
-		TokenTTL time.Duration `env-required:"true" env:"TOKEN_TTL"`
+		SignKey         string        `env-required:"true" env:"JWT_SIGN_KEY"`
+		TokenTTL        time.Duration `env-required:"true" env:"TOKEN_TTL"`
+		RefreshTokenTTL time.Duration `env-default:"720h" env:"REFRESH_TOKEN_TTL"`
+		EmailTokenTTL   time.Duration `env-default:"24h" env:"EMAIL_TOKEN_TTL"`
+		// Algorithm of access tokens: HS256 (JWT_SIGN_KEY), RS256 or EdDSA (keys in redis, published at /.well-known/jwks.json)
+		Algorithm   string        `env-default:"HS256" env:"JWT_ALGORITHM"`
+		KeyRotation time.Duration `env-default:"168h" env:"JWT_KEY_ROTATION"`
+		// KeyEncryptionKey is base64 of 32 bytes, it encrypts RS256 and EdDSA private keys stored in redis
+		KeyEncryptionKey string `env:"JWT_KEY_ENCRYPTION_KEY"`
 	}
 	Hasher struct {
 		Salt string `env-required:"true" env:"HASH_SALT"`
 	}
+	TOTP struct {
+		Issuer       string        `env-default:"API_for_SN" env:"TOTP_ISSUER"`
+		ChallengeTTL time.Duration `env-default:"5m" env:"TOTP_CHALLENGE_TTL"`
+	}
+	// SignIn lockout: after Max*Failures failed attempts within FailureWindow sign-in is locked for LockoutBase,
+	// each next failure doubles lock up to LockoutMax. Zero max failures disables the check
+	SignIn struct {
+		MaxUserFailures int           `env-default:"5" env:"SIGN_IN_MAX_USER_FAILURES"`
+		MaxIPFailures   int           `env-default:"20" env:"SIGN_IN_MAX_IP_FAILURES"`
+		FailureWindow   time.Duration `env-default:"15m" env:"SIGN_IN_FAILURE_WINDOW"`
+		LockoutBase     time.Duration `env-default:"1m" env:"SIGN_IN_LOCKOUT_BASE"`
+		LockoutMax      time.Duration `env-default:"1h" env:"SIGN_IN_LOCKOUT_MAX"`
+	}
+	Password struct {
+		ResetTokenTTL time.Duration `env-default:"15m" env:"PASSWORD_RESET_TTL"`
+	}
+	Mailer struct {
+		From string `env-default:"no-reply@localhost" env:"MAILER_FROM"`
+		// File for outgoing mail, stdout if empty
+		File string `env:"MAILER_FILE"`
+	}
 	TestPG struct {
 		Url string `env-required:"true" env:"TEST_PG_URL"`
 	}
@@ -49,5 +84,18 @@ func NewConfig() (*Config, error) {
 	if err := cleanenv.ReadEnv(c); err != nil {
 		return nil, fmt.Errorf("error reading config env: %w", err)
 	}
+	switch c.JWT.Algorithm {
+	case "HS256", "RS256", "EdDSA":
+	default:
+		return nil, fmt.Errorf("unsupported JWT_ALGORITHM %s", c.JWT.Algorithm)
+	}
+	if c.JWT.Algorithm != "HS256" && c.JWT.KeyRotation <= 0 {
+		return nil, fmt.Errorf("JWT_KEY_ROTATION must be positive")
+	}
+	if c.JWT.Algorithm != "HS256" {
+		if kek, err := base64.StdEncoding.DecodeString(c.JWT.KeyEncryptionKey); err != nil || len(kek) != 32 {
+			return nil, fmt.Errorf("JWT_KEY_ENCRYPTION_KEY must be base64 of 32 bytes")
+		}
+	}
 	return c, nil
 }
@@ -0,0 +1,187 @@
+package v1
+
+import (
+	"API_for_SN_go/internal/mocks/servicemocks"
+	"API_for_SN_go/internal/service"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthMiddleware_accessTokenScopes(t *testing.T) {
+	type MockBehaviour func(m *servicemocks.MockAuth)
+
+	patClaims := &service.TokenClaims{Username: "vasek", Role: service.RoleUser, AccessTokenId: "bot", Scopes: []string{"posts:read"}}
+	sessionClaims := &service.TokenClaims{Username: "vasek", Role: service.RoleUser, SessionId: "laptop"}
+
+	testCases := []struct {
+		testName      string
+		method        string
+		url           string
+		mockBehaviour MockBehaviour
+		expectCode    int
+		expectBody    string
+	}{
+		{
+			testName: "access token with scope",
+			method:   http.MethodGet,
+			url:      "/posts",
+			mockBehaviour: func(m *servicemocks.MockAuth) {
+				m.EXPECT().ValidateToken(gomock.Any(), "token").Return(patClaims, nil)
+			},
+			expectCode: 200,
+			expectBody: "",
+		},
+		{
+			testName: "access token without scope",
+			method:   http.MethodPost,
+			url:      "/posts",
+			mockBehaviour: func(m *servicemocks.MockAuth) {
+				m.EXPECT().ValidateToken(gomock.Any(), "token").Return(patClaims, nil)
+			},
+			expectCode: 403,
+			expectBody: `{"message":"access token has no scope posts:write"}` + "\n",
+		},
+		{
+			testName: "session token is not restricted",
+			method:   http.MethodPost,
+			url:      "/posts",
+			mockBehaviour: func(m *servicemocks.MockAuth) {
+				m.EXPECT().ValidateToken(gomock.Any(), "token").Return(sessionClaims, nil)
+			},
+			expectCode: 200,
+			expectBody: "",
+		},
+		{
+			testName: "access token for account management",
+			method:   http.MethodGet,
+			url:      "/account",
+			mockBehaviour: func(m *servicemocks.MockAuth) {
+				m.EXPECT().ValidateToken(gomock.Any(), "token").Return(patClaims, nil)
+			},
+			expectCode: 403,
+			expectBody: `{"message":"personal access token is not allowed here"}` + "\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			auth := servicemocks.NewMockAuth(ctrl)
+			tc.mockBehaviour(auth)
+			m := &AuthMiddleware{auth: auth}
+
+			ok := func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			}
+			e := echo.New()
+			posts := e.Group("/posts", m.AuthHandler, RequireScope("posts"))
+			posts.GET("", ok)
+			posts.POST("", ok)
+			e.GET("/account", ok, m.SessionHandler)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.url, nil)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer token")
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+			assert.Equal(t, tc.expectBody, w.Body.String())
+		})
+	}
+}
+
+func (s *APITestSuite) Test_authRouter_accessTokens() {
+	setup := setupApiTests(s)
+	defer tearDownApiTests(s, setup)
+
+	request := func(method, url, token, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodPost, "/auth/tokens", setup.token, `{"name": "bot", "scopes": ["posts:everything"]}`)
+	s.Assert().Equal(http.StatusBadRequest, w.Code)
+
+	w = request(http.MethodPost, "/auth/tokens", setup.token, `{"name": "bot", "scopes": ["posts:read"], "expires_in_days": 30}`)
+	s.Require().Equal(http.StatusCreated, w.Code)
+	var created struct {
+		TokenId string `json:"token_id"`
+		Token   string `json:"token"`
+	}
+	s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &created))
+	s.Assert().Regexp("^"+service.AccessTokenPrefix, created.Token)
+
+	listUrl := fmt.Sprintf("/api/v1/posts/post/list?username=%s", setup.username)
+	s.Assert().Equal(http.StatusOK, request(http.MethodGet, listUrl, created.Token, "").Code)
+	s.Assert().Equal(http.StatusForbidden, request(http.MethodPost, "/api/v1/posts/post/create", created.Token, `{"title": "t", "text": "t"}`).Code)
+	// токен не дает доступа к управлению аккаунтом и другим токенам
+	s.Assert().Equal(http.StatusForbidden, request(http.MethodGet, "/auth/tokens", created.Token, "").Code)
+
+	tokens, err := s.services.Auth.GetAccessTokens(context.Background(), setup.username)
+	s.Require().NoError(err)
+	s.Require().Len(tokens, 1)
+	s.Assert().Equal("bot", tokens[0].Name)
+	s.Assert().NotEqual(created.Token, tokens[0].TokenHash)
+
+	s.Assert().Equal(http.StatusOK, request(http.MethodDelete, "/auth/tokens/revoke", setup.token, fmt.Sprintf(`{"token_id": "%s"}`, created.TokenId)).Code)
+	s.Assert().Equal(http.StatusForbidden, request(http.MethodGet, listUrl, created.Token, "").Code)
+}
+
+func (s *APITestSuite) Test_authService_accessTokensRevokedWithCredentials() {
+	setup := setupApiTests(s)
+	defer tearDownApiTests(s, setup)
+
+	createToken := func() string {
+		token, _, err := s.services.Auth.CreateAccessToken(context.Background(), service.AccessTokenCreateInput{
+			Username: setup.username,
+			Name:     "bot",
+			Scopes:   []string{"posts:read"},
+		})
+		s.Require().NoError(err)
+		_, err = s.services.Auth.ValidateToken(context.Background(), token)
+		s.Require().NoError(err)
+		return token
+	}
+
+	// смена пароля отзывает токены доступа
+	token := createToken()
+	claims, err := s.services.Auth.ValidateToken(context.Background(), setup.token)
+	s.Require().NoError(err)
+	s.Require().NoError(s.services.Auth.ChangePassword(context.Background(), service.PasswordChangeInput{
+		Username:    setup.username,
+		SessionId:   claims.SessionId,
+		Password:    setup.password,
+		NewPassword: "4321",
+	}))
+	_, err = s.services.Auth.ValidateToken(context.Background(), token)
+	s.Assert().ErrorIs(err, service.ErrInvalidToken)
+	s.Require().NoError(s.services.Auth.ChangePassword(context.Background(), service.PasswordChangeInput{
+		Username:    setup.username,
+		SessionId:   claims.SessionId,
+		Password:    "4321",
+		NewPassword: setup.password,
+	}))
+
+	// как и принудительный выход
+	token = createToken()
+	s.Require().NoError(s.services.Admin.SignOutUser(context.Background(), service.AdminUserInput{Admin: "admin", Username: setup.username}))
+	_, err = s.services.Auth.ValidateToken(context.Background(), token)
+	s.Assert().ErrorIs(err, service.ErrInvalidToken)
+	tokens, err := s.services.Auth.GetAccessTokens(context.Background(), setup.username)
+	s.Assert().NoError(err)
+	s.Assert().Empty(tokens)
+}
@@ -0,0 +1,282 @@
+package v1
+
+import (
+	"API_for_SN_go/internal/model/pgmodel"
+	"API_for_SN_go/internal/service"
+	"context"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+type adminRouter struct {
+	userService  service.User
+	adminService service.Admin
+}
+
+func newAdminRouter(g *echo.Group, userService service.User, adminService service.Admin) {
+	r := &adminRouter{
+		userService:  userService,
+		adminService: adminService,
+	}
+	g.GET("/users", r.getUsers)
+	g.GET("/user", r.getUser)
+	g.PUT("/user/role", r.updateRole)
+	g.POST("/user/suspend", r.suspend)
+	g.POST("/user/unsuspend", r.unsuspend)
+	g.POST("/user/sign-out", r.signOut)
+	g.DELETE("/user/delete", r.deleteUser)
+}
+
+// adminUserResponse is full user record without password and two-factor secrets
+type adminUserResponse struct {
+	Id            int        `json:"id"`
+	Username      string     `json:"username"`
+	FirstName     string     `json:"first_name"`
+	LastName      string     `json:"last_name"`
+	Email         string     `json:"email"`
+	EmailVerified bool       `json:"email_verified"`
+	PendingEmail  *string    `json:"pending_email"`
+	Role          string     `json:"role"`
+	TOTPEnabled   bool       `json:"totp_enabled"`
+	SuspendedAt   *time.Time `json:"suspended_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func newAdminUserResponse(u pgmodel.User) adminUserResponse {
+	return adminUserResponse{
+		Id:            u.Id,
+		Username:      u.Username,
+		FirstName:     u.FirstName,
+		LastName:      u.LastName,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		PendingEmail:  u.PendingEmail,
+		Role:          u.Role,
+		TOTPEnabled:   u.TOTPEnabled,
+		SuspendedAt:   u.SuspendedAt,
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
+	}
+}
+
+// @Summary		List users
+// @Description	List users, oldest first by default. Query filters users by username, name or email. Available for admins
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Param			query	query		string	false	"part of username, name or email"
+// @Param			limit	query		int		false	"max number of users (default 20, max 100)"
+// @Param			cursor	query		string	false	"next_cursor from previous page"
+// @Param			sort	query		string	false	"oldest (default) or newest"
+// @Success		200		{object}	map[string]interface{}
+// @Failure		400		{object}	echo.HTTPError
+// @Failure		403		{object}	echo.HTTPError
+// @Failure		500		{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/api/v1/admin/users [get]
+func (r *adminRouter) getUsers(c echo.Context) error {
+	p, err := parsePagination(c)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	users, next, err := r.adminService.GetUsers(c.Request().Context(), c.QueryParam("query"), p)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	type response struct {
+		Users      []adminUserResponse `json:"users"`
+		NextCursor string              `json:"next_cursor"`
+	}
+	res := response{
+		Users:      make([]adminUserResponse, 0, len(users)),
+		NextCursor: next,
+	}
+	for _, u := range users {
+		res.Users = append(res.Users, newAdminUserResponse(u))
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// @Summary		Get user record
+// @Description	Get full user record. Available for admins
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Param			username	query		string	true	"username"
+// @Success		200			{object}	adminUserResponse
+// @Failure		400			{object}	echo.HTTPError
+// @Failure		403			{object}	echo.HTTPError
+// @Failure		500			{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/api/v1/admin/user [get]
+func (r *adminRouter) getUser(c echo.Context) error {
+	username := c.QueryParam("username")
+	if username == "" {
+		errorResponse(c, http.StatusBadRequest, "invalid request params")
+		return nil
+	}
+	user, err := r.adminService.GetUser(c.Request().Context(), username)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	return c.JSON(http.StatusOK, newAdminUserResponse(user))
+}
+
+type userUpdateRoleInput struct {
+	Username string `json:"username" validate:"required"`
+	Role     string `json:"role" validate:"required"`
+}
+
+// @Summary		Update user role
+// @Description	Set user role: user, moderator or admin. Available for admins. New role is applied to access tokens after refresh
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Param			input	body	userUpdateRoleInput	true	"input"
+// @Success		200
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		403	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/api/v1/admin/user/role [put]
+func (r *adminRouter) updateRole(c echo.Context) error {
+	var input userUpdateRoleInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+		return err
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	err := r.userService.UpdateRole(c.Request().Context(), service.UserUpdateRoleInput{
+		Username: input.Username,
+		Role:     input.Role,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRole) || errors.Is(err, service.ErrUserNotFound) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+type adminUserInput struct {
+	Username string `json:"username" validate:"required"`
+}
+
+// @Summary		Suspend user
+// @Description	Suspend user: all user sessions are revoked and sign-in is rejected until unsuspend. Available for admins
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Param			input	body	adminUserInput	true	"input"
+// @Success		200
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		403	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/api/v1/admin/user/suspend [post]
+func (r *adminRouter) suspend(c echo.Context) error {
+	return r.userAction(c, r.adminService.SuspendUser)
+}
+
+// @Summary		Unsuspend user
+// @Description	Lift user suspension. Available for admins
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Param			input	body	adminUserInput	true	"input"
+// @Success		200
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		403	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/api/v1/admin/user/unsuspend [post]
+func (r *adminRouter) unsuspend(c echo.Context) error {
+	return r.userAction(c, r.adminService.UnsuspendUser)
+}
+
+// @Summary		Sign out user
+// @Description	Revoke all user sessions. Available for admins
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Param			input	body	adminUserInput	true	"input"
+// @Success		200
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		403	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/api/v1/admin/user/sign-out [post]
+func (r *adminRouter) signOut(c echo.Context) error {
+	return r.userAction(c, r.adminService.SignOutUser)
+}
+
+// @Summary		Delete user
+// @Description	Delete user with all posts, comments and reactions without password confirmation. Available for admins
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Param			input	body	adminUserInput	true	"input"
+// @Success		200
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		403	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/api/v1/admin/user/delete [delete]
+func (r *adminRouter) deleteUser(c echo.Context) error {
+	return r.userAction(c, r.adminService.DeleteUser)
+}
+
+type adminUserActionFunc func(ctx context.Context, input service.AdminUserInput) error
+
+func (r *adminRouter) userAction(c echo.Context, action adminUserActionFunc) error {
+	var input adminUserInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+		return err
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	userCtx := c.Get(usernameCtx)
+	username, ok := userCtx.(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+	err := action(c.Request().Context(), service.AdminUserInput{
+		Admin:    username,
+		Username: input.Username,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) || errors.Is(err, service.ErrCannotManageSelf) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	return c.NoContent(http.StatusOK)
+}
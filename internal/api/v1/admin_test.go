@@ -0,0 +1,200 @@
+package v1
+
+import (
+	"API_for_SN_go/internal/mocks/servicemocks"
+	"API_for_SN_go/internal/service"
+	"API_for_SN_go/pkg/validator"
+	"bytes"
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminRouter_suspend(t *testing.T) {
+	type MockBehaviour func(m *servicemocks.MockAdmin)
+
+	testCases := []struct {
+		testName      string
+		inputBody     string
+		mockBehaviour MockBehaviour
+		expectCode    int
+		expectBody    string
+	}{
+		{
+			testName:  "correct test",
+			inputBody: `{"username": "petya"}`,
+			mockBehaviour: func(m *servicemocks.MockAdmin) {
+				m.EXPECT().SuspendUser(gomock.Any(), service.AdminUserInput{Admin: "vasek", Username: "petya"}).Return(nil)
+			},
+			expectCode: 200,
+			expectBody: "",
+		},
+		{
+			testName:      "empty username",
+			inputBody:     `{}`,
+			mockBehaviour: func(m *servicemocks.MockAdmin) {},
+			expectCode:    400,
+			expectBody:    `{"message":"field Username is invalid"}` + "\n",
+		},
+		{
+			testName:  "suspend self",
+			inputBody: `{"username": "vasek"}`,
+			mockBehaviour: func(m *servicemocks.MockAdmin) {
+				m.EXPECT().SuspendUser(gomock.Any(), service.AdminUserInput{Admin: "vasek", Username: "vasek"}).Return(service.ErrCannotManageSelf)
+			},
+			expectCode: 400,
+			expectBody: `{"message":"cannot apply this action to own account"}` + "\n",
+		},
+		{
+			testName:  "user not found",
+			inputBody: `{"username": "nobody"}`,
+			mockBehaviour: func(m *servicemocks.MockAdmin) {
+				m.EXPECT().SuspendUser(gomock.Any(), service.AdminUserInput{Admin: "vasek", Username: "nobody"}).Return(service.ErrUserNotFound)
+			},
+			expectCode: 400,
+			expectBody: `{"message":"user not found"}` + "\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			admin := servicemocks.NewMockAdmin(ctrl)
+			tc.mockBehaviour(admin)
+
+			e := echo.New()
+			var err error
+			e.Validator, err = validator.NewValidator()
+			if err != nil {
+				t.Fatal(err)
+			}
+			g := e.Group("/api/v1/admin", func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					c.Set(usernameCtx, "vasek")
+					return next(c)
+				}
+			})
+			newAdminRouter(g, nil, admin)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/user/suspend", bytes.NewBufferString(tc.inputBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+			assert.Equal(t, tc.expectBody, w.Body.String())
+		})
+	}
+}
+
+func (s *APITestSuite) Test_adminRouter_suspend() {
+	setup := setupApiTests(s)
+	defer tearDownApiTests(s, setup)
+
+	admin, password := "admin", "1234"
+	s.Require().NoError(s.services.Auth.CreateUser(context.Background(), service.UserCreateInput{
+		Username:  admin,
+		FirstName: "Admin",
+		LastName:  "Adminov",
+		Email:     "admin@gmail.com",
+		Password:  password,
+	}))
+	defer func() {
+		_ = s.services.Auth.DeleteUser(context.Background(), service.UserDeleteInput{Username: admin, Password: password})
+		_ = s.services.Auth.RevokeAllSessions(context.Background(), admin)
+	}()
+	s.Require().NoError(s.services.User.UpdateRole(context.Background(), service.UserUpdateRoleInput{
+		Username: admin,
+		Role:     service.RoleAdmin,
+	}))
+	tokens, err := s.services.Auth.CreateToken(context.Background(), service.UserAuthInput{
+		Username: admin,
+		Password: password,
+	})
+	s.Require().NoError(err)
+
+	adminRequest := func(method, url, body string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+tokens.AccessToken)
+		s.router.ServeHTTP(w, req)
+		return w.Code
+	}
+	userRequest := func(token string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/user?username="+setup.username, nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		s.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	s.Assert().Equal(http.StatusOK, adminRequest(http.MethodGet, "/api/v1/admin/users?query=vas", ""))
+	s.Assert().Equal(http.StatusOK, adminRequest(http.MethodPost, "/api/v1/admin/user/suspend", `{"username": "vasek"}`))
+
+	// токен заблокированного пользователя и вход отклоняются
+	s.Assert().Equal(http.StatusForbidden, userRequest(setup.token))
+	_, err = s.services.Auth.CreateToken(context.Background(), service.UserAuthInput{
+		Username: setup.username,
+		Password: setup.password,
+	})
+	s.Assert().ErrorIs(err, service.ErrUserSuspended)
+	user, err := s.services.Admin.GetUser(context.Background(), setup.username)
+	s.Require().NoError(err)
+	s.Assert().NotNil(user.SuspendedAt)
+
+	s.Assert().Equal(http.StatusOK, adminRequest(http.MethodPost, "/api/v1/admin/user/unsuspend", `{"username": "vasek"}`))
+	userTokens, err := s.services.Auth.CreateToken(context.Background(), service.UserAuthInput{
+		Username: setup.username,
+		Password: setup.password,
+	})
+	s.Require().NoError(err)
+	s.Assert().Equal(http.StatusOK, userRequest(userTokens.AccessToken))
+
+	s.Assert().Equal(http.StatusOK, adminRequest(http.MethodPost, "/api/v1/admin/user/sign-out", `{"username": "vasek"}`))
+	s.Assert().Equal(http.StatusForbidden, userRequest(userTokens.AccessToken))
+}
+
+func (s *APITestSuite) Test_adminService_suspensionMark() {
+	setup := setupApiTests(s)
+	defer tearDownApiTests(s, setup)
+
+	admin := service.AdminUserInput{Admin: "admin", Username: setup.username}
+	s.Require().NoError(s.services.Admin.SuspendUser(context.Background(), admin))
+
+	// метка переходит на новый username вместе с пользователем
+	s.Require().NoError(s.services.Auth.UpdateUsername(context.Background(), service.UpdateUsernameInput{
+		Username:    setup.username,
+		NewUsername: "vasek2",
+		Password:    setup.password,
+	}))
+	exists, err := s.redis.Pool.Get(context.Background(), "suspended:vasek2").Result()
+	s.Assert().NoError(err)
+	s.Assert().Equal("1", exists)
+	_, err = s.redis.Pool.Get(context.Background(), "suspended:"+setup.username).Result()
+	s.Assert().ErrorIs(err, goredis.Nil)
+
+	// после удаления метка не достается новому пользователю с тем же username
+	s.Require().NoError(s.services.Auth.DeleteUser(context.Background(), service.UserDeleteInput{Username: "vasek2", Password: setup.password}))
+	_, err = s.redis.Pool.Get(context.Background(), "suspended:vasek2").Result()
+	s.Assert().ErrorIs(err, goredis.Nil)
+
+	s.Require().NoError(s.services.Auth.CreateUser(context.Background(), service.UserCreateInput{
+		Username:  setup.username,
+		FirstName: "Vasya",
+		LastName:  "Pupkin",
+		Email:     "test",
+		Password:  setup.password,
+	}))
+	tokens, err := s.services.Auth.CreateToken(context.Background(), service.UserAuthInput{Username: setup.username, Password: setup.password})
+	s.Require().NoError(err)
+	_, err = s.services.Auth.ValidateToken(context.Background(), tokens.AccessToken)
+	s.Assert().NoError(err)
+}
@@ -1,22 +1,51 @@
 package v1
 
 import (
+	"API_for_SN_go/internal/model/pgmodel"
 	"API_for_SN_go/internal/service"
 	"errors"
 	"github.com/labstack/echo/v4"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 type authRouter struct {
 	authService service.Auth
 }
 
-func newAuthRouter(g *echo.Group, authService service.Auth) {
+func newAuthRouter(g *echo.Group, authService service.Auth, authMiddleware *AuthMiddleware) {
 	r := &authRouter{authService: authService}
 	g.POST("/sign-up", r.signUp)
 	g.POST("/sign-in", r.signIn)
+	g.POST("/sign-in/2fa", r.signInTwoFactor)
+	g.POST("/refresh", r.refresh)
 	g.DELETE("/user/delete", r.deleteUser)
 	g.PUT("/user/update/username", r.updateUsername)
+	g.PUT("/user/update/password", r.changePassword, authMiddleware.SessionHandler)
+	g.PUT("/user/update/email", r.changeEmail, authMiddleware.SessionHandler)
+	g.POST("/password/forgot", r.forgotPassword)
+	g.POST("/password/reset", r.resetPassword)
+	g.GET("/email/confirm", r.confirmEmail)
+	g.POST("/email/resend", r.resendEmailConfirmation, authMiddleware.SessionHandler)
+
+	g.POST("/sign-out", r.signOut, authMiddleware.SessionHandler)
+
+	twoFactor := g.Group("/2fa", authMiddleware.SessionHandler)
+	twoFactor.POST("/enroll", r.enrollTwoFactor)
+	twoFactor.POST("/confirm", r.confirmTwoFactor)
+	twoFactor.DELETE("/disable", r.disableTwoFactor)
+
+	sessions := g.Group("/sessions", authMiddleware.SessionHandler)
+	sessions.GET("", r.getSessions)
+	sessions.DELETE("/revoke", r.revokeSession)
+	sessions.DELETE("/revoke-all", r.revokeAllSessions)
+
+	tokens := g.Group("/tokens", authMiddleware.SessionHandler)
+	tokens.POST("", r.createAccessToken)
+	tokens.GET("", r.getAccessTokens)
+	tokens.DELETE("/revoke", r.revokeAccessToken)
 }
 
 type signUpInput struct {
@@ -71,13 +100,15 @@ type signInInput struct {
 }
 
 // @Summary		Sign in
-// @Description	Sign in
+// @Description	Sign in. If user has enabled two-factor auth, response contains only challenge_token for /auth/sign-in/2fa. Returns short-lived access token and refresh token. Each sign in starts new session, device is taken from User-Agent header
 // @Tags			auth
 // @Accept			json
 // @Produce		json
 // @Param			input	body		signInInput	true	"input"
-// @Success		200		{object} map[string]string
+// @Success		200		{object}	tokensResponse
 // @Failure		400		{object}	echo.HTTPError
+// @Failure		403		{object}	echo.HTTPError
+// @Failure		429		{object}	echo.HTTPError
 // @Failure		500		{object}	echo.HTTPError
 // @Router			/auth/sign-in [post]
 func (r *authRouter) signIn(c echo.Context) error {
@@ -87,23 +118,164 @@ func (r *authRouter) signIn(c echo.Context) error {
 		errorResponse(c, http.StatusBadRequest, "invalid request body")
 		return err
 	}
-	token, err := r.authService.CreateToken(c.Request().Context(), service.UserAuthInput{
+	tokens, err := r.authService.CreateToken(c.Request().Context(), service.UserAuthInput{
 		Username: input.Username,
 		Password: input.Password,
+		Device:   c.Request().UserAgent(),
+		IP:       c.RealIP(),
 	})
 	if err != nil {
 		if errors.Is(err, service.ErrCannotCreateToken) {
 			errorResponse(c, http.StatusInternalServerError, "internal server error")
 			return err
 		}
+		if lockedResponse(c, err) {
+			return err
+		}
 		errorResponse(c, http.StatusForbidden, err.Error())
 		return err
 	}
+	if tokens.ChallengeToken != "" {
+		return c.JSON(http.StatusOK, challengeResponse{ChallengeToken: tokens.ChallengeToken})
+	}
+	return c.JSON(http.StatusOK, newTokensResponse(tokens))
+}
 
-	type response struct {
-		Token string `json:"token"`
+// lockedResponse sends 429 with Retry-After header if sign-in is locked
+func lockedResponse(c echo.Context, err error) bool {
+	var lockedErr *service.LockedError
+	if !errors.As(err, &lockedErr) {
+		return false
+	}
+	retryAfter := int(math.Ceil(time.Until(lockedErr.Until).Seconds()))
+	c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	errorResponse(c, http.StatusTooManyRequests, err.Error())
+	return true
+}
+
+type challengeResponse struct {
+	ChallengeToken string `json:"challenge_token"`
+}
+
+type signInTwoFactorInput struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+// @Summary		Sign in second step
+// @Description	Finish sign-in with authenticator code or one of recovery codes
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			input	body		signInTwoFactorInput	true	"input"
+// @Success		200		{object}	tokensResponse
+// @Failure		400		{object}	echo.HTTPError
+// @Failure		403		{object}	echo.HTTPError
+// @Failure		429		{object}	echo.HTTPError
+// @Failure		500		{object}	echo.HTTPError
+// @Router			/auth/sign-in/2fa [post]
+func (r *authRouter) signInTwoFactor(c echo.Context) error {
+	var input signInTwoFactorInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+		return err
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	tokens, err := r.authService.VerifyTOTP(c.Request().Context(), service.TOTPVerifyInput{
+		ChallengeToken: input.ChallengeToken,
+		Code:           input.Code,
+		Device:         c.Request().UserAgent(),
+		IP:             c.RealIP(),
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidToken) || errors.Is(err, service.ErrInvalidTOTPCode) || errors.Is(err, service.ErrTooManyTOTPAttempts) ||
+			errors.Is(err, service.ErrUserSuspended) {
+			errorResponse(c, http.StatusForbidden, err.Error())
+			return err
+		}
+		if lockedResponse(c, err) {
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	return c.JSON(http.StatusOK, newTokensResponse(tokens))
+}
+
+// @Summary		Sign out
+// @Description	End current session. Its access and refresh tokens stop working immediately
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Success		200
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/auth/sign-out [post]
+func (r *authRouter) signOut(c echo.Context) error {
+	// заголовок уже проверен SessionHandler
+	token, _ := parseToken(c.Request())
+	err := r.authService.SignOut(c.Request().Context(), token)
+	// сессия могла быть завершена параллельным запросом - результат тот же
+	if err != nil && !errors.Is(err, service.ErrExpiredToken) {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+type tokensResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func newTokensResponse(tokens service.Tokens) tokensResponse {
+	return tokensResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}
+}
+
+type refreshInput struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// @Summary		Refresh tokens
+// @Description	Exchange refresh token for new access and refresh tokens. Refresh token can be used only once, reuse revokes the session
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			input	body		refreshInput	true	"input"
+// @Success		200		{object}	tokensResponse
+// @Failure		400		{object}	echo.HTTPError
+// @Failure		403		{object}	echo.HTTPError
+// @Failure		500		{object}	echo.HTTPError
+// @Router			/auth/refresh [post]
+func (r *authRouter) refresh(c echo.Context) error {
+	var input refreshInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+		return err
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	tokens, err := r.authService.RefreshToken(c.Request().Context(), input.RefreshToken)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidToken) || errors.Is(err, service.ErrExpiredToken) || errors.Is(err, service.ErrRefreshTokenReused) ||
+			errors.Is(err, service.ErrUserSuspended) {
+			errorResponse(c, http.StatusForbidden, err.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
 	}
-	return c.JSON(http.StatusOK, response{Token: token})
+	return c.JSON(http.StatusOK, newTokensResponse(tokens))
 }
 
 // @Summary		Delete user
@@ -115,6 +287,7 @@ func (r *authRouter) signIn(c echo.Context) error {
 // @Success		200
 // @Failure		400	{object}	echo.HTTPError
 // @Failure		403	{object}	echo.HTTPError
+// @Failure		429	{object}	echo.HTTPError
 // @Failure		500	{object}	echo.HTTPError
 // @Router			/auth/user/delete [delete]
 func (r *authRouter) deleteUser(c echo.Context) error {
@@ -128,6 +301,7 @@ func (r *authRouter) deleteUser(c echo.Context) error {
 	if err := r.authService.DeleteUser(c.Request().Context(), service.UserDeleteInput{
 		Username: input.Username,
 		Password: input.Password,
+		IP:       c.RealIP(),
 	}); err != nil {
 		if errors.Is(err, service.ErrUserNotFound) {
 			errorResponse(c, http.StatusBadRequest, err.Error())
@@ -137,6 +311,9 @@ func (r *authRouter) deleteUser(c echo.Context) error {
 			errorResponse(c, http.StatusForbidden, err.Error())
 			return err
 		}
+		if lockedResponse(c, err) {
+			return err
+		}
 		errorResponse(c, http.StatusInternalServerError, err.Error())
 		return err
 	}
@@ -158,6 +335,7 @@ type updateUsernameInput struct {
 // @Success		200
 // @Response		400	{object}	echo.HTTPError
 // @Failure		403	{object}	echo.HTTPError
+// @Failure		429	{object}	echo.HTTPError
 // @Failure		500	{object}	echo.HTTPError
 // @Router			/user/update/username [put]
 func (r *authRouter) updateUsername(c echo.Context) error {
@@ -175,6 +353,7 @@ func (r *authRouter) updateUsername(c echo.Context) error {
 		Username:    input.Username,
 		NewUsername: input.NewUsername,
 		Password:    input.Password,
+		IP:          c.RealIP(),
 	}); err != nil {
 		if errors.Is(err, service.ErrUserNotFound) {
 			errorResponse(c, http.StatusBadRequest, err.Error())
@@ -184,8 +363,685 @@ func (r *authRouter) updateUsername(c echo.Context) error {
 			errorResponse(c, http.StatusForbidden, err.Error())
 			return err
 		}
+		if lockedResponse(c, err) {
+			return err
+		}
 		errorResponse(c, http.StatusInternalServerError, err.Error())
 		return err
 	}
 	return c.NoContent(http.StatusOK)
 }
+
+type changePasswordInput struct {
+	Password    string `json:"password" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
+}
+
+// @Summary		Change password
+// @Description	Change current user password. All other sessions are revoked
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			input	body	changePasswordInput	true	"input"
+// @Success		200
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		403	{object}	echo.HTTPError
+// @Failure		429	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/auth/user/update/password [put]
+func (r *authRouter) changePassword(c echo.Context) error {
+	var input changePasswordInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+		return err
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	userCtx := c.Get(usernameCtx)
+	username, ok := userCtx.(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+	sessionId, _ := c.Get(sessionIdCtx).(string)
+
+	err := r.authService.ChangePassword(c.Request().Context(), service.PasswordChangeInput{
+		Username:    username,
+		SessionId:   sessionId,
+		Password:    input.Password,
+		NewPassword: input.NewPassword,
+		IP:          c.RealIP(),
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		if errors.Is(err, service.ErrIncorrectPassword) {
+			errorResponse(c, http.StatusForbidden, err.Error())
+			return err
+		}
+		if lockedResponse(c, err) {
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+type changeEmailInput struct {
+	Password string `json:"password" validate:"required"`
+	NewEmail string `json:"new_email" validate:"required,email"`
+}
+
+// @Summary		Change email
+// @Description	Request current user email change. New email replaces the current one after confirmation by link sent to it
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			input	body	changeEmailInput	true	"input"
+// @Success		202
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		403	{object}	echo.HTTPError
+// @Failure		429	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/auth/user/update/email [put]
+func (r *authRouter) changeEmail(c echo.Context) error {
+	var input changeEmailInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+		return err
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	userCtx := c.Get(usernameCtx)
+	username, ok := userCtx.(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+
+	err := r.authService.ChangeEmail(c.Request().Context(), service.EmailChangeInput{
+		Username: username,
+		Password: input.Password,
+		NewEmail: input.NewEmail,
+		IP:       c.RealIP(),
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) || errors.Is(err, service.ErrEmailAlreadyTaken) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		if errors.Is(err, service.ErrIncorrectPassword) {
+			errorResponse(c, http.StatusForbidden, err.Error())
+			return err
+		}
+		if lockedResponse(c, err) {
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	return c.NoContent(http.StatusAccepted)
+}
+
+type forgotPasswordInput struct {
+	Email string `json:"email" validate:"required"`
+}
+
+// @Summary		Forgot password
+// @Description	Send password reset token to user email. Response does not depend on whether the email is registered
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			input	body	forgotPasswordInput	true	"input"
+// @Success		202
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Router			/auth/password/forgot [post]
+func (r *authRouter) forgotPassword(c echo.Context) error {
+	var input forgotPasswordInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+		return err
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	if err := r.authService.RequestPasswordReset(c.Request().Context(), input.Email); err != nil {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	return c.NoContent(http.StatusAccepted)
+}
+
+type resetPasswordInput struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
+}
+
+// @Summary		Reset password
+// @Description	Set new password by reset token from email. Token is single-use, all user sessions are revoked
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			input	body	resetPasswordInput	true	"input"
+// @Success		200
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Router			/auth/password/reset [post]
+func (r *authRouter) resetPassword(c echo.Context) error {
+	var input resetPasswordInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+		return err
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	err := r.authService.ResetPassword(c.Request().Context(), service.PasswordResetInput{
+		Token:       input.Token,
+		NewPassword: input.NewPassword,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidToken) || errors.Is(err, service.ErrUserNotFound) {
+			errorResponse(c, http.StatusBadRequest, service.ErrInvalidToken.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// @Summary		Confirm email
+// @Description	Confirm user email by token from confirmation link
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			token	query	string	true	"confirmation token"
+// @Success		200
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Router			/auth/email/confirm [get]
+func (r *authRouter) confirmEmail(c echo.Context) error {
+	token := c.QueryParam("token")
+	if len(token) == 0 {
+		errorResponse(c, http.StatusBadRequest, "invalid request params")
+		return nil
+	}
+	if err := r.authService.ConfirmEmail(c.Request().Context(), token); err != nil {
+		if errors.Is(err, service.ErrInvalidToken) || errors.Is(err, service.ErrEmailAlreadyTaken) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// @Summary		Resend email confirmation
+// @Description	Send new confirmation link to current user email
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Success		202
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/auth/email/resend [post]
+func (r *authRouter) resendEmailConfirmation(c echo.Context) error {
+	userCtx := c.Get(usernameCtx)
+	username, ok := userCtx.(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+	if err := r.authService.SendEmailConfirmation(c.Request().Context(), username); err != nil {
+		if errors.Is(err, service.ErrEmailAlreadyVerified) || errors.Is(err, service.ErrUserNotFound) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	return c.NoContent(http.StatusAccepted)
+}
+
+// @Summary		Enroll two-factor auth
+// @Description	Generate two-factor secret and recovery codes. Two-factor auth is enabled after confirmation with authenticator code
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Success		200	{object}	map[string]interface{}
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/auth/2fa/enroll [post]
+func (r *authRouter) enrollTwoFactor(c echo.Context) error {
+	userCtx := c.Get(usernameCtx)
+	username, ok := userCtx.(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+	enrollment, err := r.authService.EnrollTOTP(c.Request().Context(), username)
+	if err != nil {
+		if errors.Is(err, service.ErrTOTPAlreadyEnabled) || errors.Is(err, service.ErrUserNotFound) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	type response struct {
+		Secret        string   `json:"secret"`
+		URI           string   `json:"otpauth_uri"`
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	return c.JSON(http.StatusOK, response{
+		Secret:        enrollment.Secret,
+		URI:           enrollment.URI,
+		RecoveryCodes: enrollment.RecoveryCodes,
+	})
+}
+
+type confirmTwoFactorInput struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// @Summary		Confirm two-factor auth
+// @Description	Enable two-factor auth with current authenticator code
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			input	body	confirmTwoFactorInput	true	"input"
+// @Success		200
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/auth/2fa/confirm [post]
+func (r *authRouter) confirmTwoFactor(c echo.Context) error {
+	var input confirmTwoFactorInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+		return err
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	userCtx := c.Get(usernameCtx)
+	username, ok := userCtx.(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+	err := r.authService.ConfirmTOTP(c.Request().Context(), service.TOTPConfirmInput{
+		Username: username,
+		Code:     input.Code,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTOTPCode) || errors.Is(err, service.ErrTOTPNotEnrolled) ||
+			errors.Is(err, service.ErrTOTPAlreadyEnabled) || errors.Is(err, service.ErrUserNotFound) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+type disableTwoFactorInput struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// @Summary		Disable two-factor auth
+// @Description	Disable two-factor auth of current user, requires password
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			input	body	disableTwoFactorInput	true	"input"
+// @Success		200
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		403	{object}	echo.HTTPError
+// @Failure		429	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/auth/2fa/disable [delete]
+func (r *authRouter) disableTwoFactor(c echo.Context) error {
+	var input disableTwoFactorInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+		return err
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	userCtx := c.Get(usernameCtx)
+	username, ok := userCtx.(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+	err := r.authService.DisableTOTP(c.Request().Context(), service.TOTPDisableInput{
+		Username: username,
+		Password: input.Password,
+		IP:       c.RealIP(),
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrTOTPNotEnabled) || errors.Is(err, service.ErrUserNotFound) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		if errors.Is(err, service.ErrIncorrectPassword) {
+			errorResponse(c, http.StatusForbidden, err.Error())
+			return err
+		}
+		if lockedResponse(c, err) {
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// @Summary		Get sessions
+// @Description	Get active sessions of current user, newest first
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Success		200	{object}	map[string]interface{}
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/auth/sessions [get]
+func (r *authRouter) getSessions(c echo.Context) error {
+	userCtx := c.Get(usernameCtx)
+	username, ok := userCtx.(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+	sessionId, _ := c.Get(sessionIdCtx).(string)
+
+	sessions, err := r.authService.GetSessions(c.Request().Context(), username)
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	type sessionResponse struct {
+		SessionId  string    `json:"session_id"`
+		Device     string    `json:"device"`
+		IP         string    `json:"ip"`
+		CreatedAt  time.Time `json:"created_at"`
+		LastSeenAt time.Time `json:"last_seen_at"`
+		Current    bool      `json:"current"`
+	}
+	type response struct {
+		Sessions []sessionResponse `json:"sessions"`
+	}
+	res := response{Sessions: make([]sessionResponse, 0, len(sessions))}
+	for _, session := range sessions {
+		res.Sessions = append(res.Sessions, sessionResponse{
+			SessionId:  session.Id,
+			Device:     session.Device,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Current:    session.Id == sessionId,
+		})
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+type revokeSessionInput struct {
+	SessionId string `json:"session_id" validate:"required"`
+}
+
+// @Summary		Revoke session
+// @Description	Revoke one of current user sessions, its token stops working immediately
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			input	body	revokeSessionInput	true	"input"
+// @Success		200
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/auth/sessions/revoke [delete]
+func (r *authRouter) revokeSession(c echo.Context) error {
+	var input revokeSessionInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+		return err
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	userCtx := c.Get(usernameCtx)
+	username, ok := userCtx.(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+
+	err := r.authService.RevokeSession(c.Request().Context(), service.SessionRevokeInput{
+		Username:  username,
+		SessionId: input.SessionId,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// @Summary		Revoke all sessions
+// @Description	Revoke all current user sessions including current one
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Success		200
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/auth/sessions/revoke-all [delete]
+func (r *authRouter) revokeAllSessions(c echo.Context) error {
+	userCtx := c.Get(usernameCtx)
+	username, ok := userCtx.(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+
+	if err := r.authService.RevokeAllSessions(c.Request().Context(), username); err != nil {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+type createAccessTokenInput struct {
+	Name   string   `json:"name" validate:"required"`
+	Scopes []string `json:"scopes" validate:"required"`
+	// ExpiresInDays is token lifetime, 0 - token does not expire
+	ExpiresInDays int `json:"expires_in_days" validate:"min=0"`
+}
+
+type accessTokenResponse struct {
+	TokenId    string     `json:"token_id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newAccessTokenResponse(t pgmodel.AccessToken) accessTokenResponse {
+	return accessTokenResponse{
+		TokenId:    t.TokenId,
+		Name:       t.Name,
+		Scopes:     t.Scopes,
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+		CreatedAt:  t.CreatedAt,
+	}
+}
+
+// @Summary		Create personal access token
+// @Description	Create named personal access token for scripts and integrations. Scopes are "<resource>:<read|write>",
+// @Description	resources: posts, comments, reactions, users, feed, moderation, admin. Token is shown only once
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			input	body		createAccessTokenInput	true	"input"
+// @Success		201		{object}	map[string]interface{}
+// @Failure		400		{object}	echo.HTTPError
+// @Failure		500		{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/auth/tokens [post]
+func (r *authRouter) createAccessToken(c echo.Context) error {
+	var input createAccessTokenInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+		return err
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	userCtx := c.Get(usernameCtx)
+	username, ok := userCtx.(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+
+	token, info, err := r.authService.CreateAccessToken(c.Request().Context(), service.AccessTokenCreateInput{
+		Username: username,
+		Name:     input.Name,
+		Scopes:   input.Scopes,
+		TTL:      time.Duration(input.ExpiresInDays) * 24 * time.Hour,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidScope) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	type response struct {
+		accessTokenResponse
+		Token string `json:"token"`
+	}
+	return c.JSON(http.StatusCreated, response{
+		accessTokenResponse: newAccessTokenResponse(info),
+		Token:               token,
+	})
+}
+
+// @Summary		Get personal access tokens
+// @Description	Get personal access tokens of current user, newest first. Tokens themselves are not returned
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Success		200	{object}	map[string]interface{}
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/auth/tokens [get]
+func (r *authRouter) getAccessTokens(c echo.Context) error {
+	userCtx := c.Get(usernameCtx)
+	username, ok := userCtx.(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+
+	tokens, err := r.authService.GetAccessTokens(c.Request().Context(), username)
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	type response struct {
+		Tokens []accessTokenResponse `json:"tokens"`
+	}
+	res := response{Tokens: make([]accessTokenResponse, 0, len(tokens))}
+	for _, t := range tokens {
+		res.Tokens = append(res.Tokens, newAccessTokenResponse(t))
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+type revokeAccessTokenInput struct {
+	TokenId string `json:"token_id" validate:"required"`
+}
+
+// @Summary		Revoke personal access token
+// @Description	Revoke personal access token of current user, it stops working immediately
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			input	body	revokeAccessTokenInput	true	"input"
+// @Success		200
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/auth/tokens/revoke [delete]
+func (r *authRouter) revokeAccessToken(c echo.Context) error {
+	var input revokeAccessTokenInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+		return err
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	userCtx := c.Get(usernameCtx)
+	username, ok := userCtx.(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+
+	err := r.authService.RevokeAccessToken(c.Request().Context(), service.AccessTokenRevokeInput{
+		Username: username,
+		TokenId:  input.TokenId,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrAccessTokenNotFound) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	return c.NoContent(http.StatusOK)
+}
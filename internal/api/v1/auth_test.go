@@ -4,15 +4,24 @@ import (
 	"API_for_SN_go/internal/mocks/servicemocks"
 	"API_for_SN_go/internal/model/pgmodel"
 	"API_for_SN_go/internal/service"
+	"API_for_SN_go/pkg/hasher"
+	"API_for_SN_go/pkg/totp"
 	"API_for_SN_go/pkg/validator"
 	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/golang/mock/gomock"
 	"github.com/labstack/echo/v4"
+	goredis "github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestAuthRouter_signUp(t *testing.T) {
@@ -60,10 +69,10 @@ func TestAuthRouter_signUp(t *testing.T) {
 		{
 			testName:      "incorrect email",
 			args:          args{ctx: context.Background()},
-			inputBody:     `{"username": "vasek", "first_name": "Vasya", "last_name": "Pupkin", "email": "asdasda3124p9-09as-d@gmail.com", "password": "1234"}`,
+			inputBody:     `{"username": "vasek", "first_name": "Vasya", "last_name": "Pupkin", "email": "vasiliy@gmail", "password": "1234"}`,
 			mockBehaviour: func(m *servicemocks.MockAuth, args args) {},
 			expectCode:    400,
-			expectBody:    `{"message":"field email is incorrect. Make sure that you entered the email correctly and it exists"}` + "\n",
+			expectBody:    `{"message":"field email is incorrect. Make sure that you entered the email correctly"}` + "\n",
 		},
 		{
 			testName:      "too long username",
@@ -93,7 +102,7 @@ func TestAuthRouter_signUp(t *testing.T) {
 
 			e := echo.New()
 			e.Validator, _ = validator.NewValidator()
-			newAuthRouter(e.Group("/auth"), services.Auth)
+			newAuthRouter(e.Group("/auth"), services.Auth, &AuthMiddleware{auth: services.Auth})
 
 			// create request
 			w := httptest.NewRecorder()
@@ -163,7 +172,13 @@ func (s *APITestSuite) Test_authRouter_signUp() {
 			}
 			tc.input.Id = u.Id
 			tc.input.Password = u.Password
+			tc.input.CreatedAt = u.CreatedAt
+			tc.input.UpdatedAt = u.UpdatedAt
 			s.Assert().Equal(tc.input, u)
+
+			// письмо с подтверждением отправлено, почта еще не подтверждена
+			_, ok := s.mailer.Last(tc.input.Email)
+			s.Assert().True(ok)
 		}
 	}
 }
@@ -206,11 +221,930 @@ func (s *APITestSuite) Test_authRouter_signIn() {
 		s.Assert().Equal(tc.expectCode, w.Code)
 
 		if tc.expectCode == 200 {
-			token, err := s.redis.Pool.Get(context.Background(), "jwt:vasek").Result()
+			var response struct {
+				Token string `json:"token"`
+			}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+			claims, err := s.services.Auth.ValidateToken(context.Background(), response.Token)
 			s.Assert().Equal(nil, err)
-			s.Assert().NotEqual("", token)
+			s.Assert().Equal("vasek", claims.Username)
+			sessions, err := s.services.Auth.GetSessions(context.Background(), "vasek")
+			s.Assert().Equal(nil, err)
+			s.Assert().Len(sessions, 1)
 		} else {
 			s.Assert().Equal(tc.expectBody, w.Body.String())
 		}
 	}
 }
+
+func (s *APITestSuite) Test_authRouter_signInRehash() {
+	setup := setupApiTests(s)
+	defer tearDownApiTests(s, setup)
+
+	// пароль, сохраненный до перехода на argon2
+	legacy, err := hasher.NewHasher("secret").Hash(setup.password)
+	s.Require().NoError(err)
+	s.Require().NoError(s.repositories.User.UpdatePassword(context.Background(), setup.username, legacy))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/sign-in", bytes.NewBufferString(`{"username": "`+setup.username+`", "password": "`+setup.password+`"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	s.router.ServeHTTP(w, req)
+	s.Assert().Equal(http.StatusOK, w.Code)
+
+	user, err := s.repositories.User.GetUserByUsername(context.Background(), setup.username)
+	s.Require().NoError(err)
+	s.Assert().True(strings.HasPrefix(user.Password, "$argon2id$"))
+	s.Assert().True(hasher.NewArgon2Hasher("secret").Verify(setup.password, user.Password))
+}
+
+func TestAuthRouter_signOut(t *testing.T) {
+	type args struct {
+		ctx   context.Context
+		token string
+	}
+	type MockBehaviour func(m *servicemocks.MockAuth, args args)
+
+	testCases := []struct {
+		testName      string
+		args          args
+		mockBehaviour MockBehaviour
+		expectCode    int
+		expectBody    string
+	}{
+		{
+			testName: "correct test",
+			args:     args{ctx: context.Background(), token: "token"},
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().ValidateToken(args.ctx, args.token).Return(&service.TokenClaims{Username: "vasek", SessionId: "laptop"}, nil)
+				m.EXPECT().SignOut(args.ctx, args.token).Return(nil)
+			},
+			expectCode: 200,
+			expectBody: "",
+		},
+		{
+			testName: "session ended concurrently",
+			args:     args{ctx: context.Background(), token: "token"},
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().ValidateToken(args.ctx, args.token).Return(&service.TokenClaims{Username: "vasek", SessionId: "laptop"}, nil)
+				m.EXPECT().SignOut(args.ctx, args.token).Return(service.ErrExpiredToken)
+			},
+			expectCode: 200,
+			expectBody: "",
+		},
+		{
+			testName: "cannot revoke session",
+			args:     args{ctx: context.Background(), token: "token"},
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().ValidateToken(args.ctx, args.token).Return(&service.TokenClaims{Username: "vasek", SessionId: "laptop"}, nil)
+				m.EXPECT().SignOut(args.ctx, args.token).Return(service.ErrCannotRevokeSession)
+			},
+			expectCode: 500,
+			expectBody: `{"message":"internal server error"}` + "\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			auth := servicemocks.NewMockAuth(ctrl)
+			tc.mockBehaviour(auth, tc.args)
+			services := &service.Services{Auth: auth}
+
+			e := echo.New()
+			e.Validator, _ = validator.NewValidator()
+			newAuthRouter(e.Group("/auth"), services.Auth, &AuthMiddleware{auth: services.Auth})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/auth/sign-out", nil)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+tc.args.token)
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+			assert.Equal(t, tc.expectBody, w.Body.String())
+		})
+	}
+}
+
+func TestAuthRouter_revokeSession(t *testing.T) {
+	type args struct {
+		ctx   context.Context
+		token string
+		input service.SessionRevokeInput
+	}
+	type MockBehaviour func(m *servicemocks.MockAuth, args args)
+
+	testCases := []struct {
+		testName      string
+		args          args
+		inputBody     string
+		mockBehaviour MockBehaviour
+		expectCode    int
+		expectBody    string
+	}{
+		{
+			testName: "correct test",
+			args: args{
+				ctx:   context.Background(),
+				token: "token",
+				input: service.SessionRevokeInput{Username: "vasek", SessionId: "phone"},
+			},
+			inputBody: `{"session_id": "phone"}`,
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().ValidateToken(args.ctx, args.token).Return(&service.TokenClaims{Username: "vasek", SessionId: "laptop"}, nil)
+				m.EXPECT().RevokeSession(args.ctx, args.input).Return(nil)
+			},
+			expectCode: 200,
+			expectBody: "",
+		},
+		{
+			testName: "session not found",
+			args: args{
+				ctx:   context.Background(),
+				token: "token",
+				input: service.SessionRevokeInput{Username: "vasek", SessionId: "other"},
+			},
+			inputBody: `{"session_id": "other"}`,
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().ValidateToken(args.ctx, args.token).Return(&service.TokenClaims{Username: "vasek", SessionId: "laptop"}, nil)
+				m.EXPECT().RevokeSession(args.ctx, args.input).Return(service.ErrSessionNotFound)
+			},
+			expectCode: 400,
+			expectBody: `{"message":"session not found"}` + "\n",
+		},
+		{
+			testName:  "revoked token",
+			args:      args{ctx: context.Background(), token: "token"},
+			inputBody: `{"session_id": "phone"}`,
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().ValidateToken(args.ctx, args.token).Return(nil, service.ErrExpiredToken)
+			},
+			expectCode: 403,
+			expectBody: `{"message":"expired token"}` + "\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			auth := servicemocks.NewMockAuth(ctrl)
+			tc.mockBehaviour(auth, tc.args)
+			services := &service.Services{Auth: auth}
+
+			e := echo.New()
+			e.Validator, _ = validator.NewValidator()
+			newAuthRouter(e.Group("/auth"), services.Auth, &AuthMiddleware{auth: services.Auth})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodDelete, "/auth/sessions/revoke", bytes.NewBufferString(tc.inputBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+tc.args.token)
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+			assert.Equal(t, tc.expectBody, w.Body.String())
+		})
+	}
+}
+
+func TestAuthRouter_refresh(t *testing.T) {
+	type args struct {
+		ctx          context.Context
+		refreshToken string
+	}
+	type MockBehaviour func(m *servicemocks.MockAuth, args args)
+
+	testCases := []struct {
+		testName      string
+		args          args
+		inputBody     string
+		mockBehaviour MockBehaviour
+		expectCode    int
+		expectBody    string
+	}{
+		{
+			testName:  "correct test",
+			args:      args{ctx: context.Background(), refreshToken: "refresh"},
+			inputBody: `{"refresh_token": "refresh"}`,
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().RefreshToken(args.ctx, args.refreshToken).Return(service.Tokens{AccessToken: "access", RefreshToken: "new refresh"}, nil)
+			},
+			expectCode: 200,
+			expectBody: `{"token":"access","refresh_token":"new refresh"}` + "\n",
+		},
+		{
+			testName:  "reused token",
+			args:      args{ctx: context.Background(), refreshToken: "refresh"},
+			inputBody: `{"refresh_token": "refresh"}`,
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().RefreshToken(args.ctx, args.refreshToken).Return(service.Tokens{}, service.ErrRefreshTokenReused)
+			},
+			expectCode: 403,
+			expectBody: `{"message":"refresh token already used, session revoked"}` + "\n",
+		},
+		{
+			testName:      "without token",
+			inputBody:     `{}`,
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {},
+			expectCode:    400,
+			expectBody:    `{"message":"field RefreshToken is invalid"}` + "\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			auth := servicemocks.NewMockAuth(ctrl)
+			tc.mockBehaviour(auth, tc.args)
+			services := &service.Services{Auth: auth}
+
+			e := echo.New()
+			e.Validator, _ = validator.NewValidator()
+			newAuthRouter(e.Group("/auth"), services.Auth, &AuthMiddleware{auth: services.Auth})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/auth/refresh", bytes.NewBufferString(tc.inputBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+			assert.Equal(t, tc.expectBody, w.Body.String())
+		})
+	}
+}
+
+func (s *APITestSuite) Test_authRouter_refresh() {
+	setup := setupApiTests(s)
+	defer tearDownApiTests(s, setup)
+
+	tokens, err := s.services.Auth.CreateToken(context.Background(), service.UserAuthInput{
+		Username: setup.username,
+		Password: setup.password,
+		Device:   "phone",
+	})
+	s.Require().NoError(err)
+
+	refresh := func(refreshToken string) (int, tokensResponse) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/auth/refresh", bytes.NewBufferString(`{"refresh_token": "`+refreshToken+`"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		s.router.ServeHTTP(w, req)
+		var res tokensResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &res)
+		return w.Code, res
+	}
+
+	code, rotated := refresh(tokens.RefreshToken)
+	s.Assert().Equal(http.StatusOK, code)
+	s.Assert().NotEqual(tokens.RefreshToken, rotated.RefreshToken)
+	_, err = s.services.Auth.ValidateToken(context.Background(), rotated.Token)
+	s.Assert().NoError(err)
+
+	// повторное использование старого токена завершает сессию
+	code, _ = refresh(tokens.RefreshToken)
+	s.Assert().Equal(http.StatusForbidden, code)
+	_, err = s.services.Auth.ValidateToken(context.Background(), rotated.Token)
+	s.Assert().Equal(service.ErrExpiredToken, err)
+	code, _ = refresh(rotated.RefreshToken)
+	s.Assert().Equal(http.StatusForbidden, code)
+
+	// сессия, созданная в setupApiTests, не затронута
+	_, err = s.services.Auth.ValidateToken(context.Background(), setup.token)
+	s.Assert().NoError(err)
+
+	// параллельно токен обменивается только один раз
+	tokens, err = s.services.Auth.CreateToken(context.Background(), service.UserAuthInput{
+		Username: setup.username,
+		Password: setup.password,
+		Device:   "tablet",
+	})
+	s.Require().NoError(err)
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+		ok int
+	)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if code, _ := refresh(tokens.RefreshToken); code == http.StatusOK {
+				mu.Lock()
+				ok++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	s.Assert().Equal(1, ok)
+}
+
+func (s *APITestSuite) Test_authRouter_signOut() {
+	setup := setupApiTests(s)
+	defer tearDownApiTests(s, setup)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/sign-out", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+setup.token)
+	s.router.ServeHTTP(w, req)
+	s.Assert().Equal(http.StatusOK, w.Code)
+
+	// токен завершенной сессии больше не принимается
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/user?username="+setup.username, nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+setup.token)
+	s.router.ServeHTTP(w, req)
+	s.Assert().Equal(http.StatusForbidden, w.Code)
+	s.Assert().Equal(`{"message":"expired token"}`+"\n", w.Body.String())
+}
+
+func (s *APITestSuite) Test_authService_touchSession() {
+	setup := setupApiTests(s)
+	defer tearDownApiTests(s, setup)
+
+	ctx := context.Background()
+	tokens, err := s.services.Auth.CreateToken(ctx, service.UserAuthInput{
+		Username: setup.username,
+		Password: setup.password,
+		Device:   "phone",
+	})
+	s.Require().NoError(err)
+	claims, err := s.services.Auth.ValidateToken(ctx, tokens.AccessToken)
+	s.Require().NoError(err)
+
+	sessionKey := "session:" + claims.SessionId
+	getSession := func() (service.Session, error) {
+		var session service.Session
+		data, err := s.redis.Pool.Get(ctx, sessionKey).Bytes()
+		if err != nil {
+			return session, err
+		}
+		return session, json.Unmarshal(data, &session)
+	}
+
+	// last seen старше интервала обновления, поэтому проверка токена его обновит
+	session, err := getSession()
+	s.Require().NoError(err)
+	session.LastSeenAt = session.LastSeenAt.Add(-time.Hour)
+	data, err := json.Marshal(session)
+	s.Require().NoError(err)
+	s.Require().NoError(s.redis.Pool.Set(ctx, sessionKey, data, goredis.KeepTTL).Err())
+
+	_, err = s.services.Auth.ValidateToken(ctx, tokens.AccessToken)
+	s.Require().NoError(err)
+	touched, err := getSession()
+	s.Require().NoError(err)
+	s.Assert().WithinDuration(time.Now(), touched.LastSeenAt, 5*time.Second)
+	s.Assert().Equal(session.RefreshHash, touched.RefreshHash)
+	s.Assert().Equal(session.Device, touched.Device)
+	ttl, err := s.redis.Pool.(*goredis.Client).TTL(ctx, sessionKey).Result()
+	s.Require().NoError(err)
+	s.Assert().Greater(ttl, time.Duration(0))
+
+	// обновление last seen не мешает обмену refresh токена
+	tokens, err = s.services.Auth.RefreshToken(ctx, tokens.RefreshToken)
+	s.Require().NoError(err)
+	session, err = getSession()
+	s.Require().NoError(err)
+
+	// отозванная сессия не восстанавливается ни проверкой токена, ни refresh токеном
+	s.Require().NoError(s.services.Auth.RevokeSession(ctx, service.SessionRevokeInput{Username: setup.username, SessionId: claims.SessionId}))
+	err = s.redis.Pool.Get(ctx, "refresh:"+session.RefreshHash).Err()
+	s.Assert().ErrorIs(err, goredis.Nil)
+	_, err = s.services.Auth.ValidateToken(ctx, tokens.AccessToken)
+	s.Assert().Equal(service.ErrExpiredToken, err)
+	_, err = s.services.Auth.RefreshToken(ctx, tokens.RefreshToken)
+	s.Assert().Equal(service.ErrInvalidToken, err)
+	_, err = getSession()
+	s.Assert().ErrorIs(err, goredis.Nil)
+}
+
+func TestAuthRouter_changePassword(t *testing.T) {
+	type args struct {
+		ctx   context.Context
+		token string
+		input service.PasswordChangeInput
+	}
+	type MockBehaviour func(m *servicemocks.MockAuth, args args)
+
+	testCases := []struct {
+		testName      string
+		args          args
+		inputBody     string
+		mockBehaviour MockBehaviour
+		expectCode    int
+		expectBody    string
+	}{
+		{
+			testName: "correct test",
+			args: args{
+				ctx:   context.Background(),
+				token: "token",
+				input: service.PasswordChangeInput{Username: "vasek", SessionId: "laptop", Password: "1234", NewPassword: "4321", IP: "192.0.2.1"},
+			},
+			inputBody: `{"password": "1234", "new_password": "4321"}`,
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().ValidateToken(args.ctx, args.token).Return(&service.TokenClaims{Username: "vasek", SessionId: "laptop"}, nil)
+				m.EXPECT().ChangePassword(args.ctx, args.input).Return(nil)
+			},
+			expectCode: 200,
+			expectBody: "",
+		},
+		{
+			testName: "incorrect password",
+			args: args{
+				ctx:   context.Background(),
+				token: "token",
+				input: service.PasswordChangeInput{Username: "vasek", SessionId: "laptop", Password: "0000", NewPassword: "4321", IP: "192.0.2.1"},
+			},
+			inputBody: `{"password": "0000", "new_password": "4321"}`,
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().ValidateToken(args.ctx, args.token).Return(&service.TokenClaims{Username: "vasek", SessionId: "laptop"}, nil)
+				m.EXPECT().ChangePassword(args.ctx, args.input).Return(service.ErrIncorrectPassword)
+			},
+			expectCode: 403,
+			expectBody: `{"message":"incorrect user password"}` + "\n",
+		},
+		{
+			testName: "locked",
+			args: args{
+				ctx:   context.Background(),
+				token: "token",
+				input: service.PasswordChangeInput{Username: "vasek", SessionId: "laptop", Password: "0000", NewPassword: "4321", IP: "192.0.2.1"},
+			},
+			inputBody: `{"password": "0000", "new_password": "4321"}`,
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().ValidateToken(args.ctx, args.token).Return(&service.TokenClaims{Username: "vasek", SessionId: "laptop"}, nil)
+				m.EXPECT().ChangePassword(args.ctx, args.input).Return(&service.LockedError{Until: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)})
+			},
+			expectCode: 429,
+			expectBody: `{"message":"too many failed sign-in attempts, locked until 2030-01-01T00:00:00Z"}` + "\n",
+		},
+		{
+			testName:  "without new password",
+			args:      args{ctx: context.Background(), token: "token"},
+			inputBody: `{"password": "1234"}`,
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().ValidateToken(args.ctx, args.token).Return(&service.TokenClaims{Username: "vasek", SessionId: "laptop"}, nil)
+			},
+			expectCode: 400,
+			expectBody: `{"message":"field NewPassword is invalid"}` + "\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			auth := servicemocks.NewMockAuth(ctrl)
+			tc.mockBehaviour(auth, tc.args)
+			services := &service.Services{Auth: auth}
+
+			e := echo.New()
+			e.Validator, _ = validator.NewValidator()
+			newAuthRouter(e.Group("/auth"), services.Auth, &AuthMiddleware{auth: services.Auth})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/auth/user/update/password", bytes.NewBufferString(tc.inputBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+tc.args.token)
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+			assert.Equal(t, tc.expectBody, w.Body.String())
+		})
+	}
+}
+
+func (s *APITestSuite) Test_authRouter_resetPassword() {
+	setup := setupApiTests(s)
+	defer tearDownApiTests(s, setup)
+
+	post := func(path, body string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		s.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// неизвестный email не раскрывается
+	s.Assert().Equal(http.StatusAccepted, post("/auth/password/forgot", `{"email": "unknown@example.com"}`))
+	_, ok := s.mailer.Last("unknown@example.com")
+	s.Assert().False(ok)
+
+	s.Require().Equal(http.StatusAccepted, post("/auth/password/forgot", `{"email": "test"}`))
+	msg, ok := s.mailer.Last("test")
+	s.Require().True(ok)
+	token := regexp.MustCompile(`password: (\S+)`).FindStringSubmatch(msg.Body)
+	s.Require().Len(token, 2)
+
+	body := fmt.Sprintf(`{"token": "%s", "new_password": "4321"}`, token[1])
+	s.Assert().Equal(http.StatusOK, post("/auth/password/reset", body))
+	setup.password = "4321"
+	// токен одноразовый
+	s.Assert().Equal(http.StatusBadRequest, post("/auth/password/reset", body))
+
+	// все сессии завершены, вход работает с новым паролем
+	_, err := s.services.Auth.ValidateToken(context.Background(), setup.token)
+	s.Assert().Equal(service.ErrExpiredToken, err)
+	_, err = s.services.Auth.CreateToken(context.Background(), service.UserAuthInput{
+		Username: setup.username,
+		Password: "4321",
+	})
+	s.Assert().NoError(err)
+}
+
+func (s *APITestSuite) Test_authRouter_confirmEmail() {
+	setup := setupApiTests(s)
+	defer tearDownApiTests(s, setup)
+
+	msg, ok := s.mailer.Last("test")
+	s.Require().True(ok)
+	token := regexp.MustCompile(`token=(\S+)`).FindStringSubmatch(msg.Body)
+	s.Require().Len(token, 2)
+
+	confirm := func(token string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/auth/email/confirm?token="+token, nil)
+		s.router.ServeHTTP(w, req)
+		return w.Code
+	}
+	s.Assert().Equal(http.StatusBadRequest, confirm("invalid"))
+	// access токен подписан тем же ключом, но не подходит для подтверждения
+	s.Assert().Equal(http.StatusBadRequest, confirm(setup.token))
+	s.Assert().Equal(http.StatusOK, confirm(token[1]))
+
+	u, err := s.services.User.GetUserByUsername(context.Background(), setup.username)
+	s.Require().NoError(err)
+	s.Assert().True(u.EmailVerified)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/email/resend", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+setup.token)
+	s.router.ServeHTTP(w, req)
+	s.Assert().Equal(http.StatusBadRequest, w.Code)
+	s.Assert().Equal(`{"message":"email already verified"}`+"\n", w.Body.String())
+}
+
+func TestAuthMiddleware_validateToken(t *testing.T) {
+	testCases := []struct {
+		testName   string
+		err        error
+		expectCode int
+		expectBody string
+	}{
+		{
+			testName:   "valid token",
+			expectCode: 200,
+			expectBody: "",
+		},
+		{
+			testName:   "malformed token",
+			err:        service.ErrCannotParseToken,
+			expectCode: 401,
+			expectBody: `{"message":"cannot parse token"}` + "\n",
+		},
+		{
+			testName:   "revoked session",
+			err:        service.ErrExpiredToken,
+			expectCode: 403,
+			expectBody: `{"message":"expired token"}` + "\n",
+		},
+		{
+			testName:   "suspended user",
+			err:        service.ErrUserSuspended,
+			expectCode: 403,
+			expectBody: `{"message":"user is suspended"}` + "\n",
+		},
+		{
+			testName:   "token state unavailable",
+			err:        service.ErrCannotValidateToken,
+			expectCode: 500,
+			expectBody: `{"message":"internal server error"}` + "\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			auth := servicemocks.NewMockAuth(ctrl)
+			var claims *service.TokenClaims
+			if tc.err == nil {
+				claims = &service.TokenClaims{Username: "vasek", SessionId: "laptop"}
+			}
+			auth.EXPECT().ValidateToken(gomock.Any(), "token").Return(claims, tc.err)
+			m := &AuthMiddleware{auth: auth}
+
+			e := echo.New()
+			e.GET("/", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			}, m.AuthHandler)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer token")
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+			assert.Equal(t, tc.expectBody, w.Body.String())
+		})
+	}
+}
+
+func TestAuthMiddleware_verifiedEmail(t *testing.T) {
+	type MockBehaviour func(m *servicemocks.MockUser)
+
+	testCases := []struct {
+		testName      string
+		mockBehaviour MockBehaviour
+		expectCode    int
+		expectBody    string
+	}{
+		{
+			testName: "verified email",
+			mockBehaviour: func(m *servicemocks.MockUser) {
+				m.EXPECT().GetUserByUsername(gomock.Any(), "vasek").Return(pgmodel.User{Username: "vasek", EmailVerified: true}, nil)
+			},
+			expectCode: 200,
+			expectBody: "",
+		},
+		{
+			testName: "not verified email",
+			mockBehaviour: func(m *servicemocks.MockUser) {
+				m.EXPECT().GetUserByUsername(gomock.Any(), "vasek").Return(pgmodel.User{Username: "vasek"}, nil)
+			},
+			expectCode: 403,
+			expectBody: `{"message":"email is not verified"}` + "\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			user := servicemocks.NewMockUser(ctrl)
+			tc.mockBehaviour(user)
+			m := &AuthMiddleware{user: user}
+
+			e := echo.New()
+			e.GET("/", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			}, func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					c.Set(usernameCtx, "vasek")
+					return next(c)
+				}
+			}, m.VerifiedEmailHandler)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+			assert.Equal(t, tc.expectBody, w.Body.String())
+		})
+	}
+}
+
+func (s *APITestSuite) Test_authRouter_changeEmail() {
+	setup := setupApiTests(s)
+	defer tearDownApiTests(s, setup)
+
+	changeEmail := func(body string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/auth/user/update/email", bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+setup.token)
+		s.router.ServeHTTP(w, req)
+		return w.Code
+	}
+	s.Assert().Equal(http.StatusForbidden, changeEmail(`{"password": "wrong", "new_email": "vasek@example.com"}`))
+	s.Assert().Equal(http.StatusAccepted, changeEmail(fmt.Sprintf(`{"password": "%s", "new_email": "vasek@example.com"}`, setup.password)))
+
+	// до подтверждения почта не меняется
+	u, err := s.services.User.GetUserByUsername(context.Background(), setup.username)
+	s.Require().NoError(err)
+	s.Assert().Equal("test", u.Email)
+	s.Require().NotNil(u.PendingEmail)
+	s.Assert().Equal("vasek@example.com", *u.PendingEmail)
+	notice, ok := s.mailer.Last("test")
+	s.Require().True(ok)
+	s.Assert().Contains(notice.Body, "vasek@example.com")
+
+	msg, ok := s.mailer.Last("vasek@example.com")
+	s.Require().True(ok)
+	token := regexp.MustCompile(`token=(\S+)`).FindStringSubmatch(msg.Body)
+	s.Require().Len(token, 2)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/auth/email/confirm?token="+token[1], nil)
+	s.router.ServeHTTP(w, req)
+	s.Assert().Equal(http.StatusOK, w.Code)
+
+	u, err = s.services.User.GetUserByUsername(context.Background(), setup.username)
+	s.Require().NoError(err)
+	s.Assert().Equal("vasek@example.com", u.Email)
+	s.Assert().Nil(u.PendingEmail)
+	s.Assert().True(u.EmailVerified)
+}
+
+func (s *APITestSuite) Test_authRouter_twoFactor() {
+	setup := setupApiTests(s)
+	defer tearDownApiTests(s, setup)
+
+	request := func(method, path, body string, response interface{}) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+setup.token)
+		s.router.ServeHTTP(w, req)
+		if response != nil {
+			_ = json.Unmarshal(w.Body.Bytes(), response)
+		}
+		return w.Code
+	}
+
+	var enrollment struct {
+		Secret        string   `json:"secret"`
+		URI           string   `json:"otpauth_uri"`
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	s.Require().Equal(http.StatusOK, request(http.MethodPost, "/auth/2fa/enroll", "", &enrollment))
+	s.Assert().Contains(enrollment.URI, "otpauth://totp/")
+	s.Require().Len(enrollment.RecoveryCodes, 10)
+
+	// до подтверждения вход без второго фактора
+	tokens, err := s.services.Auth.CreateToken(context.Background(), service.UserAuthInput{Username: setup.username, Password: setup.password})
+	s.Require().NoError(err)
+	s.Assert().Empty(tokens.ChallengeToken)
+
+	s.Assert().Equal(http.StatusBadRequest, request(http.MethodPost, "/auth/2fa/confirm", `{"code": "000000"}`, nil))
+	code, err := totp.Code(enrollment.Secret, time.Now())
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusOK, request(http.MethodPost, "/auth/2fa/confirm", fmt.Sprintf(`{"code": "%s"}`, code), nil))
+
+	signIn := func() string {
+		var res challengeResponse
+		code := request(http.MethodPost, "/auth/sign-in", fmt.Sprintf(`{"username": "%s", "password": "%s"}`, setup.username, setup.password), &res)
+		s.Require().Equal(http.StatusOK, code)
+		s.Require().NotEmpty(res.ChallengeToken)
+		return res.ChallengeToken
+	}
+	secondStep := func(challenge, code string) (int, tokensResponse) {
+		var res tokensResponse
+		status := request(http.MethodPost, "/auth/sign-in/2fa", fmt.Sprintf(`{"challenge_token": "%s", "code": "%s"}`, challenge, code), &res)
+		return status, res
+	}
+
+	challenge := signIn()
+	status, _ := secondStep(challenge, "000000")
+	s.Assert().Equal(http.StatusForbidden, status)
+	status, res := secondStep(challenge, code)
+	s.Assert().Equal(http.StatusForbidden, status, "code used for confirmation cannot be reused")
+	status, res = secondStep(challenge, enrollment.RecoveryCodes[0])
+	s.Require().Equal(http.StatusOK, status)
+	_, err = s.services.Auth.ValidateToken(context.Background(), res.Token)
+	s.Assert().NoError(err)
+
+	// challenge и код восстановления одноразовые
+	status, _ = secondStep(challenge, enrollment.RecoveryCodes[1])
+	s.Assert().Equal(http.StatusForbidden, status)
+	status, _ = secondStep(signIn(), enrollment.RecoveryCodes[0])
+	s.Assert().Equal(http.StatusForbidden, status)
+
+	s.Assert().Equal(http.StatusForbidden, request(http.MethodDelete, "/auth/2fa/disable", `{"password": "wrong"}`, nil))
+	s.Assert().Equal(http.StatusOK, request(http.MethodDelete, "/auth/2fa/disable", fmt.Sprintf(`{"password": "%s"}`, setup.password), nil))
+	tokens, err = s.services.Auth.CreateToken(context.Background(), service.UserAuthInput{Username: setup.username, Password: setup.password})
+	s.Require().NoError(err)
+	s.Assert().NotEmpty(tokens.AccessToken)
+}
+
+func TestAuthRouter_signInLocked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	until := time.Now().Add(2 * time.Minute)
+	auth := servicemocks.NewMockAuth(ctrl)
+	auth.EXPECT().CreateToken(gomock.Any(), gomock.Any()).Return(service.Tokens{}, &service.LockedError{Until: until})
+
+	e := echo.New()
+	e.Validator, _ = validator.NewValidator()
+	newAuthRouter(e.Group("/auth"), auth, &AuthMiddleware{auth: auth})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/sign-in", bytes.NewBufferString(`{"username": "vasek", "password": "1234"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	e.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "120", w.Header().Get("Retry-After"))
+	assert.Equal(t, fmt.Sprintf(`{"message":"too many failed sign-in attempts, locked until %s"}`, until.UTC().Format(time.RFC3339))+"\n", w.Body.String())
+}
+
+func (s *APITestSuite) Test_authRouter_signInLockout() {
+	username, password := "petya", "1234"
+	s.Require().NoError(s.services.Auth.CreateUser(context.Background(), service.UserCreateInput{
+		Username:  username,
+		FirstName: "Petya",
+		LastName:  "Petrov",
+		Email:     "petrov@example.com",
+		Password:  password,
+	}))
+	// блокировка хранится в redis, который не очищается между тестами
+	defer s.redis.Pool.Del(context.Background(), "sign_in_failures:user:"+username, "sign_in_lock:user:"+username)
+
+	signIn := func(password string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/auth/sign-in", bytes.NewBufferString(fmt.Sprintf(`{"username": "%s", "password": "%s"}`, username, password)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// успешный вход сбрасывает счетчик
+	s.Assert().Equal(http.StatusForbidden, signIn("wrong").Code)
+	s.Assert().Equal(http.StatusForbidden, signIn("wrong").Code)
+	s.Assert().Equal(http.StatusOK, signIn(password).Code)
+
+	for i := 0; i < 3; i++ {
+		s.Assert().Equal(http.StatusForbidden, signIn("wrong").Code)
+	}
+	// заблокирован даже верный пароль
+	w := signIn(password)
+	s.Assert().Equal(http.StatusTooManyRequests, w.Code)
+	s.Assert().Contains(w.Body.String(), "locked until")
+	s.Assert().NotEmpty(w.Header().Get("Retry-After"))
+}
+
+func (s *APITestSuite) Test_authRouter_twoFactorLockout() {
+	setup := setupApiTests(s)
+	defer tearDownApiTests(s, setup)
+
+	enrollment, err := s.services.Auth.EnrollTOTP(context.Background(), setup.username)
+	s.Require().NoError(err)
+	code, err := totp.Code(enrollment.Secret, time.Now())
+	s.Require().NoError(err)
+	s.Require().NoError(s.services.Auth.ConfirmTOTP(context.Background(), service.TOTPConfirmInput{Username: setup.username, Code: code}))
+
+	request := func(path, body string, response interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		s.router.ServeHTTP(w, req)
+		if response != nil {
+			_ = json.Unmarshal(w.Body.Bytes(), response)
+		}
+		return w
+	}
+	signIn := func() string {
+		var res challengeResponse
+		w := request("/auth/sign-in", fmt.Sprintf(`{"username": "%s", "password": "%s"}`, setup.username, setup.password), &res)
+		s.Require().Equal(http.StatusOK, w.Code)
+		return res.ChallengeToken
+	}
+	secondStep := func(challenge, code string) *httptest.ResponseRecorder {
+		return request("/auth/sign-in/2fa", fmt.Sprintf(`{"challenge_token": "%s", "code": "%s"}`, challenge, code), nil)
+	}
+
+	// верный пароль не сбрасывает ошибки второго фактора, новые challenge не дают новых попыток
+	s.Assert().Equal(http.StatusForbidden, secondStep(signIn(), "000000").Code)
+	s.Assert().Equal(http.StatusForbidden, secondStep(signIn(), "000000").Code)
+	s.Assert().Equal(http.StatusForbidden, secondStep(signIn(), "000000").Code)
+
+	w := request("/auth/sign-in", fmt.Sprintf(`{"username": "%s", "password": "%s"}`, setup.username, setup.password), nil)
+	s.Assert().Equal(http.StatusTooManyRequests, w.Code)
+	s.Assert().NotEmpty(w.Header().Get("Retry-After"))
+}
+
+func (s *APITestSuite) Test_authRouter_passwordCheckLockout() {
+	setup := setupApiTests(s)
+	defer tearDownApiTests(s, setup)
+
+	deleteUser := func(password string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodDelete, "/auth/user/delete", bytes.NewBufferString(fmt.Sprintf(`{"username": "%s", "password": "%s"}`, setup.username, password)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		s.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// проверка пароля вне входа ограничена той же блокировкой
+	for i := 0; i < 3; i++ {
+		s.Assert().Equal(http.StatusForbidden, deleteUser("wrong"))
+	}
+	s.Assert().Equal(http.StatusTooManyRequests, deleteUser(setup.password))
+	_, err := s.services.User.GetUserByUsername(context.Background(), setup.username)
+	s.Assert().NoError(err)
+}
@@ -1,10 +1,12 @@
 package v1
 
 import (
+	"API_for_SN_go/internal/model/pgmodel"
 	"API_for_SN_go/internal/service"
 	"errors"
 	"github.com/labstack/echo/v4"
 	"net/http"
+	"time"
 )
 
 type commentRouter struct {
@@ -20,12 +22,13 @@ func newCommentRouter(g *echo.Group, commentService service.Comment) {
 }
 
 type commentCreateInput struct {
-	PostId  string `json:"post_id"`
-	Comment string `json:"comment"`
+	PostId   string `json:"post_id"`
+	ParentId string `json:"parent_id"`
+	Comment  string `json:"comment"`
 }
 
 // @Summary		Create comment
-// @Description	Create comment for post
+// @Description	Create comment for post. Set parent_id to reply to another comment of the same post
 // @Tags			comment
 // @Accept			json
 // @Produce		json
@@ -51,6 +54,7 @@ func (r *commentRouter) create(c echo.Context) error {
 	commentId, err := r.commentService.CreateComment(c.Request().Context(), service.CommentCreateInput{
 		Username: username,
 		PostId:   input.PostId,
+		ParentId: input.ParentId,
 		Comment:  input.Comment,
 	})
 	if err != nil {
@@ -62,6 +66,10 @@ func (r *commentRouter) create(c echo.Context) error {
 			errorResponse(c, http.StatusBadRequest, err.Error())
 			return err
 		}
+		if errors.Is(err, service.ErrParentCommentNotFound) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
 		errorResponse(c, http.StatusInternalServerError, err.Error())
 		return err
 	}
@@ -117,7 +125,7 @@ func (r *commentRouter) updateComment(c echo.Context) error {
 }
 
 type commentDeleteInput struct {
-	CommentId string `json:"comment_id"`
+	CommentId string `json:"comment_id" validate:"required"`
 }
 
 // @Summary		Delete comment
@@ -185,16 +193,59 @@ func (r *commentRouter) getCommentById(c echo.Context) error {
 		errorResponse(c, http.StatusInternalServerError, "internal server error")
 		return err
 	}
-	type response struct {
-		Username  string `json:"username"`
-		PostId    string `json:"post_id"`
-		CommentId string `json:"comment_id"`
-		Comment   string `json:"comment"`
-	}
-	return c.JSON(http.StatusOK, response{
+	return c.JSON(http.StatusOK, newCommentResponse(comment))
+}
+
+type commentResponse struct {
+	Username  string    `json:"username"`
+	PostId    string    `json:"post_id"`
+	CommentId string    `json:"comment_id"`
+	ParentId  *string   `json:"parent_id,omitempty"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newCommentResponse(comment pgmodel.Comment) commentResponse {
+	return commentResponse{
 		Username:  comment.Username,
 		PostId:    comment.PostId,
 		CommentId: comment.CommentId,
+		ParentId:  comment.ParentId,
 		Comment:   comment.Comment,
-	})
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+	}
+}
+
+func newCommentsResponse(comments []pgmodel.Comment) []commentResponse {
+	res := make([]commentResponse, 0, len(comments))
+	for _, comment := range comments {
+		res = append(res, newCommentResponse(comment))
+	}
+	return res
+}
+
+type commentNodeResponse struct {
+	Username  string                `json:"username"`
+	CommentId string                `json:"comment_id"`
+	Comment   string                `json:"comment"`
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
+	Replies   []commentNodeResponse `json:"replies"`
+}
+
+func newCommentTreeResponse(nodes []service.CommentNode) []commentNodeResponse {
+	res := make([]commentNodeResponse, 0, len(nodes))
+	for _, node := range nodes {
+		res = append(res, commentNodeResponse{
+			Username:  node.Username,
+			CommentId: node.CommentId,
+			Comment:   node.Comment.Comment,
+			CreatedAt: node.CreatedAt,
+			UpdatedAt: node.UpdatedAt,
+			Replies:   newCommentTreeResponse(node.Replies),
+		})
+	}
+	return res
 }
@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"API_for_SN_go/internal/service"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -8,6 +9,7 @@ import (
 	"github.com/labstack/echo/v4"
 	"net/http"
 	"net/http/httptest"
+	"time"
 )
 
 func (s *APITestSuite) Test_commentRouter_create() {
@@ -29,6 +31,11 @@ func (s *APITestSuite) Test_commentRouter_create() {
 			inputBody:  `{"post_id": "0", "comment": "subscribe on my channel"}`,
 			expectCode: 400,
 		},
+		{
+			testName:   "incorrect parent comment id",
+			inputBody:  fmt.Sprintf(`{"post_id": "%s", "parent_id": "0", "comment": "reply"}`, setup.postId),
+			expectCode: 400,
+		},
 	}
 	for _, tc := range testCases {
 		w := httptest.NewRecorder()
@@ -47,3 +54,174 @@ func (s *APITestSuite) Test_commentRouter_create() {
 		}
 	}
 }
+
+func (s *APITestSuite) Test_postRouter_getCommentsTree() {
+	setup := setupReactionRouterTests(s)
+	defer tearDownRouterTests(s, setup)
+
+	rootId, err := s.services.Comment.CreateComment(context.Background(), service.CommentCreateInput{
+		Username: setup.username,
+		PostId:   setup.postId,
+		Comment:  "root",
+	})
+	s.Require().NoError(err)
+	replyId, err := s.services.Comment.CreateComment(context.Background(), service.CommentCreateInput{
+		Username: setup.username,
+		PostId:   setup.postId,
+		ParentId: rootId,
+		Comment:  "reply",
+	})
+	s.Require().NoError(err)
+	_, err = s.services.Comment.CreateComment(context.Background(), service.CommentCreateInput{
+		Username: setup.username,
+		PostId:   setup.postId,
+		ParentId: replyId,
+		Comment:  "reply to reply",
+	})
+	s.Require().NoError(err)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/posts/post/comments?post_id=%s&mode=tree&depth=2", setup.postId), nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+setup.token)
+	s.router.ServeHTTP(w, req)
+	s.Assert().Equal(http.StatusOK, w.Code)
+
+	var response struct {
+		Comments []commentNodeResponse `json:"comments"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	s.Require().Len(response.Comments, 1)
+	s.Assert().Equal(rootId, response.Comments[0].CommentId)
+	s.Require().Len(response.Comments[0].Replies, 1)
+	s.Assert().Equal(replyId, response.Comments[0].Replies[0].CommentId)
+	// третий уровень обрезается глубиной
+	s.Assert().Len(response.Comments[0].Replies[0].Replies, 0)
+}
+
+func (s *APITestSuite) Test_postRouter_getCommentsPagination() {
+	setup := setupReactionRouterTests(s)
+	defer tearDownRouterTests(s, setup)
+
+	var commentIds []string
+	for i := 0; i < 3; i++ {
+		commentId, err := s.services.Comment.CreateComment(context.Background(), service.CommentCreateInput{
+			Username: setup.username,
+			PostId:   setup.postId,
+			Comment:  fmt.Sprintf("comment %d", i),
+		})
+		s.Require().NoError(err)
+		commentIds = append(commentIds, commentId)
+	}
+
+	type response struct {
+		Comments   []commentResponse `json:"comments"`
+		NextCursor string            `json:"next_cursor"`
+	}
+	getPage := func(cursor string) (int, response) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/posts/post/comments?post_id=%s&limit=2&cursor=%s", setup.postId, cursor), nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+setup.token)
+		s.router.ServeHTTP(w, req)
+		var res response
+		_ = json.Unmarshal(w.Body.Bytes(), &res)
+		return w.Code, res
+	}
+
+	code, first := getPage("")
+	s.Assert().Equal(http.StatusOK, code)
+	s.Require().Len(first.Comments, 2)
+	s.Assert().Equal(commentIds[0], first.Comments[0].CommentId)
+	s.Assert().Equal(commentIds[1], first.Comments[1].CommentId)
+	s.Require().NotEmpty(first.NextCursor)
+
+	code, second := getPage(first.NextCursor)
+	s.Assert().Equal(http.StatusOK, code)
+	s.Require().Len(second.Comments, 1)
+	s.Assert().Equal(commentIds[2], second.Comments[0].CommentId)
+	s.Assert().Empty(second.NextCursor)
+
+	code, _ = getPage("not-a-cursor")
+	s.Assert().Equal(http.StatusBadRequest, code)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/posts/post/comments?post_id=%s&sort=newest", setup.postId), nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+setup.token)
+	s.router.ServeHTTP(w, req)
+	s.Assert().Equal(http.StatusOK, w.Code)
+
+	var newest response
+	_ = json.Unmarshal(w.Body.Bytes(), &newest)
+	s.Require().Len(newest.Comments, 3)
+	s.Assert().Equal(commentIds[2], newest.Comments[0].CommentId)
+	s.Assert().False(newest.Comments[0].CreatedAt.IsZero())
+}
+
+// Deleting comment sends event for each deleted reply, deleting post ends events of the post
+func (s *APITestSuite) Test_commentService_deleteEvents() {
+	setup := setupReactionRouterTests(s)
+	defer tearDownRouterTests(s, setup)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := s.services.Events.WatchPost(ctx, setup.postId)
+	s.Require().NoError(err)
+	next := func() (service.PostEvent, bool) {
+		select {
+		case event, ok := <-events:
+			return event, ok
+		case <-time.After(time.Second):
+			s.FailNow("no post event")
+			return service.PostEvent{}, false
+		}
+	}
+
+	rootId, err := s.services.Comment.CreateComment(context.Background(), service.CommentCreateInput{
+		Username: setup.username,
+		PostId:   setup.postId,
+		Comment:  "root",
+	})
+	s.Require().NoError(err)
+	replyId, err := s.services.Comment.CreateComment(context.Background(), service.CommentCreateInput{
+		Username: setup.username,
+		PostId:   setup.postId,
+		ParentId: rootId,
+		Comment:  "reply",
+	})
+	s.Require().NoError(err)
+	for _, commentId := range []string{rootId, replyId} {
+		comment, err := s.services.Comment.GetCommentById(context.Background(), commentId)
+		s.Require().NoError(err)
+		event, ok := next()
+		s.Require().True(ok)
+		s.Assert().Equal(service.EventCommentCreated, event.Type)
+		s.Assert().Equal(commentId, event.Comment.CommentId)
+		// время события - время сохранения комментария
+		s.Assert().True(comment.CreatedAt.Equal(event.CreatedAt))
+	}
+
+	err = s.services.Comment.DeleteComment(context.Background(), service.CommentDeleteInput{
+		Username:  setup.username,
+		CommentId: rootId,
+	})
+	s.Require().NoError(err)
+	for _, commentId := range []string{rootId, replyId} {
+		event, ok := next()
+		s.Require().True(ok)
+		s.Assert().Equal(service.EventCommentDeleted, event.Type)
+		s.Assert().Equal(commentId, event.Comment.CommentId)
+	}
+	_, err = s.services.Comment.GetCommentById(context.Background(), replyId)
+	s.Assert().ErrorIs(err, service.ErrCommentNotFound)
+
+	err = s.services.Post.DeletePost(context.Background(), service.PostDeleteInput{
+		Username: setup.username,
+		PostId:   setup.postId,
+	})
+	s.Require().NoError(err)
+	event, ok := next()
+	s.Require().True(ok)
+	s.Assert().Equal(service.EventPostDeleted, event.Type)
+	s.Assert().Equal(setup.postId, event.PostId)
+	_, ok = next()
+	s.Assert().False(ok)
+}
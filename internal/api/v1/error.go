@@ -7,6 +7,8 @@ import (
 
 var (
 	ErrInvalidAuthHeader = errors.New("invalid authorization header")
+	ErrInvalidPagination = errors.New("invalid pagination params")
+	ErrInsufficientRole  = errors.New("insufficient role")
 )
 
 func errorResponse(c echo.Context, status int, msg string) {
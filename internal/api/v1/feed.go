@@ -0,0 +1,86 @@
+package v1
+
+import (
+	"API_for_SN_go/internal/service"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+type feedRouter struct {
+	postService service.Post
+}
+
+func newFeedRouter(g *echo.Group, postService service.Post) {
+	r := &feedRouter{postService: postService}
+	g.GET("", r.getFeed)
+}
+
+// @Summary		Get feed
+// @Description	Get posts of followed users and current user own posts, newest first by default. Each post contains number of reactions of each kind and number of comments
+// @Tags			feed
+// @Accept			json
+// @Produce		json
+// @Param			limit	query		int		false	"max number of posts (default 20, max 100)"
+// @Param			cursor	query		string	false	"next_cursor from previous page"
+// @Param			sort	query		string	false	"newest (default) or oldest"
+// @Success		200		{object}	map[string]interface{}
+// @Failure		400		{object}	echo.HTTPError
+// @Failure		500		{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/api/v1/feed [get]
+func (r *feedRouter) getFeed(c echo.Context) error {
+	userCtx := c.Get(usernameCtx)
+	username, ok := userCtx.(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+	p, err := parsePagination(c)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	items, next, err := r.postService.GetFeed(c.Request().Context(), username, p)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+
+	type itemResponse struct {
+		Username      string         `json:"username"`
+		PostId        string         `json:"post_id"`
+		Title         string         `json:"title"`
+		Text          string         `json:"text"`
+		Reactions     map[string]int `json:"reactions"`
+		CommentsCount int            `json:"comments_count"`
+		CreatedAt     time.Time      `json:"created_at"`
+		UpdatedAt     time.Time      `json:"updated_at"`
+	}
+	type response struct {
+		Posts      []itemResponse `json:"posts"`
+		NextCursor string         `json:"next_cursor"`
+	}
+	res := response{
+		Posts:      make([]itemResponse, 0, len(items)),
+		NextCursor: next,
+	}
+	for _, item := range items {
+		res.Posts = append(res.Posts, itemResponse{
+			Username:      item.Username,
+			PostId:        item.PostId,
+			Title:         item.Title,
+			Text:          item.Text,
+			Reactions:     item.Reactions,
+			CommentsCount: item.CommentsCount,
+			CreatedAt:     item.CreatedAt,
+			UpdatedAt:     item.UpdatedAt,
+		})
+	}
+	return c.JSON(http.StatusOK, res)
+}
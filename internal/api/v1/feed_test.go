@@ -0,0 +1,51 @@
+package v1
+
+import (
+	"API_for_SN_go/internal/service"
+	"context"
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"net/http/httptest"
+)
+
+func (s *APITestSuite) Test_feedRouterGet() {
+	setup := setupReactionRouterTests(s)
+	defer tearDownRouterTests(s, setup)
+
+	_, err := s.services.Comment.CreateComment(context.Background(), service.CommentCreateInput{
+		Username: setup.username,
+		PostId:   setup.postId,
+		Comment:  "comment",
+	})
+	s.Require().NoError(err)
+	_, err = s.services.Reaction.CreateReaction(context.Background(), service.ReactionCreateInput{
+		Username: setup.username,
+		PostId:   setup.postId,
+		Reaction: "like",
+	})
+	s.Require().NoError(err)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/feed", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+setup.token)
+	s.router.ServeHTTP(w, req)
+	s.Assert().Equal(http.StatusOK, w.Code)
+
+	var response struct {
+		Posts []struct {
+			PostId        string         `json:"post_id"`
+			Username      string         `json:"username"`
+			Reactions     map[string]int `json:"reactions"`
+			CommentsCount int            `json:"comments_count"`
+		} `json:"posts"`
+		NextCursor string `json:"next_cursor"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	s.Require().Len(response.Posts, 1)
+	s.Assert().Equal(setup.postId, response.Posts[0].PostId)
+	s.Assert().Equal(setup.username, response.Posts[0].Username)
+	s.Assert().Equal(map[string]int{"like": 1}, response.Posts[0].Reactions)
+	s.Assert().Equal(1, response.Posts[0].CommentsCount)
+	s.Assert().Empty(response.NextCursor)
+}
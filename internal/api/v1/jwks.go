@@ -0,0 +1,37 @@
+package v1
+
+import (
+	"API_for_SN_go/internal/service"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+// клиенты кэшируют ключи, новый kid они должны запрашивать повторно
+const jwksMaxAge = "max-age=300"
+
+type wellKnownRouter struct {
+	authService service.Auth
+}
+
+func newWellKnownRouter(g *echo.Group, authService service.Auth) {
+	r := &wellKnownRouter{authService: authService}
+	g.GET("/jwks.json", r.jwks)
+}
+
+// @Summary		JSON Web Key Set
+// @Description	Public keys which verify access tokens signed with RS256 or EdDSA, token header kid selects the key.
+// @Description	Keys are rotated, refetch the set when token has unknown kid. Set is empty when tokens are signed with HS256
+// @Tags			auth
+// @Produce		json
+// @Success		200	{object}	map[string]interface{}
+// @Failure		500	{object}	echo.HTTPError
+// @Router			/.well-known/jwks.json [get]
+func (r *wellKnownRouter) jwks(c echo.Context) error {
+	set, err := r.authService.JWKS(c.Request().Context())
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	c.Response().Header().Set(echo.HeaderCacheControl, jwksMaxAge)
+	return c.JSON(http.StatusOK, set)
+}
@@ -0,0 +1,147 @@
+package v1
+
+import (
+	"API_for_SN_go/internal/mocks/servicemocks"
+	"API_for_SN_go/internal/service"
+	"API_for_SN_go/pkg/hasher"
+	"API_for_SN_go/pkg/jwk"
+	"API_for_SN_go/pkg/validator"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWellKnownRouter_jwks(t *testing.T) {
+	type MockBehaviour func(m *servicemocks.MockAuth)
+
+	testCases := []struct {
+		testName      string
+		mockBehaviour MockBehaviour
+		expectCode    int
+		expectBody    string
+	}{
+		{
+			testName: "correct test",
+			mockBehaviour: func(m *servicemocks.MockAuth) {
+				m.EXPECT().JWKS(gomock.Any()).Return(jwk.Set{Keys: []jwk.Key{
+					{Kty: "OKP", Kid: "key1", Use: "sig", Alg: jwk.EdDSA, Crv: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"},
+				}}, nil)
+			},
+			expectCode: 200,
+			expectBody: `{"keys":[{"kty":"OKP","kid":"key1","use":"sig","alg":"EdDSA","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}]}` + "\n",
+		},
+		{
+			testName: "hs256 signing",
+			mockBehaviour: func(m *servicemocks.MockAuth) {
+				m.EXPECT().JWKS(gomock.Any()).Return(jwk.Set{Keys: []jwk.Key{}}, nil)
+			},
+			expectCode: 200,
+			expectBody: `{"keys":[]}` + "\n",
+		},
+		{
+			testName: "redis error",
+			mockBehaviour: func(m *servicemocks.MockAuth) {
+				m.EXPECT().JWKS(gomock.Any()).Return(jwk.Set{}, errors.New("redis error"))
+			},
+			expectCode: 500,
+			expectBody: `{"message":"internal server error"}` + "\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			auth := servicemocks.NewMockAuth(ctrl)
+			tc.mockBehaviour(auth)
+
+			e := echo.New()
+			newWellKnownRouter(e.Group("/.well-known"), auth)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+			assert.Equal(t, tc.expectBody, w.Body.String())
+		})
+	}
+}
+
+// Access token signed with RS256 or EdDSA must be verifiable offline with keys from jwks endpoint
+func (s *APITestSuite) Test_wellKnownRouter_jwksVerify() {
+	for _, alg := range []string{jwk.RS256, jwk.EdDSA} {
+		s.Run(alg, func() {
+			setup := setupApiTests(s)
+			defer tearDownApiTests(s, setup)
+
+			services := service.NewServices(service.ServicesDependencies{
+				Repos:            s.repositories,
+				Hasher:           hasher.NewArgon2Hasher("secret"),
+				Redis:            s.redis,
+				Mailer:           s.mailer,
+				SignKey:          "secret",
+				SignAlgorithm:    alg,
+				KeyEncryptionKey: []byte("0123456789abcdef0123456789abcdef"),
+				KeyRotation:      time.Hour,
+				TokenTTL:         time.Hour,
+				RefreshTokenTTL:  24 * time.Hour,
+			})
+			router := echo.New()
+			var err error
+			router.Validator, err = validator.NewValidator()
+			s.Require().NoError(err)
+			NewRouter(router, services)
+
+			tokens, err := services.Auth.CreateToken(context.Background(), service.UserAuthInput{
+				Username: setup.username,
+				Password: setup.password,
+			})
+			s.Require().NoError(err)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+			s.Require().Equal(http.StatusOK, w.Code)
+			var set jwk.Set
+			s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &set))
+			defer func() {
+				for _, key := range set.Keys {
+					_ = s.redis.Pool.Del(context.Background(), "jwk:"+key.Kid).Err()
+				}
+				_ = s.redis.Pool.Del(context.Background(), "jwks").Err()
+			}()
+
+			claims := &service.TokenClaims{}
+			token, err := jwt.ParseWithClaims(tokens.AccessToken, claims, func(t *jwt.Token) (interface{}, error) {
+				kid, _ := t.Header["kid"].(string)
+				key, ok := set.Find(kid)
+				if !ok {
+					return nil, errors.New("unknown kid")
+				}
+				return key.PublicKey()
+			})
+			s.Require().NoError(err)
+			s.Assert().True(token.Valid)
+			s.Assert().Equal(alg, token.Method.Alg())
+			s.Assert().Equal(setup.username, claims.Username)
+
+			// токен с новым алгоритмом принимается api, HS256 токен - нет
+			for accessToken, expectCode := range map[string]int{tokens.AccessToken: http.StatusOK, setup.token: http.StatusUnauthorized} {
+				w = httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodGet, "/api/v1/user?username="+setup.username, nil)
+				req.Header.Set(echo.HeaderAuthorization, "Bearer "+accessToken)
+				router.ServeHTTP(w, req)
+				s.Assert().Equal(expectCode, w.Code)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"API_for_SN_go/internal/repo"
 	"API_for_SN_go/internal/service"
 	"API_for_SN_go/pkg/hasher"
+	"API_for_SN_go/pkg/mailer"
 	"API_for_SN_go/pkg/postgres"
 	"API_for_SN_go/pkg/redis"
 	"API_for_SN_go/pkg/validator"
@@ -24,6 +25,7 @@ type APITestSuite struct {
 	pg           *postgres.Postgres
 	repositories *repo.Repositories
 	redis        *redis.Redis
+	mailer       *mailer.MemoryMailer
 	services     *service.Services
 	m            *migrate.Migrate
 }
@@ -48,12 +50,27 @@ func (s *APITestSuite) SetupTest() {
 	s.redis = redis.NewRedis(redisUrl)
 
 	s.repositories = repo.NewRepositories(pg)
+	s.mailer = mailer.NewMemoryMailer()
 	d := service.ServicesDependencies{
-		Repos:    s.repositories,
-		Hasher:   hasher.NewHasher("secret"),
-		Redis:    s.redis,
-		SignKey:  "secret",
-		TokenTTL: time.Hour,
+		Repos:           s.repositories,
+		Hasher:          hasher.NewArgon2Hasher("secret"),
+		Redis:           s.redis,
+		Mailer:          s.mailer,
+		SignKey:         "secret",
+		TokenTTL:        time.Hour,
+		RefreshTokenTTL: 24 * time.Hour,
+		ResetTokenTTL:   15 * time.Minute,
+		EmailTokenTTL:   time.Hour,
+		PublicURL:       "http://localhost:8080",
+		TOTPIssuer:      "API_for_SN",
+		ChallengeTTL:    5 * time.Minute,
+		Lockout: service.LockoutConfig{
+			MaxUserFailures: 3,
+			MaxIPFailures:   100,
+			FailureWindow:   time.Minute,
+			BaseLockout:     time.Minute,
+			MaxLockout:      time.Hour,
+		},
 	}
 	s.services = service.NewServices(d)
 
@@ -83,7 +100,7 @@ func setupApiTests(s *APITestSuite) *apiTestsInfo {
 	}); err != nil {
 		panic(err)
 	}
-	token, err := s.services.Auth.CreateToken(context.Background(), service.UserAuthInput{
+	tokens, err := s.services.Auth.CreateToken(context.Background(), service.UserAuthInput{
 		Username: username,
 		Password: password,
 	})
@@ -93,18 +110,22 @@ func setupApiTests(s *APITestSuite) *apiTestsInfo {
 	return &apiTestsInfo{
 		username: username,
 		password: password,
-		token:    token,
+		token:    tokens.AccessToken,
 	}
 }
 
 func tearDownApiTests(s *APITestSuite, setup *apiTestsInfo) {
+	// блокировка входа хранится в redis, который не очищается между тестами
+	if err := s.redis.Pool.Del(context.Background(), "sign_in_failures:user:"+setup.username, "sign_in_lock:user:"+setup.username).Err(); err != nil {
+		panic(err)
+	}
 	if err := s.services.Auth.DeleteUser(context.Background(), service.UserDeleteInput{
 		Username: setup.username,
 		Password: setup.password,
 	}); err != nil {
 		panic(err)
 	}
-	if err := s.redis.Pool.Del(context.Background(), "jwt:"+setup.username).Err(); err != nil {
+	if err := s.services.Auth.RevokeAllSessions(context.Background(), setup.username); err != nil {
 		panic(err)
 	}
 }
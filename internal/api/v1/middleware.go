@@ -3,6 +3,7 @@ package v1
 import (
 	"API_for_SN_go/internal/service"
 	"errors"
+	"fmt"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"log"
@@ -14,10 +15,15 @@ import (
 const (
 	bearerPrefix = "Bearer "
 	usernameCtx  = "username"
+	sessionIdCtx = "session_id"
+	roleCtx      = "role"
+	// scopesCtx is set only for requests with personal access token
+	scopesCtx = "scopes"
 )
 
 type AuthMiddleware struct {
 	auth service.Auth
+	user service.User
 }
 
 func (h *AuthMiddleware) AuthHandler(next echo.HandlerFunc) echo.HandlerFunc {
@@ -27,21 +33,110 @@ func (h *AuthMiddleware) AuthHandler(next echo.HandlerFunc) echo.HandlerFunc {
 			errorResponse(c, http.StatusUnauthorized, ErrInvalidAuthHeader.Error())
 			return nil
 		}
-		username, err := h.auth.ValidateToken(c.Request().Context(), token)
+		claims, err := h.auth.ValidateToken(c.Request().Context(), token)
 		if err != nil {
 			if errors.Is(err, service.ErrCannotParseToken) {
 				errorResponse(c, http.StatusUnauthorized, err.Error())
+			} else if errors.Is(err, service.ErrCannotValidateToken) {
+				errorResponse(c, http.StatusInternalServerError, "internal server error")
 			} else {
 				// token is invalid or expired
 				errorResponse(c, http.StatusForbidden, err.Error())
 			}
 			return nil
 		}
-		c.Set(usernameCtx, username)
+		c.Set(usernameCtx, claims.Username)
+		c.Set(sessionIdCtx, claims.SessionId)
+		c.Set(roleCtx, claims.Role)
+		if claims.AccessTokenId != "" {
+			c.Set(scopesCtx, claims.Scopes)
+		}
+		return next(c)
+	}
+}
+
+// SessionHandler is AuthHandler which rejects personal access tokens, used for account management
+func (h *AuthMiddleware) SessionHandler(next echo.HandlerFunc) echo.HandlerFunc {
+	return h.AuthHandler(func(c echo.Context) error {
+		if _, ok := c.Get(scopesCtx).([]string); ok {
+			errorResponse(c, http.StatusForbidden, service.ErrAccessTokenNotAllowed.Error())
+			return nil
+		}
+		return next(c)
+	})
+}
+
+// VerifiedEmailHandler rejects users with unconfirmed email. Must be used after AuthHandler
+func (h *AuthMiddleware) VerifiedEmailHandler(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userCtx := c.Get(usernameCtx)
+		username, ok := userCtx.(string)
+		if !ok {
+			errorResponse(c, http.StatusInternalServerError, "internal server error")
+			return nil
+		}
+		user, err := h.user.GetUserByUsername(c.Request().Context(), username)
+		if err != nil {
+			if errors.Is(err, service.ErrUserNotFound) {
+				errorResponse(c, http.StatusForbidden, err.Error())
+				return nil
+			}
+			errorResponse(c, http.StatusInternalServerError, "internal server error")
+			return nil
+		}
+		if !user.EmailVerified {
+			errorResponse(c, http.StatusForbidden, service.ErrEmailNotVerified.Error())
+			return nil
+		}
 		return next(c)
 	}
 }
 
+// RequireRoles allows request only for users with one of the roles. Must be used after AuthHandler
+func RequireRoles(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			role, _ := c.Get(roleCtx).(string)
+			// токены, выданные до появления ролей, не содержат роль
+			if role == "" {
+				role = service.RoleUser
+			}
+			for _, r := range roles {
+				if r == role {
+					return next(c)
+				}
+			}
+			errorResponse(c, http.StatusForbidden, ErrInsufficientRole.Error())
+			return nil
+		}
+	}
+}
+
+// RequireScope checks that personal access token has scope of resource: read scope for GET requests
+// and write scope for others. Requests with session token are not restricted. Must be used after AuthHandler
+func RequireScope(resource string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			scopes, ok := c.Get(scopesCtx).([]string)
+			if !ok {
+				return next(c)
+			}
+			access := service.ScopeWrite
+			if m := c.Request().Method; m == http.MethodGet || m == http.MethodHead {
+				access = service.ScopeRead
+			}
+			required := service.Scope(resource, access)
+			for _, scope := range scopes {
+				if scope == required {
+					return next(c)
+				}
+			}
+			errorResponse(c, http.StatusForbidden, fmt.Sprintf("access token has no scope %s", required))
+			return nil
+		}
+	}
+}
+
 // Auth via bearer token in header Authorization
 func parseToken(r *http.Request) (string, bool) {
 	header := r.Header.Get(echo.HeaderAuthorization)
@@ -0,0 +1,112 @@
+package v1
+
+import (
+	"API_for_SN_go/internal/service"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type moderationRouter struct {
+	postService    service.Post
+	commentService service.Comment
+}
+
+func newModerationRouter(g *echo.Group, postService service.Post, commentService service.Comment) {
+	r := &moderationRouter{
+		postService:    postService,
+		commentService: commentService,
+	}
+	g.DELETE("/post", r.deletePost)
+	g.DELETE("/comment", r.deleteComment)
+}
+
+// @Summary		Delete any post
+// @Description	Delete post of any author with all its reactions and comments. Available for moderators and admins
+// @Tags			moderation
+// @Accept			json
+// @Produce		json
+// @Param			input	body	postDeleteInput	true	"input"
+// @Success		200
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		403	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/api/v1/moderation/post [delete]
+func (r *moderationRouter) deletePost(c echo.Context) error {
+	var input postDeleteInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+		return err
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	userCtx := c.Get(usernameCtx)
+	username, ok := userCtx.(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+	err := r.postService.DeletePost(c.Request().Context(), service.PostDeleteInput{
+		Username: username,
+		PostId:   input.PostId,
+		Moderate: true,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrPostNotFound) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// @Summary		Delete any comment
+// @Description	Delete comment of any author. Available for moderators and admins
+// @Tags			moderation
+// @Accept			json
+// @Produce		json
+// @Param			input	body	commentDeleteInput	true	"input"
+// @Success		200
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		403	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/api/v1/moderation/comment [delete]
+func (r *moderationRouter) deleteComment(c echo.Context) error {
+	var input commentDeleteInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+		return err
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	userCtx := c.Get(usernameCtx)
+	username, ok := userCtx.(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+	err := r.commentService.DeleteComment(c.Request().Context(), service.CommentDeleteInput{
+		Username:  username,
+		CommentId: input.CommentId,
+		Moderate:  true,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrCommentNotFound) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,225 @@
+package v1
+
+import (
+	"API_for_SN_go/internal/mocks/servicemocks"
+	"API_for_SN_go/internal/service"
+	"API_for_SN_go/pkg/validator"
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireRoles(t *testing.T) {
+	testCases := []struct {
+		testName   string
+		role       string
+		expectCode int
+		expectBody string
+	}{
+		{
+			testName:   "moderator",
+			role:       service.RoleModerator,
+			expectCode: 200,
+			expectBody: "",
+		},
+		{
+			testName:   "admin",
+			role:       service.RoleAdmin,
+			expectCode: 200,
+			expectBody: "",
+		},
+		{
+			testName:   "user",
+			role:       service.RoleUser,
+			expectCode: 403,
+			expectBody: `{"message":"insufficient role"}` + "\n",
+		},
+		{
+			testName:   "token without role",
+			role:       "",
+			expectCode: 403,
+			expectBody: `{"message":"insufficient role"}` + "\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			e := echo.New()
+			e.GET("/", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			}, func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					c.Set(roleCtx, tc.role)
+					return next(c)
+				}
+			}, RequireRoles(service.RoleModerator, service.RoleAdmin))
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+			assert.Equal(t, tc.expectBody, w.Body.String())
+		})
+	}
+}
+
+func TestModerationRouter_deleteComment(t *testing.T) {
+	type MockBehaviour func(m *servicemocks.MockComment)
+
+	testCases := []struct {
+		testName      string
+		inputBody     string
+		mockBehaviour MockBehaviour
+		expectCode    int
+		expectBody    string
+	}{
+		{
+			testName:  "correct test",
+			inputBody: `{"comment_id": "1"}`,
+			mockBehaviour: func(m *servicemocks.MockComment) {
+				m.EXPECT().DeleteComment(gomock.Any(), service.CommentDeleteInput{Username: "moder", CommentId: "1", Moderate: true}).Return(nil)
+			},
+			expectCode: 200,
+			expectBody: "",
+		},
+		{
+			testName:      "without comment id",
+			inputBody:     `{}`,
+			mockBehaviour: func(m *servicemocks.MockComment) {},
+			expectCode:    400,
+			expectBody:    `{"message":"field CommentId is invalid"}` + "\n",
+		},
+		{
+			testName:  "comment not found",
+			inputBody: `{"comment_id": "2"}`,
+			mockBehaviour: func(m *servicemocks.MockComment) {
+				m.EXPECT().DeleteComment(gomock.Any(), service.CommentDeleteInput{Username: "moder", CommentId: "2", Moderate: true}).Return(service.ErrCommentNotFound)
+			},
+			expectCode: 400,
+			expectBody: `{"message":"comment not found"}` + "\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			comment := servicemocks.NewMockComment(ctrl)
+			tc.mockBehaviour(comment)
+
+			e := echo.New()
+			e.Validator, _ = validator.NewValidator()
+			g := e.Group("/moderation", func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					c.Set(usernameCtx, "moder")
+					return next(c)
+				}
+			})
+			newModerationRouter(g, nil, comment)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodDelete, "/moderation/comment", bytes.NewBufferString(tc.inputBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+			assert.Equal(t, tc.expectBody, w.Body.String())
+		})
+	}
+}
+
+func (s *APITestSuite) Test_moderationRouter_delete() {
+	setup := setupReactionRouterTests(s)
+	defer tearDownRouterTests(s, setup)
+
+	commentId, err := s.services.Comment.CreateComment(context.Background(), service.CommentCreateInput{
+		Username: setup.username,
+		PostId:   setup.postId,
+		Comment:  "test",
+	})
+	s.Require().NoError(err)
+
+	moderator, password := "moder", "1234"
+	s.Require().NoError(s.services.Auth.CreateUser(context.Background(), service.UserCreateInput{
+		Username:  moderator,
+		FirstName: "Petya",
+		LastName:  "Moderatorov",
+		Email:     "moder@gmail.com",
+		Password:  password,
+	}))
+	defer func() {
+		_ = s.services.Auth.DeleteUser(context.Background(), service.UserDeleteInput{Username: moderator, Password: password})
+		_ = s.services.Auth.RevokeAllSessions(context.Background(), moderator)
+	}()
+	s.Require().NoError(s.services.User.UpdateRole(context.Background(), service.UserUpdateRoleInput{
+		Username: moderator,
+		Role:     service.RoleModerator,
+	}))
+	tokens, err := s.services.Auth.CreateToken(context.Background(), service.UserAuthInput{
+		Username: moderator,
+		Password: password,
+	})
+	s.Require().NoError(err)
+
+	testCases := []struct {
+		testName   string
+		url        string
+		token      string
+		inputBody  string
+		expectCode int
+	}{
+		{
+			testName:   "user cannot moderate",
+			url:        "/api/v1/moderation/post",
+			token:      setup.token,
+			inputBody:  fmt.Sprintf(`{"post_id": "%s"}`, setup.postId),
+			expectCode: 403,
+		},
+		{
+			testName:   "delete comment",
+			url:        "/api/v1/moderation/comment",
+			token:      tokens.AccessToken,
+			inputBody:  fmt.Sprintf(`{"comment_id": "%s"}`, commentId),
+			expectCode: 200,
+		},
+		{
+			testName:   "comment already deleted",
+			url:        "/api/v1/moderation/comment",
+			token:      tokens.AccessToken,
+			inputBody:  fmt.Sprintf(`{"comment_id": "%s"}`, commentId),
+			expectCode: 400,
+		},
+		{
+			testName:   "delete post",
+			url:        "/api/v1/moderation/post",
+			token:      tokens.AccessToken,
+			inputBody:  fmt.Sprintf(`{"post_id": "%s"}`, setup.postId),
+			expectCode: 200,
+		},
+		{
+			testName:   "post already deleted",
+			url:        "/api/v1/moderation/post",
+			token:      tokens.AccessToken,
+			inputBody:  fmt.Sprintf(`{"post_id": "%s"}`, setup.postId),
+			expectCode: 400,
+		},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodDelete, tc.url, bytes.NewBufferString(tc.inputBody))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+tc.token)
+		s.router.ServeHTTP(w, req)
+		s.Assert().Equal(tc.expectCode, w.Code, tc.testName)
+	}
+	_, err = s.services.Post.GetPostById(context.Background(), setup.postId)
+	s.Assert().Equal(service.ErrPostNotFound, err)
+}
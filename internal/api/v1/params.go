@@ -0,0 +1,49 @@
+package v1
+
+import (
+	"API_for_SN_go/internal/repo"
+	"API_for_SN_go/internal/repo/pagination"
+	"github.com/labstack/echo/v4"
+	"strconv"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+
+	defaultCommentsDepth = 3
+	maxCommentsDepth     = 10
+)
+
+// Query params limit, cursor and sort. Limit cannot be greater than maxLimit, cursor is taken from next_cursor of previous page.
+// Sort is newest or oldest, if it is empty list default is used
+func parsePagination(c echo.Context) (repo.Pagination, error) {
+	p := repo.Pagination{Limit: defaultLimit, Cursor: c.QueryParam("cursor")}
+	switch s := pagination.Sort(c.QueryParam("sort")); s {
+	case "", pagination.SortNewest, pagination.SortOldest:
+		p.Sort = s
+	default:
+		return repo.Pagination{}, ErrInvalidPagination
+	}
+	if l := c.QueryParam("limit"); l != "" {
+		v, err := strconv.Atoi(l)
+		if err != nil || v <= 0 {
+			return repo.Pagination{}, ErrInvalidPagination
+		}
+		p.Limit = min(v, maxLimit)
+	}
+	return p, nil
+}
+
+// Query param depth for comments tree. Depth cannot be greater than maxCommentsDepth
+func parseCommentsDepth(c echo.Context) (int, bool) {
+	d := c.QueryParam("depth")
+	if d == "" {
+		return defaultCommentsDepth, true
+	}
+	v, err := strconv.Atoi(d)
+	if err != nil || v <= 0 {
+		return 0, false
+	}
+	return min(v, maxCommentsDepth), true
+}
@@ -1,10 +1,12 @@
 package v1
 
 import (
+	"API_for_SN_go/internal/repo"
 	"API_for_SN_go/internal/service"
 	"errors"
 	"github.com/labstack/echo/v4"
 	"net/http"
+	"time"
 )
 
 type postRouter struct {
@@ -21,6 +23,9 @@ func newPostRouter(g *echo.Group, postService service.Post, reactionService serv
 	}
 	g.POST("/create", r.create)
 	g.GET("", r.getById)
+	g.GET("/list", r.getUserPosts)
+	g.PUT("/update", r.update)
+	g.DELETE("/delete", r.delete)
 	g.GET("/comments", r.getPostComments)
 }
 
@@ -78,12 +83,12 @@ func (r *postRouter) create(c echo.Context) error {
 }
 
 // @Summary		Get post
-// @Description	Get post by id
+// @Description	Get post by id with number of reactions of each kind
 // @Tags			post
 // @Accept			json
 // @Produce		json
 // @Param			post_id	query		string	true	"post id"
-// @Success		200		{object}	map[string]string
+// @Success		200		{object}	map[string]interface{}
 // @Failure		400		{object}	echo.HTTPError
 // @Failure		500		{object}	echo.HTTPError
 // @Security		JWT
@@ -103,37 +108,55 @@ func (r *postRouter) getById(c echo.Context) error {
 		errorResponse(c, http.StatusInternalServerError, "internal server error")
 		return err
 	}
-	reactions, err := r.reactionService.GetManyReactions(c.Request().Context(), postId)
+	reactions, err := r.reactionService.GetReactionSummary(c.Request().Context(), postId)
 	if err != nil {
-		if !errors.Is(err, service.ErrReactionNotFound) {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	// реакция текущего пользователя, чтобы клиент мог показать "вы отреагировали"
+	var userReaction string
+	if username, ok := c.Get(usernameCtx).(string); ok {
+		reaction, err := r.reactionService.GetUserReaction(c.Request().Context(), postId, username)
+		if err != nil && !errors.Is(err, service.ErrReactionNotFound) {
 			errorResponse(c, http.StatusInternalServerError, "internal server error")
 			return err
 		}
-
+		userReaction = reaction.Reaction
 	}
 	type response struct {
-		Username  string            `json:"username"`
-		PostId    string            `json:"post_id"`
-		Title     string            `json:"title"`
-		Text      string            `json:"text"`
-		Reactions map[string]string `json:"reactions"`
+		Username     string         `json:"username"`
+		PostId       string         `json:"post_id"`
+		Title        string         `json:"title"`
+		Text         string         `json:"text"`
+		Reactions    map[string]int `json:"reactions"`
+		UserReaction string         `json:"user_reaction,omitempty"`
+		CreatedAt    time.Time      `json:"created_at"`
+		UpdatedAt    time.Time      `json:"updated_at"`
 	}
 	return c.JSON(http.StatusOK, response{
-		Username:  post.Username,
-		PostId:    post.PostId,
-		Title:     post.Title,
-		Text:      post.Text,
-		Reactions: reactions,
+		Username:     post.Username,
+		PostId:       post.PostId,
+		Title:        post.Title,
+		Text:         post.Text,
+		CreatedAt:    post.CreatedAt,
+		UpdatedAt:    post.UpdatedAt,
+		Reactions:    reactions,
+		UserReaction: userReaction,
 	})
 }
 
 // @Summary		Get post comments
-// @Description	Get all post comments by post id
+// @Description	Get post comments by post id, oldest first by default. Mode "flat" (default) returns list of comments, mode "tree" returns root comments with nested replies up to depth level (pagination is applied to root comments)
 // @Tags			post
 // @Accept			json
 // @Produce		json
 // @Param			post_id	query		string	true	"post id"
-// @Success		200		{object}	map[string]string
+// @Param			mode	query		string	false	"flat or tree"
+// @Param			depth	query		int		false	"max replies depth for tree mode (default 3, max 10)"
+// @Param			limit	query		int		false	"max number of comments (default 20, max 100)"
+// @Param			cursor	query		string	false	"next_cursor from previous page"
+// @Param			sort	query		string	false	"oldest (default) or newest"
+// @Success		200		{object}	map[string]interface{}
 // @Failure		400		{object}	echo.HTTPError
 // @Failure		500		{object}	echo.HTTPError
 // @Security		JWT
@@ -144,21 +167,230 @@ func (r *postRouter) getPostComments(c echo.Context) error {
 		errorResponse(c, http.StatusBadRequest, "invalid request params")
 		return nil
 	}
-	comments, err := r.commentService.GetManyComments(c.Request().Context(), "post_id", postId)
+	p, err := parsePagination(c)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	switch c.QueryParam("mode") {
+	case "", "flat":
+	case "tree":
+		return r.getPostCommentsTree(c, postId, p)
+	default:
+		errorResponse(c, http.StatusBadRequest, "invalid request params")
+		return nil
+	}
+	comments, next, err := r.commentService.GetManyComments(c.Request().Context(), "post_id", postId, p)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	type response struct {
+		PostId     string            `json:"post_id"`
+		Comments   []commentResponse `json:"comments"`
+		NextCursor string            `json:"next_cursor"`
+	}
+	return c.JSON(http.StatusOK, response{
+		PostId:     postId,
+		Comments:   newCommentsResponse(comments),
+		NextCursor: next,
+	})
+}
+
+// @Summary		Get user posts
+// @Description	Get user posts by username (current user by default). Newest posts first by default
+// @Tags			post
+// @Accept			json
+// @Produce		json
+// @Param			username	query		string	false	"username"
+// @Param			limit		query		int		false	"max number of posts (default 20, max 100)"
+// @Param			cursor		query		string	false	"next_cursor from previous page"
+// @Param			sort		query		string	false	"newest (default) or oldest"
+// @Success		200			{object}	map[string]interface{}
+// @Failure		400			{object}	echo.HTTPError
+// @Failure		500			{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/api/v1/posts/post/list [get]
+func (r *postRouter) getUserPosts(c echo.Context) error {
+	username := c.QueryParam("username")
+	if len(username) == 0 {
+		userCtx := c.Get(usernameCtx)
+		u, ok := userCtx.(string)
+		if !ok {
+			errorResponse(c, http.StatusInternalServerError, "internal server error")
+			return nil
+		}
+		username = u
+	}
+	p, err := parsePagination(c)
 	if err != nil {
-		if errors.Is(err, service.ErrCommentNotFound) {
-			errorResponse(c, http.StatusBadRequest, "comments not found")
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	posts, next, err := r.postService.GetManyPosts(c.Request().Context(), username, p)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+
+	type postResponse struct {
+		PostId    string    `json:"post_id"`
+		Title     string    `json:"title"`
+		Text      string    `json:"text"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+	}
+	type response struct {
+		Username   string         `json:"username"`
+		Posts      []postResponse `json:"posts"`
+		NextCursor string         `json:"next_cursor"`
+	}
+	res := response{
+		Username:   username,
+		Posts:      make([]postResponse, 0, len(posts)),
+		NextCursor: next,
+	}
+	for _, post := range posts {
+		res.Posts = append(res.Posts, postResponse{
+			PostId:    post.PostId,
+			Title:     post.Title,
+			Text:      post.Text,
+			CreatedAt: post.CreatedAt,
+			UpdatedAt: post.UpdatedAt,
+		})
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+type postUpdateInput struct {
+	PostId string `json:"post_id" validate:"required"`
+	Title  string `json:"title" validate:"required_without=Text"`
+	Text   string `json:"text"`
+}
+
+// @Summary		Update post
+// @Description	Update post title and/or text. Only the author can update post
+// @Tags			post
+// @Accept			json
+// @Produce		json
+// @Param			input	body	postUpdateInput	true	"input"
+// @Success		200
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/api/v1/posts/post/update [put]
+func (r *postRouter) update(c echo.Context) error {
+	var input postUpdateInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+		return err
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	userCtx := c.Get(usernameCtx)
+	username, ok := userCtx.(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+	err := r.postService.UpdatePost(c.Request().Context(), service.PostUpdateInput{
+		Username: username,
+		PostId:   input.PostId,
+		Title:    input.Title,
+		Text:     input.Text,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrPostNotFound) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+type postDeleteInput struct {
+	PostId string `json:"post_id" validate:"required"`
+}
+
+// @Summary		Delete post
+// @Description	Delete post with all its reactions and comments. Only the author can delete post
+// @Tags			post
+// @Accept			json
+// @Produce		json
+// @Param			input	body	postDeleteInput	true	"input"
+// @Success		200
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/api/v1/posts/post/delete [delete]
+func (r *postRouter) delete(c echo.Context) error {
+	var input postDeleteInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+		return err
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	userCtx := c.Get(usernameCtx)
+	username, ok := userCtx.(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+	err := r.postService.DeletePost(c.Request().Context(), service.PostDeleteInput{
+		Username: username,
+		PostId:   input.PostId,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrPostNotFound) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (r *postRouter) getPostCommentsTree(c echo.Context, postId string, p repo.Pagination) error {
+	depth, ok := parseCommentsDepth(c)
+	if !ok {
+		errorResponse(c, http.StatusBadRequest, "invalid request params")
+		return nil
+	}
+	comments, next, err := r.commentService.GetCommentTree(c.Request().Context(), postId, depth, p)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
 			return err
 		}
 		errorResponse(c, http.StatusInternalServerError, "internal server error")
 		return err
 	}
 	type response struct {
-		PostId   string            `json:"post_id"`
-		Comments map[string]string `json:"comments"`
+		PostId     string                `json:"post_id"`
+		Comments   []commentNodeResponse `json:"comments"`
+		NextCursor string                `json:"next_cursor"`
 	}
 	return c.JSON(http.StatusOK, response{
-		PostId:   postId,
-		Comments: comments,
+		PostId:     postId,
+		Comments:   newCommentTreeResponse(comments),
+		NextCursor: next,
 	})
 }
@@ -1,12 +1,19 @@
 package v1
 
 import (
+	"API_for_SN_go/internal/mocks/servicemocks"
+	"API_for_SN_go/internal/service"
+	"API_for_SN_go/pkg/validator"
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"github.com/golang/mock/gomock"
 	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
+	"testing"
 )
 
 func (s *APITestSuite) Test_postRouterCreate() {
@@ -42,3 +49,152 @@ func (s *APITestSuite) Test_postRouterCreate() {
 		}
 	}
 }
+
+func TestPostRouter_update(t *testing.T) {
+	type args struct {
+		ctx   context.Context
+		input service.PostUpdateInput
+	}
+	type MockBehaviour func(m *servicemocks.MockPost, args args)
+
+	testCases := []struct {
+		testName      string
+		args          args
+		inputBody     string
+		mockBehaviour MockBehaviour
+		expectCode    int
+		expectBody    string
+	}{
+		{
+			testName: "correct test",
+			args: args{
+				ctx: context.Background(),
+				input: service.PostUpdateInput{
+					Username: "vasek",
+					PostId:   "1000",
+					Title:    "new title",
+				},
+			},
+			inputBody: `{"post_id": "1000", "title": "new title"}`,
+			mockBehaviour: func(m *servicemocks.MockPost, args args) {
+				m.EXPECT().UpdatePost(args.ctx, args.input).Return(nil)
+			},
+			expectCode: 200,
+			expectBody: "",
+		},
+		{
+			testName: "not owner or not exists",
+			args: args{
+				ctx: context.Background(),
+				input: service.PostUpdateInput{
+					Username: "vasek",
+					PostId:   "1000",
+					Text:     "new text",
+				},
+			},
+			inputBody: `{"post_id": "1000", "text": "new text"}`,
+			mockBehaviour: func(m *servicemocks.MockPost, args args) {
+				m.EXPECT().UpdatePost(args.ctx, args.input).Return(service.ErrPostNotFound)
+			},
+			expectCode: 400,
+			expectBody: `{"message":"post not found"}` + "\n",
+		},
+		{
+			testName:      "without post id",
+			inputBody:     `{"title": "new title"}`,
+			mockBehaviour: func(m *servicemocks.MockPost, args args) {},
+			expectCode:    400,
+			expectBody:    `{"message":"field PostId is invalid"}` + "\n",
+		},
+		{
+			testName:      "nothing to update",
+			inputBody:     `{"post_id": "1000"}`,
+			mockBehaviour: func(m *servicemocks.MockPost, args args) {},
+			expectCode:    400,
+			expectBody:    `{"message":"field Title is invalid"}` + "\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			post := servicemocks.NewMockPost(ctrl)
+			tc.mockBehaviour(post, tc.args)
+			services := &service.Services{Post: post}
+
+			e := echo.New()
+			e.Validator, _ = validator.NewValidator()
+			g := e.Group("/api/v1/posts/post", func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					c.Set(usernameCtx, "vasek")
+					return next(c)
+				}
+			})
+			newPostRouter(g, services.Post, services.Reaction, services.Comment)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/posts/post/update", bytes.NewBufferString(tc.inputBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+			assert.Equal(t, tc.expectBody, w.Body.String())
+		})
+	}
+}
+
+func (s *APITestSuite) Test_postRouterDelete() {
+	setup := setupReactionRouterTests(s)
+	defer tearDownRouterTests(s, setup)
+
+	testCases := []struct {
+		testName   string
+		inputBody  string
+		expectCode int
+	}{
+		{
+			testName:   "correct test",
+			inputBody:  fmt.Sprintf(`{"post_id": "%s"}`, setup.postId),
+			expectCode: 200,
+		},
+		{
+			testName:   "already deleted",
+			inputBody:  fmt.Sprintf(`{"post_id": "%s"}`, setup.postId),
+			expectCode: 400,
+		},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/posts/post/delete", bytes.NewBufferString(tc.inputBody))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+setup.token)
+		s.router.ServeHTTP(w, req)
+		s.Assert().Equal(tc.expectCode, w.Code)
+	}
+	_, err := s.services.Post.GetPostById(context.Background(), setup.postId)
+	s.Assert().Equal(service.ErrPostNotFound, err)
+}
+
+func (s *APITestSuite) Test_postRouterList() {
+	setup := setupReactionRouterTests(s)
+	defer tearDownRouterTests(s, setup)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/posts/post/list?limit=10", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+setup.token)
+	s.router.ServeHTTP(w, req)
+	s.Assert().Equal(http.StatusOK, w.Code)
+
+	var response struct {
+		Username string `json:"username"`
+		Posts    []struct {
+			PostId string `json:"post_id"`
+		} `json:"posts"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	s.Assert().Equal(setup.username, response.Username)
+	s.Assert().Len(response.Posts, 1)
+	s.Assert().Equal(setup.postId, response.Posts[0].PostId)
+}
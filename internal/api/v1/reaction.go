@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/labstack/echo/v4"
 	"net/http"
+	"time"
 )
 
 type reactionRouter struct {
@@ -15,6 +16,7 @@ func newReactionRouter(g *echo.Group, reactionService service.Reaction) {
 	r := &reactionRouter{reactionService: reactionService}
 	g.POST("/create", r.create)
 	g.GET("", r.getReactionById)
+	g.GET("/summary", r.getSummary)
 	g.DELETE("/delete", r.deleteReaction)
 }
 
@@ -24,12 +26,13 @@ type reactionCreateInput struct {
 }
 
 // @Summary		Create reaction
-// @Description	Create reaction for post
+// @Description	Create reaction for post. User can have only one reaction per post: new reaction replaces previous one, same reaction removes it
 // @Tags			reaction
 // @Accept			json
 // @Produce		json
 // @Param			input	body		reactionCreateInput	true	"input"
 // @Success		201		{object}	map[string]string
+// @Success		200		"reaction removed"
 // @Failure		400		{object}	echo.HTTPError
 // @Failure		500		{object}	echo.HTTPError
 // @Security		JWT
@@ -45,8 +48,15 @@ func (r *reactionRouter) create(c echo.Context) error {
 		errorResponse(c, http.StatusBadRequest, err.Error())
 		return err
 	}
+	userCtx := c.Get(usernameCtx)
+	username, ok := userCtx.(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
 
 	reactionId, err := r.reactionService.CreateReaction(c.Request().Context(), service.ReactionCreateInput{
+		Username: username,
 		PostId:   input.PostId,
 		Reaction: input.Reaction,
 	})
@@ -62,6 +72,9 @@ func (r *reactionRouter) create(c echo.Context) error {
 		errorResponse(c, http.StatusInternalServerError, err.Error())
 		return err
 	}
+	if reactionId == "" {
+		return c.NoContent(http.StatusOK)
+	}
 
 	type response struct {
 		ReactionId string `json:"reaction_id"`
@@ -97,14 +110,53 @@ func (r *reactionRouter) getReactionById(c echo.Context) error {
 	}
 
 	type response struct {
-		PostId     string `json:"post_id"`
-		ReactionId string `json:"reaction_id"`
-		Reaction   string `json:"reaction"`
+		Username   string    `json:"username"`
+		PostId     string    `json:"post_id"`
+		ReactionId string    `json:"reaction_id"`
+		Reaction   string    `json:"reaction"`
+		CreatedAt  time.Time `json:"created_at"`
+		UpdatedAt  time.Time `json:"updated_at"`
 	}
 	return c.JSON(http.StatusOK, response{
+		Username:   reaction.Username,
 		PostId:     reaction.PostId,
 		ReactionId: reaction.ReactionId,
 		Reaction:   reaction.Reaction,
+		CreatedAt:  reaction.CreatedAt,
+		UpdatedAt:  reaction.UpdatedAt,
+	})
+}
+
+// @Summary		Get reaction summary
+// @Description	Get number of reactions of each kind for post
+// @Tags			reaction
+// @Accept			json
+// @Produce		json
+// @Param			post_id	query		string	true	"post id"
+// @Success		200		{object}	map[string]interface{}
+// @Failure		400		{object}	echo.HTTPError
+// @Failure		500		{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/api/v1/posts/reaction/summary [get]
+func (r *reactionRouter) getSummary(c echo.Context) error {
+	postId := c.QueryParam("post_id")
+	if len(postId) == 0 {
+		errorResponse(c, http.StatusBadRequest, "invalid request params")
+		return nil
+	}
+	reactions, err := r.reactionService.GetReactionSummary(c.Request().Context(), postId)
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+
+	type response struct {
+		PostId    string         `json:"post_id"`
+		Reactions map[string]int `json:"reactions"`
+	}
+	return c.JSON(http.StatusOK, response{
+		PostId:    postId,
+		Reactions: reactions,
 	})
 }
 
@@ -113,7 +165,7 @@ type reactionDeleteInput struct {
 }
 
 // @Summary		Delete reaction
-// @Description	Delete reaction for post by id
+// @Description	Delete reaction for post by id. Only the author can delete reaction
 // @Tags			reaction
 // @Accept			json
 // @Produce		json
@@ -130,7 +182,16 @@ func (r *reactionRouter) deleteReaction(c echo.Context) error {
 		errorResponse(c, http.StatusBadRequest, "invalid request body")
 		return err
 	}
-	err := r.reactionService.DeleteReaction(c.Request().Context(), input.ReactionId)
+	userCtx := c.Get(usernameCtx)
+	username, ok := userCtx.(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+	err := r.reactionService.DeleteReaction(c.Request().Context(), service.ReactionDeleteInput{
+		Username:   username,
+		ReactionId: input.ReactionId,
+	})
 	if err != nil {
 		if errors.Is(err, service.ErrReactionNotFound) {
 			errorResponse(c, http.StatusBadRequest, err.Error())
@@ -13,6 +13,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 )
 
@@ -36,6 +37,7 @@ func TestReactionRouter_create(t *testing.T) {
 			args: args{
 				ctx: context.Background(),
 				input: service.ReactionCreateInput{
+					Username: "vasek",
 					PostId:   "1000",
 					Reaction: "like",
 				},
@@ -47,6 +49,23 @@ func TestReactionRouter_create(t *testing.T) {
 			expectCode: 201,
 			expectBody: `{"reaction_id":"1234567890"}` + "\n",
 		},
+		{
+			testName: "repeated reaction removes it",
+			args: args{
+				ctx: context.Background(),
+				input: service.ReactionCreateInput{
+					Username: "vasek",
+					PostId:   "1000",
+					Reaction: "like",
+				},
+			},
+			inputBody: `{"post_id": "1000", "reaction": "like"}`,
+			mockBehaviour: func(m *servicemocks.MockReaction, args args) {
+				m.EXPECT().CreateReaction(args.ctx, args.input).Return("", nil)
+			},
+			expectCode: 200,
+			expectBody: "",
+		},
 		{
 			testName:      "incorrect reaction input",
 			inputBody:     `{"post_id": "1000", "reaction": "321boom@"}`,
@@ -87,7 +106,13 @@ func TestReactionRouter_create(t *testing.T) {
 
 			e := echo.New()
 			e.Validator, _ = validator.NewValidator()
-			newReactionRouter(e.Group("/api/v1/posts/reaction"), services.Reaction)
+			g := e.Group("/api/v1/posts/reaction", func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					c.Set(usernameCtx, "vasek")
+					return next(c)
+				}
+			})
+			newReactionRouter(g, services.Reaction)
 
 			w := httptest.NewRecorder()
 			req := httptest.NewRequest(http.MethodPost, "/api/v1/posts/reaction/create", bytes.NewBufferString(tc.inputBody))
@@ -101,6 +126,53 @@ func TestReactionRouter_create(t *testing.T) {
 	}
 }
 
+func TestReactionRouter_getSummary(t *testing.T) {
+	testCases := []struct {
+		testName      string
+		query         string
+		mockBehaviour func(m *servicemocks.MockReaction)
+		expectCode    int
+		expectBody    string
+	}{
+		{
+			testName: "correct test",
+			query:    "?post_id=1000",
+			mockBehaviour: func(m *servicemocks.MockReaction) {
+				m.EXPECT().GetReactionSummary(context.Background(), "1000").Return(map[string]int{"like": 120, "fire": 4}, nil)
+			},
+			expectCode: 200,
+			expectBody: `{"post_id":"1000","reactions":{"fire":4,"like":120}}` + "\n",
+		},
+		{
+			testName:      "without post id",
+			query:         "",
+			mockBehaviour: func(m *servicemocks.MockReaction) {},
+			expectCode:    400,
+			expectBody:    `{"message":"invalid request params"}` + "\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			reaction := servicemocks.NewMockReaction(ctrl)
+			tc.mockBehaviour(reaction)
+
+			e := echo.New()
+			newReactionRouter(e.Group("/api/v1/posts/reaction"), reaction)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/posts/reaction/summary"+tc.query, nil)
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+			assert.Equal(t, tc.expectBody, w.Body.String())
+		})
+	}
+}
+
 type reactionTestInfo struct {
 	*apiTestsInfo
 	postId string
@@ -140,6 +212,16 @@ func (s *APITestSuite) Test_reactionRouter_create() {
 			inputBody:  fmt.Sprintf(`{"post_id": "%s", "reaction": "like"}`, setup.postId),
 			expectCode: 201,
 		},
+		{
+			testName:   "replace reaction",
+			inputBody:  fmt.Sprintf(`{"post_id": "%s", "reaction": "fire"}`, setup.postId),
+			expectCode: 201,
+		},
+		{
+			testName:   "same reaction removes it",
+			inputBody:  fmt.Sprintf(`{"post_id": "%s", "reaction": "fire"}`, setup.postId),
+			expectCode: 200,
+		},
 		{
 			testName:   "incorrect post id",
 			inputBody:  `{"post_id": "0", "reaction": "boom"}`,
@@ -158,8 +240,39 @@ func (s *APITestSuite) Test_reactionRouter_create() {
 				ReactionId string `json:"reaction_id"`
 			}
 			_ = json.Unmarshal(w.Body.Bytes(), &response)
-			_, err := s.services.Reaction.GetReactionById(context.Background(), response.ReactionId)
+			reaction, err := s.services.Reaction.GetReactionById(context.Background(), response.ReactionId)
 			s.Assert().Equal(nil, err)
+			s.Assert().Equal(setup.username, reaction.Username)
 		}
 	}
+	_, err := s.services.Reaction.GetUserReaction(context.Background(), setup.postId, setup.username)
+	s.Assert().Equal(service.ErrReactionNotFound, err)
+}
+
+func (s *APITestSuite) Test_reactionService_concurrentCreate() {
+	setup := setupReactionRouterTests(s)
+	defer tearDownRouterTests(s, setup)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.services.Reaction.CreateReaction(context.Background(), service.ReactionCreateInput{
+				Username: setup.username,
+				PostId:   setup.postId,
+				Reaction: fmt.Sprintf("r%d", i),
+			})
+			s.Assert().Equal(nil, err)
+		}(i)
+	}
+	wg.Wait()
+
+	counts, err := s.services.Reaction.GetReactionSummary(context.Background(), setup.postId)
+	s.Assert().Equal(nil, err)
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	s.Assert().Equal(1, total)
 }
@@ -12,15 +12,19 @@ func NewRouter(h *echo.Echo, services *service.Services) {
 	h.Use(middleware.Recover())
 	h.GET("/ping", ping)
 	h.GET("/swagger/*", echoSwagger.WrapHandler)
+	newWellKnownRouter(h.Group("/.well-known"), services.Auth)
 
-	newAuthRouter(h.Group("/auth"), services.Auth)
-	authMiddleware := &AuthMiddleware{auth: services.Auth}
+	authMiddleware := &AuthMiddleware{auth: services.Auth, user: services.User}
+	newAuthRouter(h.Group("/auth"), services.Auth, authMiddleware)
 	v1 := h.Group("/api/v1", authMiddleware.AuthHandler)
 
-	newUserRouter(v1.Group("/user"), services.User, services.Comment)
-	newPostRouter(v1.Group("/posts/post"), services.Post, services.Reaction, services.Comment)
-	newReactionRouter(v1.Group("/posts/reaction"), services.Reaction)
-	newCommentRouter(v1.Group("/posts/comment"), services.Comment)
+	newUserRouter(v1.Group("/user", RequireScope("users")), services.User, services.Comment, services.Follow)
+	newPostRouter(v1.Group("/posts/post", RequireScope("posts")), services.Post, services.Reaction, services.Comment)
+	newReactionRouter(v1.Group("/posts/reaction", RequireScope("reactions")), services.Reaction)
+	newCommentRouter(v1.Group("/posts/comment", RequireScope("comments")), services.Comment)
+	newFeedRouter(v1.Group("/feed", RequireScope("feed")), services.Post)
+	newModerationRouter(v1.Group("/moderation", RequireRoles(service.RoleModerator, service.RoleAdmin), RequireScope("moderation")), services.Post, services.Comment)
+	newAdminRouter(v1.Group("/admin", RequireRoles(service.RoleAdmin), RequireScope("admin")), services.User, services.Admin)
 }
 
 func ping(c echo.Context) error {
@@ -1,25 +1,35 @@
 package v1
 
 import (
+	"API_for_SN_go/internal/model/pgmodel"
+	"API_for_SN_go/internal/repo"
 	"API_for_SN_go/internal/service"
+	"context"
 	"errors"
 	"github.com/labstack/echo/v4"
 	"net/http"
+	"time"
 )
 
 type userRouter struct {
 	userService    service.User
 	commentService service.Comment
+	followService  service.Follow
 }
 
-func newUserRouter(g *echo.Group, userService service.User, commentService service.Comment) {
+func newUserRouter(g *echo.Group, userService service.User, commentService service.Comment, followService service.Follow) {
 	r := &userRouter{
 		userService:    userService,
 		commentService: commentService,
+		followService:  followService,
 	}
 	g.GET("/comments", r.getUserComments)
 	g.GET("", r.getUser)
 	g.PUT("/update/full-name", r.updateFullName)
+	g.POST("/follow", r.follow)
+	g.DELETE("/unfollow", r.unfollow)
+	g.GET("/followers", r.getFollowers)
+	g.GET("/following", r.getFollowing)
 }
 
 type userUpdateFullNameInput struct {
@@ -68,12 +78,12 @@ func (r *userRouter) updateFullName(c echo.Context) error {
 }
 
 // @Summary		Get user
-// @Description	Get user by username
+// @Description	Get user by username with number of followers and following
 // @Tags			user
 // @Accept			json
 // @Produce		json
 // @Param			username	query		string	true	"username"
-// @Success		200			{object}	map[string]string
+// @Success		200			{object}	map[string]interface{}
 // @Failure		400			{object}	echo.HTTPError
 // @Failure		500			{object}	echo.HTTPError
 // @Security		JWT
@@ -93,27 +103,47 @@ func (r *userRouter) getUser(c echo.Context) error {
 		errorResponse(c, http.StatusInternalServerError, "internal server error")
 		return err
 	}
+	counts, err := r.followService.GetFollowCounts(c.Request().Context(), username)
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
 	type response struct {
-		Username  string `json:"username"`
-		FirstName string `json:"first_name"`
-		LastName  string `json:"last_name"`
-		Email     string `json:"email"`
+		Username      string    `json:"username"`
+		FirstName     string    `json:"first_name"`
+		LastName      string    `json:"last_name"`
+		Email         string    `json:"email"`
+		EmailVerified bool      `json:"email_verified"`
+		Role          string    `json:"role"`
+		Followers     int       `json:"followers_count"`
+		Following     int       `json:"following_count"`
+		CreatedAt     time.Time `json:"created_at"`
+		UpdatedAt     time.Time `json:"updated_at"`
 	}
 	return c.JSON(http.StatusOK, response{
-		Username:  user.Username,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Email:     user.Email,
+		Username:      user.Username,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Role:          user.Role,
+		Followers:     counts.Followers,
+		Following:     counts.Following,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
 	})
 }
 
 // @Summary		Get user comments
-// @Description	Get all user comments
+// @Description	Get user comments, oldest first by default
 // @Tags			user
 // @Accept			json
 // @Produce		json
 // @Param			username	query		string	false	"username"
-// @Success		200			{object}	map[string]string
+// @Param			limit		query		int		false	"max number of comments (default 20, max 100)"
+// @Param			cursor		query		string	false	"next_cursor from previous page"
+// @Param			sort		query		string	false	"oldest (default) or newest"
+// @Success		200			{object}	map[string]interface{}
 // @Failure		400			{object}	echo.HTTPError
 // @Failure		500			{object}	echo.HTTPError
 // @Security		JWT
@@ -133,17 +163,201 @@ func (r *userRouter) getUserComments(c echo.Context) error {
 		u = username
 	}
 
-	comments, err := r.commentService.GetManyComments(c.Request().Context(), "username", u)
+	p, err := parsePagination(c)
 	if err != nil {
-		if errors.Is(err, service.ErrCommentNotFound) {
-			errorResponse(c, http.StatusBadRequest, "comments not found")
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	comments, next, err := r.commentService.GetManyComments(c.Request().Context(), "username", u, p)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
 			return err
 		}
 		errorResponse(c, http.StatusInternalServerError, "internal server error")
 		return err
 	}
 	type response struct {
-		Comments map[string]string `json:"comments"`
+		Comments   []commentResponse `json:"comments"`
+		NextCursor string            `json:"next_cursor"`
+	}
+	return c.JSON(http.StatusOK, response{Comments: newCommentsResponse(comments), NextCursor: next})
+}
+
+type userFollowInput struct {
+	Username string `json:"username" validate:"required"`
+}
+
+// @Summary		Follow user
+// @Description	Follow user by username
+// @Tags			user
+// @Accept			json
+// @Produce		json
+// @Param			input	body	userFollowInput	true	"input"
+// @Success		201
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/api/v1/user/follow [post]
+func (r *userRouter) follow(c echo.Context) error {
+	var input userFollowInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+		return err
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	userCtx := c.Get(usernameCtx)
+	username, ok := userCtx.(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+	err := r.followService.Follow(c.Request().Context(), service.FollowInput{
+		Follower: username,
+		Followee: input.Username,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrCannotFollowSelf) || errors.Is(err, service.ErrAlreadyFollowing) || errors.Is(err, service.ErrUserNotFound) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	return c.NoContent(http.StatusCreated)
+}
+
+// @Summary		Unfollow user
+// @Description	Unfollow user by username
+// @Tags			user
+// @Accept			json
+// @Produce		json
+// @Param			input	body	userFollowInput	true	"input"
+// @Success		200
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/api/v1/user/unfollow [delete]
+func (r *userRouter) unfollow(c echo.Context) error {
+	var input userFollowInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+		return err
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	userCtx := c.Get(usernameCtx)
+	username, ok := userCtx.(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+	err := r.followService.Unfollow(c.Request().Context(), service.FollowInput{
+		Follower: username,
+		Followee: input.Username,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrNotFollowing) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// @Summary		Get user followers
+// @Description	Get users who follow user (current user by default), recent followers first by default
+// @Tags			user
+// @Accept			json
+// @Produce		json
+// @Param			username	query		string	false	"username"
+// @Param			limit		query		int		false	"max number of users (default 20, max 100)"
+// @Param			cursor		query		string	false	"next_cursor from previous page"
+// @Param			sort		query		string	false	"newest (default) or oldest"
+// @Success		200			{object}	map[string]interface{}
+// @Failure		400			{object}	echo.HTTPError
+// @Failure		500			{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/api/v1/user/followers [get]
+func (r *userRouter) getFollowers(c echo.Context) error {
+	return r.getFollows(c, r.followService.GetFollowers, func(f pgmodel.Follow) string { return f.Follower })
+}
+
+// @Summary		Get user following
+// @Description	Get users followed by user (current user by default), recent first by default
+// @Tags			user
+// @Accept			json
+// @Produce		json
+// @Param			username	query		string	false	"username"
+// @Param			limit		query		int		false	"max number of users (default 20, max 100)"
+// @Param			cursor		query		string	false	"next_cursor from previous page"
+// @Param			sort		query		string	false	"newest (default) or oldest"
+// @Success		200			{object}	map[string]interface{}
+// @Failure		400			{object}	echo.HTTPError
+// @Failure		500			{object}	echo.HTTPError
+// @Security		JWT
+// @Router			/api/v1/user/following [get]
+func (r *userRouter) getFollowing(c echo.Context) error {
+	return r.getFollows(c, r.followService.GetFollowing, func(f pgmodel.Follow) string { return f.Followee })
+}
+
+type getFollowsFunc func(ctx context.Context, username string, p repo.Pagination) ([]pgmodel.Follow, string, error)
+
+// общая часть followers и following: отличаются только запросом и тем, какого пользователя из связи показываем
+func (r *userRouter) getFollows(c echo.Context, get getFollowsFunc, other func(pgmodel.Follow) string) error {
+	username := c.QueryParam("username")
+	if len(username) == 0 {
+		userCtx := c.Get(usernameCtx)
+		u, ok := userCtx.(string)
+		if !ok {
+			errorResponse(c, http.StatusInternalServerError, "internal server error")
+			return nil
+		}
+		username = u
+	}
+	p, err := parsePagination(c)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	follows, next, err := get(c.Request().Context(), username, p)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+		errorResponse(c, http.StatusInternalServerError, "internal server error")
+		return err
+	}
+
+	type followResponse struct {
+		Username   string    `json:"username"`
+		FollowedAt time.Time `json:"followed_at"`
+	}
+	type response struct {
+		Username   string           `json:"username"`
+		Users      []followResponse `json:"users"`
+		NextCursor string           `json:"next_cursor"`
+	}
+	res := response{
+		Username:   username,
+		Users:      make([]followResponse, 0, len(follows)),
+		NextCursor: next,
+	}
+	for _, f := range follows {
+		res.Users = append(res.Users, followResponse{
+			Username:   other(f),
+			FollowedAt: f.CreatedAt,
+		})
 	}
-	return c.JSON(http.StatusOK, response{Comments: comments})
+	return c.JSON(http.StatusOK, res)
 }
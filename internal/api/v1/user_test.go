@@ -0,0 +1,194 @@
+package v1
+
+import (
+	"API_for_SN_go/internal/mocks/servicemocks"
+	"API_for_SN_go/internal/service"
+	"API_for_SN_go/pkg/validator"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUserRouter_follow(t *testing.T) {
+	type args struct {
+		ctx   context.Context
+		input service.FollowInput
+	}
+	type MockBehaviour func(m *servicemocks.MockFollow, args args)
+
+	testCases := []struct {
+		testName      string
+		args          args
+		inputBody     string
+		mockBehaviour MockBehaviour
+		expectCode    int
+		expectBody    string
+	}{
+		{
+			testName: "correct test",
+			args: args{
+				ctx:   context.Background(),
+				input: service.FollowInput{Follower: "vasek", Followee: "petya"},
+			},
+			inputBody: `{"username": "petya"}`,
+			mockBehaviour: func(m *servicemocks.MockFollow, args args) {
+				m.EXPECT().Follow(args.ctx, args.input).Return(nil)
+			},
+			expectCode: 201,
+			expectBody: "",
+		},
+		{
+			testName: "follow yourself",
+			args: args{
+				ctx:   context.Background(),
+				input: service.FollowInput{Follower: "vasek", Followee: "vasek"},
+			},
+			inputBody: `{"username": "vasek"}`,
+			mockBehaviour: func(m *servicemocks.MockFollow, args args) {
+				m.EXPECT().Follow(args.ctx, args.input).Return(service.ErrCannotFollowSelf)
+			},
+			expectCode: 400,
+			expectBody: `{"message":"cannot follow yourself"}` + "\n",
+		},
+		{
+			testName: "user not exists",
+			args: args{
+				ctx:   context.Background(),
+				input: service.FollowInput{Follower: "vasek", Followee: "nobody"},
+			},
+			inputBody: `{"username": "nobody"}`,
+			mockBehaviour: func(m *servicemocks.MockFollow, args args) {
+				m.EXPECT().Follow(args.ctx, args.input).Return(service.ErrUserNotFound)
+			},
+			expectCode: 400,
+			expectBody: `{"message":"user not found"}` + "\n",
+		},
+		{
+			testName:      "without username",
+			inputBody:     `{}`,
+			mockBehaviour: func(m *servicemocks.MockFollow, args args) {},
+			expectCode:    400,
+			expectBody:    `{"message":"field Username is invalid"}` + "\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			follow := servicemocks.NewMockFollow(ctrl)
+			tc.mockBehaviour(follow, tc.args)
+			services := &service.Services{Follow: follow}
+
+			e := echo.New()
+			e.Validator, _ = validator.NewValidator()
+			g := e.Group("/api/v1/user", func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					c.Set(usernameCtx, "vasek")
+					return next(c)
+				}
+			})
+			newUserRouter(g, services.User, services.Comment, services.Follow)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/user/follow", bytes.NewBufferString(tc.inputBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+			assert.Equal(t, tc.expectBody, w.Body.String())
+		})
+	}
+}
+
+func (s *APITestSuite) Test_userRouterFollow() {
+	setup := setupApiTests(s)
+	defer tearDownApiTests(s, setup)
+
+	followee := &apiTestsInfo{username: "petya", password: "4321"}
+	err := s.services.Auth.CreateUser(context.Background(), service.UserCreateInput{
+		Username:  followee.username,
+		FirstName: "Petya",
+		LastName:  "Petrov",
+		Email:     "test2",
+		Password:  followee.password,
+	})
+	s.Require().NoError(err)
+	defer func() {
+		_ = s.services.Auth.DeleteUser(context.Background(), service.UserDeleteInput{
+			Username: followee.username,
+			Password: followee.password,
+		})
+	}()
+
+	testCases := []struct {
+		testName   string
+		method     string
+		path       string
+		expectCode int
+	}{
+		{
+			testName:   "follow",
+			method:     http.MethodPost,
+			path:       "/api/v1/user/follow",
+			expectCode: 201,
+		},
+		{
+			testName:   "follow twice",
+			method:     http.MethodPost,
+			path:       "/api/v1/user/follow",
+			expectCode: 400,
+		},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tc.method, tc.path, bytes.NewBufferString(`{"username": "petya"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+setup.token)
+		s.router.ServeHTTP(w, req)
+		s.Assert().Equal(tc.expectCode, w.Code, tc.testName)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/user/followers?username=petya", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+setup.token)
+	s.router.ServeHTTP(w, req)
+	s.Assert().Equal(http.StatusOK, w.Code)
+
+	var followers struct {
+		Users []struct {
+			Username string `json:"username"`
+		} `json:"users"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &followers)
+	s.Require().Len(followers.Users, 1)
+	s.Assert().Equal(setup.username, followers.Users[0].Username)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/user?username=petya", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+setup.token)
+	s.router.ServeHTTP(w, req)
+	s.Assert().Equal(http.StatusOK, w.Code)
+
+	var profile struct {
+		Followers int `json:"followers_count"`
+		Following int `json:"following_count"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &profile)
+	s.Assert().Equal(1, profile.Followers)
+	s.Assert().Equal(0, profile.Following)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodDelete, "/api/v1/user/unfollow", bytes.NewBufferString(`{"username": "petya"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+setup.token)
+	s.router.ServeHTTP(w, req)
+	s.Assert().Equal(http.StatusOK, w.Code)
+}
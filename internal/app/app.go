@@ -9,9 +9,11 @@ import (
 	"API_for_SN_go/pkg/grpcserver"
 	"API_for_SN_go/pkg/hasher"
 	"API_for_SN_go/pkg/httpserver"
+	"API_for_SN_go/pkg/mailer"
 	"API_for_SN_go/pkg/postgres"
 	"API_for_SN_go/pkg/redis"
 	"API_for_SN_go/pkg/validator"
+	"encoding/base64"
 	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
 	log "github.com/sirupsen/logrus"
@@ -55,12 +57,44 @@ func Run() {
 	rdb := redis.NewRedis(cfg.Redis.Url, redis.MaxPoolSize(cfg.Redis.MaxPoolSize))
 	defer rdb.Close()
 
+	// mailer writes outgoing mail to file or stdout
+	var m mailer.Mailer = mailer.NewLogMailer(os.Stdout, mailer.From(cfg.Mailer.From))
+	if cfg.Mailer.File != "" {
+		m, err = mailer.NewFileMailer(cfg.Mailer.File, mailer.From(cfg.Mailer.From))
+		if err != nil {
+			log.Fatalf("Initializing mailer error: %s", err)
+		}
+	}
+
+	// ключ шифрования ключей подписи проверен в конфиге
+	kek, err := base64.StdEncoding.DecodeString(cfg.JWT.KeyEncryptionKey)
+	if err != nil {
+		log.Fatalf("Decoding key encryption key error: %s", err)
+	}
+
 	dependencies := service.ServicesDependencies{
-		Repos:    repos,
-		Hasher:   hasher.NewHasher(cfg.Hasher.Salt),
-		Redis:    rdb,
-		SignKey:  cfg.JWT.SignKey,
-		TokenTTL: cfg.JWT.TokenTTL,
+		Repos:            repos,
+		Hasher:           hasher.NewArgon2Hasher(cfg.Hasher.Salt),
+		Redis:            rdb,
+		Mailer:           m,
+		SignKey:          cfg.JWT.SignKey,
+		SignAlgorithm:    cfg.JWT.Algorithm,
+		KeyEncryptionKey: kek,
+		KeyRotation:      cfg.JWT.KeyRotation,
+		TokenTTL:         cfg.JWT.TokenTTL,
+		RefreshTokenTTL:  cfg.JWT.RefreshTokenTTL,
+		ResetTokenTTL:    cfg.Password.ResetTokenTTL,
+		EmailTokenTTL:    cfg.JWT.EmailTokenTTL,
+		PublicURL:        cfg.HTTP.PublicURL,
+		TOTPIssuer:       cfg.TOTP.Issuer,
+		ChallengeTTL:     cfg.TOTP.ChallengeTTL,
+		Lockout: service.LockoutConfig{
+			MaxUserFailures: cfg.SignIn.MaxUserFailures,
+			MaxIPFailures:   cfg.SignIn.MaxIPFailures,
+			FailureWindow:   cfg.SignIn.FailureWindow,
+			BaseLockout:     cfg.SignIn.LockoutBase,
+			MaxLockout:      cfg.SignIn.LockoutMax,
+		},
 	}
 	services := service.NewServices(dependencies)
 
@@ -1,10 +1,14 @@
 package authgrpc
 
 import (
+	"API_for_SN_go/internal/grpc/grpcerr"
 	"API_for_SN_go/internal/service"
 	pb "API_for_SN_go/proto/auth"
 	"context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
 )
 
 type authGrpc struct {
@@ -17,21 +21,107 @@ func NewAuthGrpc(g *grpc.Server, authService service.Auth) {
 }
 
 func (g *authGrpc) SignIn(ctx context.Context, in *pb.SignInRequest) (*pb.SignInResponse, error) {
-	token, err := g.authService.CreateToken(ctx, service.UserAuthInput{
+	if err := grpcerr.Required(map[string]string{"username": in.Username, "password": in.Password}); err != nil {
+		return nil, err
+	}
+	device, ip := clientInfo(ctx)
+	tokens, err := g.authService.CreateToken(ctx, service.UserAuthInput{
 		Username: in.Username,
 		Password: in.Password,
+		Device:   device,
+		IP:       ip,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &pb.SignInResponse{Token: token}, nil
+	return &pb.SignInResponse{Token: tokens.AccessToken, RefreshToken: tokens.RefreshToken, ChallengeToken: tokens.ChallengeToken}, nil
+}
+
+func (g *authGrpc) SignInTwoFactor(ctx context.Context, in *pb.SignInTwoFactorRequest) (*pb.SignInResponse, error) {
+	if err := grpcerr.Required(map[string]string{"challenge_token": in.ChallengeToken, "code": in.Code}); err != nil {
+		return nil, err
+	}
+	device, ip := clientInfo(ctx)
+	tokens, err := g.authService.VerifyTOTP(ctx, service.TOTPVerifyInput{
+		ChallengeToken: in.ChallengeToken,
+		Code:           in.Code,
+		Device:         device,
+		IP:             ip,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SignInResponse{Token: tokens.AccessToken, RefreshToken: tokens.RefreshToken}, nil
 }
 
 func (g *authGrpc) RefreshToken(ctx context.Context, in *pb.RefreshTokenRequest) (*pb.RefreshTokenResponse, error) {
-	token, err := g.authService.RefreshToken(ctx, in.Token)
+	if err := grpcerr.Required(map[string]string{"refresh_token": in.RefreshToken}); err != nil {
+		return nil, err
+	}
+	tokens, err := g.authService.RefreshToken(ctx, in.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.RefreshTokenResponse{Token: tokens.AccessToken, RefreshToken: tokens.RefreshToken}, nil
+}
+
+func (g *authGrpc) SignOut(ctx context.Context, in *pb.SignOutRequest) (*pb.SignOutResponse, error) {
+	if err := grpcerr.Required(map[string]string{"token": in.Token}); err != nil {
+		return nil, err
+	}
+	if err := g.authService.SignOut(ctx, in.Token); err != nil {
+		return nil, err
+	}
+	return &pb.SignOutResponse{}, nil
+}
+
+func (g *authGrpc) ValidateToken(ctx context.Context, in *pb.ValidateTokenRequest) (*pb.ValidateTokenResponse, error) {
+	if err := grpcerr.Required(map[string]string{"token": in.Token}); err != nil {
+		return nil, err
+	}
+	claims, err := g.authService.ValidateToken(ctx, in.Token)
 	if err != nil {
 		return nil, err
 	}
-	return &pb.RefreshTokenResponse{Token: token}, nil
+	role := claims.Role
+	if role == "" {
+		// токены, выпущенные до появления ролей
+		role = service.RoleUser
+	}
+	return &pb.ValidateTokenResponse{
+		Username:      claims.Username,
+		Roles:         []string{role},
+		SessionId:     claims.SessionId,
+		ExpiresAt:     claims.ExpiresAt,
+		AccessTokenId: claims.AccessTokenId,
+		Scopes:        claims.Scopes,
+	}, nil
+}
+
+func (g *authGrpc) RevokeToken(ctx context.Context, in *pb.RevokeTokenRequest) (*pb.RevokeTokenResponse, error) {
+	if err := grpcerr.Required(map[string]string{"token": in.Token}); err != nil {
+		return nil, err
+	}
+	if err := g.authService.RevokeToken(ctx, in.Token); err != nil {
+		return nil, err
+	}
+	return &pb.RevokeTokenResponse{}, nil
+}
+
+// Client device (user agent) and ip address of incoming call for session info
+func clientInfo(ctx context.Context) (string, string) {
+	var device, ip string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ua := md.Get("user-agent"); len(ua) != 0 {
+			device = ua[0]
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+	return device, ip
 }
@@ -0,0 +1,266 @@
+package authgrpc
+
+import (
+	"API_for_SN_go/internal/grpc/grpcerr"
+	"API_for_SN_go/internal/mocks/servicemocks"
+	"API_for_SN_go/internal/service"
+	pb "API_for_SN_go/proto/auth"
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"testing"
+)
+
+// call runs handler with error interceptor of grpc server, so errors are checked as client gets them
+func call(ctx context.Context, req interface{}, handler grpc.UnaryHandler) (interface{}, error) {
+	return grpcerr.UnaryServerInterceptor()(ctx, req, &grpc.UnaryServerInfo{FullMethod: "/auth.Auth/Test"}, handler)
+}
+
+func TestAuthGrpc_validateToken(t *testing.T) {
+	type args struct {
+		ctx   context.Context
+		token string
+	}
+	type MockBehaviour func(m *servicemocks.MockAuth, args args)
+
+	testCases := []struct {
+		testName      string
+		args          args
+		mockBehaviour MockBehaviour
+		expectCode    codes.Code
+		expectMessage string
+		expectResp    *pb.ValidateTokenResponse
+	}{
+		{
+			testName: "session token",
+			args:     args{ctx: context.Background(), token: "token"},
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				claims := &service.TokenClaims{Username: "vasek", Role: service.RoleModerator, SessionId: "laptop"}
+				claims.ExpiresAt = 1700000000
+				m.EXPECT().ValidateToken(args.ctx, args.token).Return(claims, nil)
+			},
+			expectCode: codes.OK,
+			expectResp: &pb.ValidateTokenResponse{
+				Username:  "vasek",
+				Roles:     []string{service.RoleModerator},
+				SessionId: "laptop",
+				ExpiresAt: 1700000000,
+			},
+		},
+		{
+			testName: "personal access token",
+			args:     args{ctx: context.Background(), token: "pat"},
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().ValidateToken(args.ctx, args.token).Return(&service.TokenClaims{
+					Username:      "vasek",
+					Role:          service.RoleUser,
+					AccessTokenId: "token1",
+					Scopes:        []string{"posts:read"},
+				}, nil)
+			},
+			expectCode: codes.OK,
+			expectResp: &pb.ValidateTokenResponse{
+				Username:      "vasek",
+				Roles:         []string{service.RoleUser},
+				AccessTokenId: "token1",
+				Scopes:        []string{"posts:read"},
+			},
+		},
+		{
+			testName: "token without role",
+			args:     args{ctx: context.Background(), token: "token"},
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().ValidateToken(args.ctx, args.token).Return(&service.TokenClaims{Username: "vasek", SessionId: "laptop"}, nil)
+			},
+			expectCode: codes.OK,
+			expectResp: &pb.ValidateTokenResponse{Username: "vasek", Roles: []string{service.RoleUser}, SessionId: "laptop"},
+		},
+		{
+			testName:      "empty token",
+			args:          args{ctx: context.Background(), token: ""},
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {},
+			expectCode:    codes.InvalidArgument,
+			expectMessage: "invalid request: token: field is required",
+		},
+		{
+			testName: "expired token",
+			args:     args{ctx: context.Background(), token: "token"},
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().ValidateToken(args.ctx, args.token).Return(nil, service.ErrExpiredToken)
+			},
+			expectCode:    codes.Unauthenticated,
+			expectMessage: service.ErrExpiredToken.Error(),
+		},
+		{
+			testName: "cannot parse token",
+			args:     args{ctx: context.Background(), token: "token"},
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().ValidateToken(args.ctx, args.token).Return(nil, service.ErrCannotParseToken)
+			},
+			expectCode:    codes.Unauthenticated,
+			expectMessage: service.ErrCannotParseToken.Error(),
+		},
+		{
+			testName: "suspended user",
+			args:     args{ctx: context.Background(), token: "token"},
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().ValidateToken(args.ctx, args.token).Return(nil, service.ErrUserSuspended)
+			},
+			expectCode:    codes.PermissionDenied,
+			expectMessage: service.ErrUserSuspended.Error(),
+		},
+		{
+			testName: "redis error",
+			args:     args{ctx: context.Background(), token: "token"},
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().ValidateToken(args.ctx, args.token).Return(nil, service.ErrCannotValidateToken)
+			},
+			expectCode:    codes.Internal,
+			expectMessage: service.ErrCannotValidateToken.Error(),
+		},
+		{
+			testName: "unexpected error",
+			args:     args{ctx: context.Background(), token: "token"},
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().ValidateToken(args.ctx, args.token).Return(nil, errors.New("connection refused"))
+			},
+			expectCode:    codes.Internal,
+			expectMessage: "internal server error",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			auth := servicemocks.NewMockAuth(ctrl)
+			tc.mockBehaviour(auth, tc.args)
+			g := &authGrpc{authService: auth}
+
+			resp, err := call(tc.args.ctx, &pb.ValidateTokenRequest{Token: tc.args.token}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return g.ValidateToken(ctx, req.(*pb.ValidateTokenRequest))
+			})
+
+			st := status.Convert(err)
+			assert.Equal(t, tc.expectCode, st.Code())
+			if tc.expectCode != codes.OK {
+				assert.Equal(t, tc.expectMessage, st.Message())
+				return
+			}
+			assert.True(t, proto.Equal(tc.expectResp, resp.(*pb.ValidateTokenResponse)), "response: %v", resp)
+		})
+	}
+}
+
+func TestAuthGrpc_revokeToken(t *testing.T) {
+	type args struct {
+		ctx   context.Context
+		token string
+	}
+	type MockBehaviour func(m *servicemocks.MockAuth, args args)
+
+	testCases := []struct {
+		testName      string
+		args          args
+		mockBehaviour MockBehaviour
+		expectCode    codes.Code
+		expectMessage string
+	}{
+		{
+			testName: "session token",
+			args:     args{ctx: context.Background(), token: "token"},
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().RevokeToken(args.ctx, args.token).Return(nil)
+			},
+			expectCode: codes.OK,
+		},
+		{
+			testName: "personal access token",
+			args:     args{ctx: context.Background(), token: "pat"},
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().RevokeToken(args.ctx, args.token).Return(nil)
+			},
+			expectCode: codes.OK,
+		},
+		{
+			testName:      "empty token",
+			args:          args{ctx: context.Background(), token: ""},
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {},
+			expectCode:    codes.InvalidArgument,
+			expectMessage: "invalid request: token: field is required",
+		},
+		{
+			testName: "session already ended",
+			args:     args{ctx: context.Background(), token: "token"},
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().RevokeToken(args.ctx, args.token).Return(service.ErrExpiredToken)
+			},
+			expectCode:    codes.Unauthenticated,
+			expectMessage: service.ErrExpiredToken.Error(),
+		},
+		{
+			testName: "personal access token already deleted",
+			args:     args{ctx: context.Background(), token: "pat"},
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().RevokeToken(args.ctx, args.token).Return(service.ErrAccessTokenNotFound)
+			},
+			expectCode:    codes.NotFound,
+			expectMessage: service.ErrAccessTokenNotFound.Error(),
+		},
+		{
+			testName: "cannot revoke session",
+			args:     args{ctx: context.Background(), token: "token"},
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().RevokeToken(args.ctx, args.token).Return(service.ErrCannotRevokeSession)
+			},
+			expectCode:    codes.Internal,
+			expectMessage: service.ErrCannotRevokeSession.Error(),
+		},
+		{
+			testName: "cannot revoke personal access token",
+			args:     args{ctx: context.Background(), token: "pat"},
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().RevokeToken(args.ctx, args.token).Return(service.ErrCannotRevokeToken)
+			},
+			expectCode:    codes.Internal,
+			expectMessage: service.ErrCannotRevokeToken.Error(),
+		},
+		{
+			testName: "canceled call",
+			args:     args{ctx: context.Background(), token: "token"},
+			mockBehaviour: func(m *servicemocks.MockAuth, args args) {
+				m.EXPECT().RevokeToken(args.ctx, args.token).Return(context.Canceled)
+			},
+			expectCode:    codes.Canceled,
+			expectMessage: context.Canceled.Error(),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			auth := servicemocks.NewMockAuth(ctrl)
+			tc.mockBehaviour(auth, tc.args)
+			g := &authGrpc{authService: auth}
+
+			resp, err := call(tc.args.ctx, &pb.RevokeTokenRequest{Token: tc.args.token}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return g.RevokeToken(ctx, req.(*pb.RevokeTokenRequest))
+			})
+
+			st := status.Convert(err)
+			assert.Equal(t, tc.expectCode, st.Code())
+			if tc.expectCode != codes.OK {
+				assert.Equal(t, tc.expectMessage, st.Message())
+				assert.Nil(t, resp)
+				return
+			}
+			assert.NotNil(t, resp)
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package commentgrpc
+
+import (
+	"API_for_SN_go/internal/grpc/grpcauth"
+	"API_for_SN_go/internal/grpc/grpcerr"
+	"API_for_SN_go/internal/grpc/params"
+	"API_for_SN_go/internal/model/pgmodel"
+	"API_for_SN_go/internal/service"
+	pb "API_for_SN_go/proto/comment"
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type commentGrpc struct {
+	pb.UnimplementedCommentServer
+	commentService service.Comment
+}
+
+func NewCommentGrpc(g *grpc.Server, commentService service.Comment) {
+	pb.RegisterCommentServer(g, &commentGrpc{commentService: commentService})
+}
+
+func (g *commentGrpc) CreateComment(ctx context.Context, in *pb.CreateCommentRequest) (*pb.CreateCommentResponse, error) {
+	username, err := grpcauth.Authorize(ctx, "comments", service.ScopeWrite)
+	if err != nil {
+		return nil, err
+	}
+	commentId, err := g.commentService.CreateComment(ctx, service.CommentCreateInput{
+		Username: username,
+		PostId:   in.PostId,
+		ParentId: in.ParentId,
+		Comment:  in.Comment,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CreateCommentResponse{CommentId: commentId}, nil
+}
+
+func (g *commentGrpc) GetComment(ctx context.Context, in *pb.GetCommentRequest) (*pb.GetCommentResponse, error) {
+	if _, err := grpcauth.Authorize(ctx, "comments", service.ScopeRead); err != nil {
+		return nil, err
+	}
+	if err := grpcerr.Required(map[string]string{"comment_id": in.CommentId}); err != nil {
+		return nil, err
+	}
+	comment, err := g.commentService.GetCommentById(ctx, in.CommentId)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetCommentResponse{Comment: newCommentInfo(comment)}, nil
+}
+
+func (g *commentGrpc) ListComments(ctx context.Context, in *pb.ListCommentsRequest) (*pb.ListCommentsResponse, error) {
+	username, err := grpcauth.Authorize(ctx, "comments", service.ScopeRead)
+	if err != nil {
+		return nil, err
+	}
+	p, err := params.Pagination(in.Limit, in.Cursor, in.Sort)
+	if err != nil {
+		return nil, err
+	}
+	filter, filterParams := "username", username
+	switch {
+	case in.PostId != "":
+		filter, filterParams = "post_id", in.PostId
+	case in.Username != "":
+		filterParams = in.Username
+	}
+	comments, next, err := g.commentService.GetManyComments(ctx, filter, filterParams, p)
+	if err != nil {
+		return nil, err
+	}
+	res := &pb.ListCommentsResponse{
+		Comments:   make([]*pb.CommentInfo, 0, len(comments)),
+		NextCursor: next,
+	}
+	for _, comment := range comments {
+		res.Comments = append(res.Comments, newCommentInfo(comment))
+	}
+	return res, nil
+}
+
+func (g *commentGrpc) GetCommentTree(ctx context.Context, in *pb.GetCommentTreeRequest) (*pb.GetCommentTreeResponse, error) {
+	if _, err := grpcauth.Authorize(ctx, "comments", service.ScopeRead); err != nil {
+		return nil, err
+	}
+	if err := grpcerr.Required(map[string]string{"post_id": in.PostId}); err != nil {
+		return nil, err
+	}
+	depth, err := params.CommentsDepth(in.Depth)
+	if err != nil {
+		return nil, err
+	}
+	p, err := params.Pagination(in.Limit, in.Cursor, in.Sort)
+	if err != nil {
+		return nil, err
+	}
+	nodes, next, err := g.commentService.GetCommentTree(ctx, in.PostId, depth, p)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetCommentTreeResponse{Comments: newCommentTree(nodes), NextCursor: next}, nil
+}
+
+func (g *commentGrpc) UpdateComment(ctx context.Context, in *pb.UpdateCommentRequest) (*pb.UpdateCommentResponse, error) {
+	username, err := grpcauth.Authorize(ctx, "comments", service.ScopeWrite)
+	if err != nil {
+		return nil, err
+	}
+	if err = grpcerr.Required(map[string]string{"comment_id": in.CommentId, "comment": in.Comment}); err != nil {
+		return nil, err
+	}
+	err = g.commentService.UpdateComment(ctx, service.CommentUpdateInput{
+		Username:   username,
+		CommentId:  in.CommentId,
+		NewComment: in.Comment,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.UpdateCommentResponse{}, nil
+}
+
+func (g *commentGrpc) DeleteComment(ctx context.Context, in *pb.DeleteCommentRequest) (*pb.DeleteCommentResponse, error) {
+	var username string
+	var err error
+	if in.Moderate {
+		username, err = grpcauth.Authorize(ctx, "moderation", service.ScopeWrite, service.RoleModerator, service.RoleAdmin)
+	} else {
+		username, err = grpcauth.Authorize(ctx, "comments", service.ScopeWrite)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = grpcerr.Required(map[string]string{"comment_id": in.CommentId}); err != nil {
+		return nil, err
+	}
+	err = g.commentService.DeleteComment(ctx, service.CommentDeleteInput{
+		Username:  username,
+		CommentId: in.CommentId,
+		Moderate:  in.Moderate,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.DeleteCommentResponse{}, nil
+}
+
+func newCommentInfo(comment pgmodel.Comment) *pb.CommentInfo {
+	info := &pb.CommentInfo{
+		CommentId: comment.CommentId,
+		PostId:    comment.PostId,
+		Username:  comment.Username,
+		Comment:   comment.Comment,
+		CreatedAt: timestamppb.New(comment.CreatedAt),
+		UpdatedAt: timestamppb.New(comment.UpdatedAt),
+	}
+	if comment.ParentId != nil {
+		info.ParentId = *comment.ParentId
+	}
+	return info
+}
+
+func newCommentTree(nodes []service.CommentNode) []*pb.CommentNode {
+	res := make([]*pb.CommentNode, 0, len(nodes))
+	for _, node := range nodes {
+		res = append(res, &pb.CommentNode{
+			Comment: newCommentInfo(node.Comment),
+			Replies: newCommentTree(node.Replies),
+		})
+	}
+	return res
+}
@@ -2,12 +2,34 @@ package grpc
 
 import (
 	authgrpc "API_for_SN_go/internal/grpc/auth"
+	commentgrpc "API_for_SN_go/internal/grpc/comment"
+	"API_for_SN_go/internal/grpc/grpcauth"
+	"API_for_SN_go/internal/grpc/grpcerr"
+	postgrpc "API_for_SN_go/internal/grpc/post"
+	reactiongrpc "API_for_SN_go/internal/grpc/reaction"
+	usergrpc "API_for_SN_go/internal/grpc/user"
 	"API_for_SN_go/internal/service"
+	pbauth "API_for_SN_go/proto/auth"
 	"google.golang.org/grpc"
 )
 
 func NewGRPC(services *service.Services) *grpc.Server {
-	g := grpc.NewServer()
+	// методы Auth выдают и проверяют токены сами
+	public := pbauth.Auth_ServiceDesc.ServiceName
+	g := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			grpcerr.UnaryServerInterceptor(),
+			grpcauth.UnaryServerInterceptor(services.Auth, public),
+		),
+		grpc.ChainStreamInterceptor(
+			grpcerr.StreamServerInterceptor(),
+			grpcauth.StreamServerInterceptor(services.Auth, public),
+		),
+	)
 	authgrpc.NewAuthGrpc(g, services.Auth)
+	usergrpc.NewUserGrpc(g, services.User)
+	postgrpc.NewPostGrpc(g, services.Post, services.Reaction, services.Events)
+	commentgrpc.NewCommentGrpc(g, services.Comment)
+	reactiongrpc.NewReactionGrpc(g, services.Reaction)
 	return g
 }
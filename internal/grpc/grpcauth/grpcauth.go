@@ -0,0 +1,126 @@
+package grpcauth
+
+import (
+	"API_for_SN_go/internal/service"
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+const (
+	authorizationHeader = "authorization"
+	bearerPrefix        = "Bearer "
+)
+
+var (
+	ErrInvalidAuthHeader = status.Error(codes.Unauthenticated, "invalid authorization header")
+	ErrInsufficientRole  = status.Error(codes.PermissionDenied, "insufficient role")
+)
+
+type claimsKey struct{}
+
+// UnaryServerInterceptor validates bearer token from metadata "authorization" like AuthMiddleware of REST api
+// and puts its claims to the context. Methods of public services are called without token
+func UnaryServerInterceptor(auth service.Auth, publicServices ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		for _, s := range publicServices {
+			if strings.HasPrefix(info.FullMethod, "/"+s+"/") {
+				return handler(ctx, req)
+			}
+		}
+		ctx, err := authenticate(ctx, auth)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming methods
+func StreamServerInterceptor(auth service.Auth, publicServices ...string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		for _, s := range publicServices {
+			if strings.HasPrefix(info.FullMethod, "/"+s+"/") {
+				return handler(srv, ss)
+			}
+		}
+		ctx, err := authenticate(ss.Context(), auth)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authStream passes context with token claims to stream handler
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authStream) Context() context.Context {
+	return s.ctx
+}
+
+func authenticate(ctx context.Context, auth service.Auth) (context.Context, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, ErrInvalidAuthHeader
+	}
+	values := md.Get(authorizationHeader)
+	if len(values) == 0 || !strings.HasPrefix(values[0], bearerPrefix) {
+		return nil, ErrInvalidAuthHeader
+	}
+	claims, err := auth.ValidateToken(ctx, strings.TrimPrefix(values[0], bearerPrefix))
+	if err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, claimsKey{}, claims), nil
+}
+
+// Claims returns claims of the token which the call is authenticated with
+func Claims(ctx context.Context) (*service.TokenClaims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*service.TokenClaims)
+	return claims, ok
+}
+
+// Authorize checks that the call is allowed for the token and returns its user. Personal access token must have
+// scope of resource, and if roles are set user must have one of them. Calls with session token are not restricted by scopes
+func Authorize(ctx context.Context, resource, access string, roles ...string) (string, error) {
+	claims, ok := Claims(ctx)
+	if !ok {
+		return "", errors.New("no token claims in context")
+	}
+	if len(roles) != 0 && !hasRole(claims, roles) {
+		return "", ErrInsufficientRole
+	}
+	if claims.AccessTokenId == "" {
+		return claims.Username, nil
+	}
+	required := service.Scope(resource, access)
+	for _, scope := range claims.Scopes {
+		if scope == required {
+			return claims.Username, nil
+		}
+	}
+	return "", status.Error(codes.PermissionDenied, fmt.Sprintf("access token has no scope %s", required))
+}
+
+func hasRole(claims *service.TokenClaims, roles []string) bool {
+	role := claims.Role
+	// токены, выданные до появления ролей, не содержат роль
+	if role == "" {
+		role = service.RoleUser
+	}
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package grpcerr
+
+import (
+	"API_for_SN_go/internal/service"
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"sort"
+	"strings"
+	"time"
+)
+
+const grpcErrPrefixLog = "/grpc/grpcerr"
+
+// codes of service errors. Errors are checked with errors.Is, so wrapped errors and *service.LockedError match too
+var serviceCodes = []struct {
+	err  error
+	code codes.Code
+}{
+	{service.ErrUserNotFound, codes.NotFound},
+	{service.ErrPostNotFound, codes.NotFound},
+	{service.ErrCommentNotFound, codes.NotFound},
+	{service.ErrParentCommentNotFound, codes.NotFound},
+	{service.ErrReactionNotFound, codes.NotFound},
+	{service.ErrSessionNotFound, codes.NotFound},
+	{service.ErrAccessTokenNotFound, codes.NotFound},
+
+	{service.ErrUserAlreadyExists, codes.AlreadyExists},
+	{service.ErrPostAlreadyExists, codes.AlreadyExists},
+	{service.ErrCommentAlreadyExists, codes.AlreadyExists},
+	{service.ErrReactionAlreadyExists, codes.AlreadyExists},
+	{service.ErrEmailAlreadyTaken, codes.AlreadyExists},
+	{service.ErrAlreadyFollowing, codes.AlreadyExists},
+
+	{service.ErrIncorrectPassword, codes.Unauthenticated},
+	{service.ErrInvalidToken, codes.Unauthenticated},
+	{service.ErrExpiredToken, codes.Unauthenticated},
+	{service.ErrCannotParseToken, codes.Unauthenticated},
+	{service.ErrRefreshTokenReused, codes.Unauthenticated},
+	{service.ErrInvalidTOTPCode, codes.Unauthenticated},
+
+	{service.ErrUserSuspended, codes.PermissionDenied},
+	{service.ErrCannotManageSelf, codes.PermissionDenied},
+	{service.ErrAccessTokenNotAllowed, codes.PermissionDenied},
+	{service.ErrEmailNotVerified, codes.PermissionDenied},
+
+	{service.ErrSignInLocked, codes.ResourceExhausted},
+	{service.ErrTooManyTOTPAttempts, codes.ResourceExhausted},
+
+	{service.ErrEmailAlreadyVerified, codes.FailedPrecondition},
+	{service.ErrTOTPAlreadyEnabled, codes.FailedPrecondition},
+	{service.ErrTOTPNotEnrolled, codes.FailedPrecondition},
+	{service.ErrTOTPNotEnabled, codes.FailedPrecondition},
+	{service.ErrNotFollowing, codes.FailedPrecondition},
+
+	{service.ErrInvalidRole, codes.InvalidArgument},
+	{service.ErrInvalidScope, codes.InvalidArgument},
+	{service.ErrInvalidCursor, codes.InvalidArgument},
+	{service.ErrCannotFollowSelf, codes.InvalidArgument},
+
+	// ошибки сервиса без подробностей, их текст можно отдавать клиенту
+	{service.ErrCannotCreateUser, codes.Internal},
+	{service.ErrCannotDeleteUser, codes.Internal},
+	{service.ErrCannotUpdateUser, codes.Internal},
+	{service.ErrCannotCreateToken, codes.Internal},
+	{service.ErrCannotValidateToken, codes.Internal},
+	{service.ErrCannotResetPassword, codes.Internal},
+	{service.ErrCannotVerifyEmail, codes.Internal},
+	{service.ErrCannotChangeEmail, codes.Internal},
+	{service.ErrCannotUpdateTOTP, codes.Internal},
+	{service.ErrCannotRevokeSession, codes.Internal},
+	{service.ErrCannotRevokeToken, codes.Internal},
+	{service.ErrCannotCreatePost, codes.Internal},
+	{service.ErrCannotUpdatePost, codes.Internal},
+	{service.ErrCannotDeletePost, codes.Internal},
+	{service.ErrCannotCreateReaction, codes.Internal},
+	{service.ErrCannotGetReactions, codes.Internal},
+	{service.ErrCannotDeleteReaction, codes.Internal},
+	{service.ErrCannotCreateComment, codes.Internal},
+	{service.ErrCannotDeleteComment, codes.Internal},
+	{service.ErrCannotFollow, codes.Internal},
+	{service.ErrCannotUnfollow, codes.Internal},
+}
+
+// FieldViolation describes invalid field of request message
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+// ValidationError is returned by handlers for invalid request, it is sent as InvalidArgument with BadRequest details
+type ValidationError struct {
+	Violations []FieldViolation
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		fields = append(fields, fmt.Sprintf("%s: %s", v.Field, v.Description))
+	}
+	return "invalid request: " + strings.Join(fields, "; ")
+}
+
+// Required returns *ValidationError for each empty field of the map field name -> value, or nil
+func Required(fields map[string]string) error {
+	var violations []FieldViolation
+	for field, value := range fields {
+		if value == "" {
+			violations = append(violations, FieldViolation{Field: field, Description: "field is required"})
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	sort.Slice(violations, func(i, j int) bool { return violations[i].Field < violations[j].Field })
+	return &ValidationError{Violations: violations}
+}
+
+// Status converts handler error to grpc status. Unknown errors are hidden from the client
+func Status(err error) *status.Status {
+	if st, ok := status.FromError(err); ok {
+		return st
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		br := &errdetails.BadRequest{}
+		for _, v := range validationErr.Violations {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: v.Description,
+			})
+		}
+		return withDetails(status.New(codes.InvalidArgument, validationErr.Error()), br)
+	}
+
+	var lockedErr *service.LockedError
+	if errors.As(err, &lockedErr) {
+		retry := &errdetails.RetryInfo{RetryDelay: durationpb.New(time.Until(lockedErr.Until).Round(time.Second))}
+		return withDetails(status.New(codes.ResourceExhausted, lockedErr.Error()), retry)
+	}
+
+	for _, sc := range serviceCodes {
+		if errors.Is(err, sc.err) {
+			return status.New(sc.code, err.Error())
+		}
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.New(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.New(codes.DeadlineExceeded, err.Error())
+	}
+	log.Errorf("%s/Status unexpected error: %s", grpcErrPrefixLog, err)
+	return status.New(codes.Internal, "internal server error")
+}
+
+func withDetails(st *status.Status, details ...protoadapt.MessageV1) *status.Status {
+	detailed, err := st.WithDetails(details...)
+	if err != nil {
+		log.Errorf("%s/withDetails error attach details: %s", grpcErrPrefixLog, err)
+		return st
+	}
+	return detailed
+}
+
+// UnaryServerInterceptor converts errors of all unary handlers to grpc statuses
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, Status(err).Err()
+		}
+		return resp, nil
+	}
+}
+
+// StreamServerInterceptor converts errors of all streaming handlers to grpc statuses
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return Status(err).Err()
+		}
+		return nil
+	}
+}
@@ -0,0 +1,121 @@
+package grpcerr
+
+import (
+	"API_for_SN_go/internal/service"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestStatus(t *testing.T) {
+	testCases := []struct {
+		testName      string
+		err           error
+		expectCode    codes.Code
+		expectMessage string
+	}{
+		{"not found", service.ErrPostNotFound, codes.NotFound, service.ErrPostNotFound.Error()},
+		{"access token not found", service.ErrAccessTokenNotFound, codes.NotFound, service.ErrAccessTokenNotFound.Error()},
+		{"already exists", service.ErrUserAlreadyExists, codes.AlreadyExists, service.ErrUserAlreadyExists.Error()},
+		{"already following", service.ErrAlreadyFollowing, codes.AlreadyExists, service.ErrAlreadyFollowing.Error()},
+		{"incorrect password", service.ErrIncorrectPassword, codes.Unauthenticated, service.ErrIncorrectPassword.Error()},
+		{"expired token", service.ErrExpiredToken, codes.Unauthenticated, service.ErrExpiredToken.Error()},
+		{"refresh token reused", service.ErrRefreshTokenReused, codes.Unauthenticated, service.ErrRefreshTokenReused.Error()},
+		{"suspended user", service.ErrUserSuspended, codes.PermissionDenied, service.ErrUserSuspended.Error()},
+		{"access token not allowed", service.ErrAccessTokenNotAllowed, codes.PermissionDenied, service.ErrAccessTokenNotAllowed.Error()},
+		{"too many totp attempts", service.ErrTooManyTOTPAttempts, codes.ResourceExhausted, service.ErrTooManyTOTPAttempts.Error()},
+		{"totp not enabled", service.ErrTOTPNotEnabled, codes.FailedPrecondition, service.ErrTOTPNotEnabled.Error()},
+		{"invalid cursor", service.ErrInvalidCursor, codes.InvalidArgument, service.ErrInvalidCursor.Error()},
+		{"invalid scope", service.ErrInvalidScope, codes.InvalidArgument, service.ErrInvalidScope.Error()},
+		{"cannot validate token", service.ErrCannotValidateToken, codes.Internal, service.ErrCannotValidateToken.Error()},
+		{"cannot get reactions", service.ErrCannotGetReactions, codes.Internal, service.ErrCannotGetReactions.Error()},
+		{"wrapped error", fmt.Errorf("find post: %w", service.ErrPostNotFound), codes.NotFound, "find post: " + service.ErrPostNotFound.Error()},
+		{"grpc status", status.Error(codes.PermissionDenied, "insufficient role"), codes.PermissionDenied, "insufficient role"},
+		{"canceled", context.Canceled, codes.Canceled, context.Canceled.Error()},
+		{"deadline exceeded", context.DeadlineExceeded, codes.DeadlineExceeded, context.DeadlineExceeded.Error()},
+		{"unexpected error", errors.New("pg: connection refused"), codes.Internal, "internal server error"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			st := Status(tc.err)
+
+			assert.Equal(t, tc.expectCode, st.Code())
+			assert.Equal(t, tc.expectMessage, st.Message())
+			assert.Empty(t, st.Details())
+		})
+	}
+}
+
+func TestStatus_validationError(t *testing.T) {
+	err := Required(map[string]string{"post_id": "", "comment": "", "username": "vasek"})
+
+	st := Status(err)
+
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, "invalid request: comment: field is required; post_id: field is required", st.Message())
+	require.Len(t, st.Details(), 1)
+	br, ok := st.Details()[0].(*errdetails.BadRequest)
+	require.True(t, ok)
+	require.Len(t, br.FieldViolations, 2)
+	assert.Equal(t, "comment", br.FieldViolations[0].Field)
+	assert.Equal(t, "field is required", br.FieldViolations[0].Description)
+	assert.Equal(t, "post_id", br.FieldViolations[1].Field)
+}
+
+func TestStatus_lockedError(t *testing.T) {
+	err := &service.LockedError{Until: time.Now().Add(90 * time.Second)}
+
+	st := Status(fmt.Errorf("sign in: %w", err))
+
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	assert.Equal(t, err.Error(), st.Message())
+	require.Len(t, st.Details(), 1)
+	retry, ok := st.Details()[0].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	assert.Equal(t, 90*time.Second, retry.RetryDelay.AsDuration())
+}
+
+func TestRequired(t *testing.T) {
+	assert.NoError(t, Required(map[string]string{"token": "token"}))
+	assert.NoError(t, Required(nil))
+
+	var validationErr *ValidationError
+	require.ErrorAs(t, Required(map[string]string{"token": ""}), &validationErr)
+	assert.Equal(t, []FieldViolation{{Field: "token", Description: "field is required"}}, validationErr.Violations)
+}
+
+func TestServerInterceptors(t *testing.T) {
+	unary := UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/post.Post/GetPost"}
+
+	resp, err := unary(context.Background(), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "resp", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "resp", resp)
+
+	resp, err = unary(context.Background(), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "resp", service.ErrPostNotFound
+	})
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	stream := StreamServerInterceptor()
+	streamInfo := &grpc.StreamServerInfo{FullMethod: "/post.Post/WatchPost"}
+	err = stream(nil, nil, streamInfo, func(srv interface{}, ss grpc.ServerStream) error {
+		return nil
+	})
+	assert.NoError(t, err)
+	err = stream(nil, nil, streamInfo, func(srv interface{}, ss grpc.ServerStream) error {
+		return service.ErrUserSuspended
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,55 @@
+package params
+
+import (
+	"API_for_SN_go/internal/grpc/grpcerr"
+	"API_for_SN_go/internal/repo"
+	"API_for_SN_go/internal/repo/pagination"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+
+	defaultCommentsDepth = 3
+	maxCommentsDepth     = 10
+)
+
+// Pagination checks limit, cursor and sort fields of list request the same way as query params of REST api.
+// Zero limit means default limit, empty sort - default sort of the list
+func Pagination(limit int32, cursor, sort string) (repo.Pagination, error) {
+	var violations []grpcerr.FieldViolation
+	if limit < 0 {
+		violations = append(violations, grpcerr.FieldViolation{Field: "limit", Description: "limit must be positive"})
+	}
+	// курсор проверяется здесь, чтобы ошибка указывала на поле запроса
+	if cursor != "" {
+		if _, err := pagination.DecodeCursor(cursor); err != nil {
+			violations = append(violations, grpcerr.FieldViolation{Field: "cursor", Description: "cursor must be next_cursor of previous page"})
+		}
+	}
+	s := pagination.Sort(sort)
+	switch s {
+	case "", pagination.SortNewest, pagination.SortOldest:
+	default:
+		violations = append(violations, grpcerr.FieldViolation{Field: "sort", Description: "sort must be newest or oldest"})
+	}
+	if len(violations) != 0 {
+		return repo.Pagination{}, &grpcerr.ValidationError{Violations: violations}
+	}
+	p := repo.Pagination{Limit: defaultLimit, Cursor: cursor, Sort: s}
+	if limit > 0 {
+		p.Limit = min(int(limit), maxLimit)
+	}
+	return p, nil
+}
+
+// CommentsDepth returns depth of comments tree, it cannot be greater than maxCommentsDepth
+func CommentsDepth(depth int32) (int, error) {
+	if depth < 0 {
+		return 0, &grpcerr.ValidationError{Violations: []grpcerr.FieldViolation{{Field: "depth", Description: "depth must be positive"}}}
+	}
+	if depth == 0 {
+		return defaultCommentsDepth, nil
+	}
+	return min(int(depth), maxCommentsDepth), nil
+}
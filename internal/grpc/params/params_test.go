@@ -0,0 +1,118 @@
+package params
+
+import (
+	"API_for_SN_go/internal/grpc/grpcerr"
+	"API_for_SN_go/internal/repo"
+	"API_for_SN_go/internal/repo/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestPagination(t *testing.T) {
+	cursor := pagination.EncodeCursor(42)
+
+	testCases := []struct {
+		testName         string
+		limit            int32
+		cursor           string
+		sort             string
+		expectPagination repo.Pagination
+		expectFields     []string
+	}{
+		{
+			testName:         "default limit",
+			expectPagination: repo.Pagination{Limit: defaultLimit},
+		},
+		{
+			testName:         "limit, cursor and sort",
+			limit:            5,
+			cursor:           cursor,
+			sort:             "oldest",
+			expectPagination: repo.Pagination{Limit: 5, Cursor: cursor, Sort: pagination.SortOldest},
+		},
+		{
+			testName:         "max limit",
+			limit:            maxLimit,
+			sort:             "newest",
+			expectPagination: repo.Pagination{Limit: maxLimit, Sort: pagination.SortNewest},
+		},
+		{
+			testName:         "limit greater than max",
+			limit:            maxLimit + 1,
+			expectPagination: repo.Pagination{Limit: maxLimit},
+		},
+		{
+			testName:     "negative limit",
+			limit:        -1,
+			expectFields: []string{"limit"},
+		},
+		{
+			testName:     "invalid sort",
+			sort:         "popular",
+			expectFields: []string{"sort"},
+		},
+		{
+			testName:     "cursor is not base64",
+			cursor:       "not a cursor!",
+			expectFields: []string{"cursor"},
+		},
+		{
+			testName:     "cursor is not item id",
+			cursor:       "YWJj", // abc
+			expectFields: []string{"cursor"},
+		},
+		{
+			testName:     "all fields invalid",
+			limit:        -5,
+			cursor:       "%%%",
+			sort:         "random",
+			expectFields: []string{"limit", "cursor", "sort"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			p, err := Pagination(tc.limit, tc.cursor, tc.sort)
+
+			if len(tc.expectFields) == 0 {
+				require.NoError(t, err)
+				assert.Equal(t, tc.expectPagination, p)
+				return
+			}
+			var validationErr *grpcerr.ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			fields := make([]string, 0, len(validationErr.Violations))
+			for _, v := range validationErr.Violations {
+				fields = append(fields, v.Field)
+			}
+			assert.Equal(t, tc.expectFields, fields)
+		})
+	}
+}
+
+func TestCommentsDepth(t *testing.T) {
+	testCases := []struct {
+		testName    string
+		depth       int32
+		expectDepth int
+		expectErr   bool
+	}{
+		{testName: "default depth", depth: 0, expectDepth: defaultCommentsDepth},
+		{testName: "depth", depth: 2, expectDepth: 2},
+		{testName: "max depth", depth: maxCommentsDepth + 1, expectDepth: maxCommentsDepth},
+		{testName: "negative depth", depth: -1, expectErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			depth, err := CommentsDepth(tc.depth)
+
+			if tc.expectErr {
+				var validationErr *grpcerr.ValidationError
+				assert.ErrorAs(t, err, &validationErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectDepth, depth)
+		})
+	}
+}
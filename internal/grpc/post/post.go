@@ -0,0 +1,265 @@
+package postgrpc
+
+import (
+	"API_for_SN_go/internal/grpc/grpcauth"
+	"API_for_SN_go/internal/grpc/grpcerr"
+	"API_for_SN_go/internal/grpc/params"
+	"API_for_SN_go/internal/model/pgmodel"
+	"API_for_SN_go/internal/service"
+	pb "API_for_SN_go/proto/post"
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type postGrpc struct {
+	pb.UnimplementedPostServer
+	postService     service.Post
+	reactionService service.Reaction
+	events          service.PostEvents
+}
+
+func NewPostGrpc(g *grpc.Server, postService service.Post, reactionService service.Reaction, events service.PostEvents) {
+	pb.RegisterPostServer(g, &postGrpc{postService: postService, reactionService: reactionService, events: events})
+}
+
+func (g *postGrpc) CreatePost(ctx context.Context, in *pb.CreatePostRequest) (*pb.CreatePostResponse, error) {
+	username, err := grpcauth.Authorize(ctx, "posts", service.ScopeWrite)
+	if err != nil {
+		return nil, err
+	}
+	postId, err := g.postService.CreatePost(ctx, service.PostCreateInput{
+		Username: username,
+		Title:    in.Title,
+		Text:     in.Text,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CreatePostResponse{PostId: postId}, nil
+}
+
+func (g *postGrpc) GetPost(ctx context.Context, in *pb.GetPostRequest) (*pb.GetPostResponse, error) {
+	username, err := grpcauth.Authorize(ctx, "posts", service.ScopeRead)
+	if err != nil {
+		return nil, err
+	}
+	if err = grpcerr.Required(map[string]string{"post_id": in.PostId}); err != nil {
+		return nil, err
+	}
+	post, err := g.postService.GetPostById(ctx, in.PostId)
+	if err != nil {
+		return nil, err
+	}
+	reactions, err := g.reactionService.GetReactionSummary(ctx, in.PostId)
+	if err != nil {
+		return nil, err
+	}
+	reaction, err := g.reactionService.GetUserReaction(ctx, in.PostId, username)
+	if err != nil && !errors.Is(err, service.ErrReactionNotFound) {
+		return nil, err
+	}
+	return &pb.GetPostResponse{
+		Post:         newPostInfo(post),
+		Reactions:    newReactionCounts(reactions),
+		UserReaction: reaction.Reaction,
+	}, nil
+}
+
+func (g *postGrpc) ListPosts(ctx context.Context, in *pb.ListPostsRequest) (*pb.ListPostsResponse, error) {
+	username, err := grpcauth.Authorize(ctx, "posts", service.ScopeRead)
+	if err != nil {
+		return nil, err
+	}
+	if in.Username != "" {
+		username = in.Username
+	}
+	p, err := params.Pagination(in.Limit, in.Cursor, in.Sort)
+	if err != nil {
+		return nil, err
+	}
+	posts, next, err := g.postService.GetManyPosts(ctx, username, p)
+	if err != nil {
+		return nil, err
+	}
+	res := &pb.ListPostsResponse{
+		Username:   username,
+		Posts:      make([]*pb.PostInfo, 0, len(posts)),
+		NextCursor: next,
+	}
+	for _, post := range posts {
+		res.Posts = append(res.Posts, newPostInfo(post))
+	}
+	return res, nil
+}
+
+func (g *postGrpc) GetFeed(ctx context.Context, in *pb.GetFeedRequest) (*pb.GetFeedResponse, error) {
+	username, err := grpcauth.Authorize(ctx, "feed", service.ScopeRead)
+	if err != nil {
+		return nil, err
+	}
+	p, err := params.Pagination(in.Limit, in.Cursor, in.Sort)
+	if err != nil {
+		return nil, err
+	}
+	items, next, err := g.postService.GetFeed(ctx, username, p)
+	if err != nil {
+		return nil, err
+	}
+	res := &pb.GetFeedResponse{
+		Posts:      make([]*pb.FeedItem, 0, len(items)),
+		NextCursor: next,
+	}
+	for _, item := range items {
+		res.Posts = append(res.Posts, &pb.FeedItem{
+			Post:          newPostInfo(item.Post),
+			Reactions:     newReactionCounts(item.Reactions),
+			CommentsCount: int32(item.CommentsCount),
+		})
+	}
+	return res, nil
+}
+
+func (g *postGrpc) UpdatePost(ctx context.Context, in *pb.UpdatePostRequest) (*pb.UpdatePostResponse, error) {
+	username, err := grpcauth.Authorize(ctx, "posts", service.ScopeWrite)
+	if err != nil {
+		return nil, err
+	}
+	if err = grpcerr.Required(map[string]string{"post_id": in.PostId}); err != nil {
+		return nil, err
+	}
+	if in.Title == "" && in.Text == "" {
+		return nil, &grpcerr.ValidationError{Violations: []grpcerr.FieldViolation{{Field: "title", Description: "title or text is required"}}}
+	}
+	err = g.postService.UpdatePost(ctx, service.PostUpdateInput{
+		Username: username,
+		PostId:   in.PostId,
+		Title:    in.Title,
+		Text:     in.Text,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.UpdatePostResponse{}, nil
+}
+
+func (g *postGrpc) DeletePost(ctx context.Context, in *pb.DeletePostRequest) (*pb.DeletePostResponse, error) {
+	var username string
+	var err error
+	if in.Moderate {
+		username, err = grpcauth.Authorize(ctx, "moderation", service.ScopeWrite, service.RoleModerator, service.RoleAdmin)
+	} else {
+		username, err = grpcauth.Authorize(ctx, "posts", service.ScopeWrite)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = grpcerr.Required(map[string]string{"post_id": in.PostId}); err != nil {
+		return nil, err
+	}
+	err = g.postService.DeletePost(ctx, service.PostDeleteInput{
+		Username: username,
+		PostId:   in.PostId,
+		Moderate: in.Moderate,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.DeletePostResponse{}, nil
+}
+
+func (g *postGrpc) WatchPost(in *pb.WatchPostRequest, stream pb.Post_WatchPostServer) error {
+	ctx := stream.Context()
+	if _, err := grpcauth.Authorize(ctx, "posts", service.ScopeRead); err != nil {
+		return err
+	}
+	if err := grpcerr.Required(map[string]string{"post_id": in.PostId}); err != nil {
+		return err
+	}
+	if _, err := g.postService.GetPostById(ctx, in.PostId); err != nil {
+		return err
+	}
+	events, err := g.events.WatchPost(ctx, in.PostId)
+	if err != nil {
+		return err
+	}
+	// заголовок означает, что подписка активна и клиент может загружать пост
+	if err = stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	for event := range events {
+		if err = stream.Send(newPostEvent(event)); err != nil {
+			return err
+		}
+		if event.Type == service.EventPostDeleted {
+			return nil
+		}
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	return status.Error(codes.Unavailable, "too many post events, reload the post and watch again")
+}
+
+var eventTypes = map[string]pb.EventType{
+	service.EventCommentCreated:  pb.EventType_COMMENT_CREATED,
+	service.EventCommentUpdated:  pb.EventType_COMMENT_UPDATED,
+	service.EventCommentDeleted:  pb.EventType_COMMENT_DELETED,
+	service.EventReactionCreated: pb.EventType_REACTION_CREATED,
+	service.EventReactionUpdated: pb.EventType_REACTION_UPDATED,
+	service.EventReactionDeleted: pb.EventType_REACTION_DELETED,
+	service.EventPostDeleted:     pb.EventType_POST_DELETED,
+}
+
+func newPostEvent(event service.PostEvent) *pb.PostEvent {
+	res := &pb.PostEvent{
+		Type:      eventTypes[event.Type],
+		PostId:    event.PostId,
+		CreatedAt: timestamppb.New(event.CreatedAt),
+	}
+	switch {
+	case event.Comment != nil:
+		comment := &pb.EventComment{
+			CommentId: event.Comment.CommentId,
+			Username:  event.Comment.Username,
+			Comment:   event.Comment.Comment,
+			CreatedAt: timestamppb.New(event.Comment.CreatedAt),
+			UpdatedAt: timestamppb.New(event.Comment.UpdatedAt),
+		}
+		if event.Comment.ParentId != nil {
+			comment.ParentId = *event.Comment.ParentId
+		}
+		res.Payload = &pb.PostEvent_Comment{Comment: comment}
+	case event.Reaction != nil:
+		res.Payload = &pb.PostEvent_Reaction{Reaction: &pb.EventReaction{
+			ReactionId: event.Reaction.ReactionId,
+			Username:   event.Reaction.Username,
+			Reaction:   event.Reaction.Reaction,
+			CreatedAt:  timestamppb.New(event.Reaction.CreatedAt),
+		}}
+	}
+	return res
+}
+
+func newPostInfo(post pgmodel.Post) *pb.PostInfo {
+	return &pb.PostInfo{
+		PostId:    post.PostId,
+		Username:  post.Username,
+		Title:     post.Title,
+		Text:      post.Text,
+		CreatedAt: timestamppb.New(post.CreatedAt),
+		UpdatedAt: timestamppb.New(post.UpdatedAt),
+	}
+}
+
+func newReactionCounts(reactions map[string]int) map[string]int32 {
+	res := make(map[string]int32, len(reactions))
+	for reaction, count := range reactions {
+		res[reaction] = int32(count)
+	}
+	return res
+}
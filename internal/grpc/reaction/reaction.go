@@ -0,0 +1,134 @@
+package reactiongrpc
+
+import (
+	"API_for_SN_go/internal/grpc/grpcauth"
+	"API_for_SN_go/internal/grpc/grpcerr"
+	"API_for_SN_go/internal/grpc/params"
+	"API_for_SN_go/internal/model/pgmodel"
+	"API_for_SN_go/internal/service"
+	"API_for_SN_go/pkg/validator"
+	pb "API_for_SN_go/proto/reaction"
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type reactionGrpc struct {
+	pb.UnimplementedReactionServer
+	reactionService service.Reaction
+}
+
+func NewReactionGrpc(g *grpc.Server, reactionService service.Reaction) {
+	pb.RegisterReactionServer(g, &reactionGrpc{reactionService: reactionService})
+}
+
+func (g *reactionGrpc) CreateReaction(ctx context.Context, in *pb.CreateReactionRequest) (*pb.CreateReactionResponse, error) {
+	username, err := grpcauth.Authorize(ctx, "reactions", service.ScopeWrite)
+	if err != nil {
+		return nil, err
+	}
+	if err = grpcerr.Required(map[string]string{"post_id": in.PostId, "reaction": in.Reaction}); err != nil {
+		return nil, err
+	}
+	if !validator.IsValidReaction(in.Reaction) {
+		return nil, &grpcerr.ValidationError{Violations: []grpcerr.FieldViolation{{
+			Field:       "reaction",
+			Description: "reaction can only consist of lower Latin characters and numbers. Max length is 16",
+		}}}
+	}
+	reactionId, err := g.reactionService.CreateReaction(ctx, service.ReactionCreateInput{
+		Username: username,
+		PostId:   in.PostId,
+		Reaction: in.Reaction,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CreateReactionResponse{ReactionId: reactionId}, nil
+}
+
+func (g *reactionGrpc) GetReaction(ctx context.Context, in *pb.GetReactionRequest) (*pb.GetReactionResponse, error) {
+	if _, err := grpcauth.Authorize(ctx, "reactions", service.ScopeRead); err != nil {
+		return nil, err
+	}
+	if err := grpcerr.Required(map[string]string{"reaction_id": in.ReactionId}); err != nil {
+		return nil, err
+	}
+	reaction, err := g.reactionService.GetReactionById(ctx, in.ReactionId)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetReactionResponse{Reaction: newReactionInfo(reaction)}, nil
+}
+
+func (g *reactionGrpc) ListReactions(ctx context.Context, in *pb.ListReactionsRequest) (*pb.ListReactionsResponse, error) {
+	if _, err := grpcauth.Authorize(ctx, "reactions", service.ScopeRead); err != nil {
+		return nil, err
+	}
+	if err := grpcerr.Required(map[string]string{"post_id": in.PostId}); err != nil {
+		return nil, err
+	}
+	p, err := params.Pagination(in.Limit, in.Cursor, in.Sort)
+	if err != nil {
+		return nil, err
+	}
+	reactions, next, err := g.reactionService.GetManyReactions(ctx, in.PostId, p)
+	if err != nil {
+		return nil, err
+	}
+	res := &pb.ListReactionsResponse{
+		Reactions:  make([]*pb.ReactionInfo, 0, len(reactions)),
+		NextCursor: next,
+	}
+	for _, reaction := range reactions {
+		res.Reactions = append(res.Reactions, newReactionInfo(reaction))
+	}
+	return res, nil
+}
+
+func (g *reactionGrpc) GetReactionSummary(ctx context.Context, in *pb.GetReactionSummaryRequest) (*pb.GetReactionSummaryResponse, error) {
+	if _, err := grpcauth.Authorize(ctx, "reactions", service.ScopeRead); err != nil {
+		return nil, err
+	}
+	if err := grpcerr.Required(map[string]string{"post_id": in.PostId}); err != nil {
+		return nil, err
+	}
+	summary, err := g.reactionService.GetReactionSummary(ctx, in.PostId)
+	if err != nil {
+		return nil, err
+	}
+	res := &pb.GetReactionSummaryResponse{Reactions: make(map[string]int32, len(summary))}
+	for reaction, count := range summary {
+		res.Reactions[reaction] = int32(count)
+	}
+	return res, nil
+}
+
+func (g *reactionGrpc) DeleteReaction(ctx context.Context, in *pb.DeleteReactionRequest) (*pb.DeleteReactionResponse, error) {
+	username, err := grpcauth.Authorize(ctx, "reactions", service.ScopeWrite)
+	if err != nil {
+		return nil, err
+	}
+	if err = grpcerr.Required(map[string]string{"reaction_id": in.ReactionId}); err != nil {
+		return nil, err
+	}
+	err = g.reactionService.DeleteReaction(ctx, service.ReactionDeleteInput{
+		Username:   username,
+		ReactionId: in.ReactionId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.DeleteReactionResponse{}, nil
+}
+
+func newReactionInfo(reaction pgmodel.Reaction) *pb.ReactionInfo {
+	return &pb.ReactionInfo{
+		ReactionId: reaction.ReactionId,
+		PostId:     reaction.PostId,
+		Username:   reaction.Username,
+		Reaction:   reaction.Reaction,
+		CreatedAt:  timestamppb.New(reaction.CreatedAt),
+		UpdatedAt:  timestamppb.New(reaction.UpdatedAt),
+	}
+}
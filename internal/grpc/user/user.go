@@ -0,0 +1,77 @@
+package usergrpc
+
+import (
+	"API_for_SN_go/internal/grpc/grpcauth"
+	"API_for_SN_go/internal/grpc/grpcerr"
+	"API_for_SN_go/internal/service"
+	pb "API_for_SN_go/proto/user"
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type userGrpc struct {
+	pb.UnimplementedUserServer
+	userService service.User
+}
+
+func NewUserGrpc(g *grpc.Server, userService service.User) {
+	pb.RegisterUserServer(g, &userGrpc{userService: userService})
+}
+
+func (g *userGrpc) GetUser(ctx context.Context, in *pb.GetUserRequest) (*pb.GetUserResponse, error) {
+	username, err := grpcauth.Authorize(ctx, "users", service.ScopeRead)
+	if err != nil {
+		return nil, err
+	}
+	if in.Username != "" {
+		username = in.Username
+	}
+	user, err := g.userService.GetUserByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetUserResponse{
+		Username:      user.Username,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Role:          user.Role,
+		CreatedAt:     timestamppb.New(user.CreatedAt),
+		UpdatedAt:     timestamppb.New(user.UpdatedAt),
+	}, nil
+}
+
+func (g *userGrpc) UpdateFullName(ctx context.Context, in *pb.UpdateFullNameRequest) (*pb.UpdateFullNameResponse, error) {
+	username, err := grpcauth.Authorize(ctx, "users", service.ScopeWrite)
+	if err != nil {
+		return nil, err
+	}
+	err = g.userService.UpdateFullName(ctx, service.UserUpdateFullNameInput{
+		Username:  username,
+		FirstName: in.FirstName,
+		LastName:  in.LastName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.UpdateFullNameResponse{}, nil
+}
+
+func (g *userGrpc) UpdateRole(ctx context.Context, in *pb.UpdateRoleRequest) (*pb.UpdateRoleResponse, error) {
+	if _, err := grpcauth.Authorize(ctx, "admin", service.ScopeWrite, service.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if err := grpcerr.Required(map[string]string{"username": in.Username, "role": in.Role}); err != nil {
+		return nil, err
+	}
+	err := g.userService.UpdateRole(ctx, service.UserUpdateRoleInput{
+		Username: in.Username,
+		Role:     in.Role,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.UpdateRoleResponse{}, nil
+}
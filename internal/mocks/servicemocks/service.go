@@ -6,7 +6,9 @@ package servicemocks
 
 import (
 	pgmodel "API_for_SN_go/internal/model/pgmodel"
+	repo "API_for_SN_go/internal/repo"
 	service "API_for_SN_go/internal/service"
+	jwk "API_for_SN_go/pkg/jwk"
 	context "context"
 	reflect "reflect"
 
@@ -36,11 +38,83 @@ func (m *MockAuth) EXPECT() *MockAuthMockRecorder {
 	return m.recorder
 }
 
+// ChangeEmail mocks base method.
+func (m *MockAuth) ChangeEmail(ctx context.Context, input service.EmailChangeInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeEmail", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangeEmail indicates an expected call of ChangeEmail.
+func (mr *MockAuthMockRecorder) ChangeEmail(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeEmail", reflect.TypeOf((*MockAuth)(nil).ChangeEmail), ctx, input)
+}
+
+// ChangePassword mocks base method.
+func (m *MockAuth) ChangePassword(ctx context.Context, input service.PasswordChangeInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePassword", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockAuthMockRecorder) ChangePassword(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockAuth)(nil).ChangePassword), ctx, input)
+}
+
+// ConfirmEmail mocks base method.
+func (m *MockAuth) ConfirmEmail(ctx context.Context, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmEmail", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConfirmEmail indicates an expected call of ConfirmEmail.
+func (mr *MockAuthMockRecorder) ConfirmEmail(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmEmail", reflect.TypeOf((*MockAuth)(nil).ConfirmEmail), ctx, token)
+}
+
+// ConfirmTOTP mocks base method.
+func (m *MockAuth) ConfirmTOTP(ctx context.Context, input service.TOTPConfirmInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmTOTP", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConfirmTOTP indicates an expected call of ConfirmTOTP.
+func (mr *MockAuthMockRecorder) ConfirmTOTP(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmTOTP", reflect.TypeOf((*MockAuth)(nil).ConfirmTOTP), ctx, input)
+}
+
+// CreateAccessToken mocks base method.
+func (m *MockAuth) CreateAccessToken(ctx context.Context, input service.AccessTokenCreateInput) (string, pgmodel.AccessToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAccessToken", ctx, input)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(pgmodel.AccessToken)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CreateAccessToken indicates an expected call of CreateAccessToken.
+func (mr *MockAuthMockRecorder) CreateAccessToken(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccessToken", reflect.TypeOf((*MockAuth)(nil).CreateAccessToken), ctx, input)
+}
+
 // CreateToken mocks base method.
-func (m *MockAuth) CreateToken(ctx context.Context, input service.UserAuthInput) (string, error) {
+func (m *MockAuth) CreateToken(ctx context.Context, input service.UserAuthInput) (service.Tokens, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateToken", ctx, input)
-	ret0, _ := ret[0].(string)
+	ret0, _ := ret[0].(service.Tokens)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -79,19 +153,205 @@ func (mr *MockAuthMockRecorder) DeleteUser(ctx, input interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockAuth)(nil).DeleteUser), ctx, input)
 }
 
+// DisableTOTP mocks base method.
+func (m *MockAuth) DisableTOTP(ctx context.Context, input service.TOTPDisableInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTOTP", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTOTP indicates an expected call of DisableTOTP.
+func (mr *MockAuthMockRecorder) DisableTOTP(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTOTP", reflect.TypeOf((*MockAuth)(nil).DisableTOTP), ctx, input)
+}
+
+// EnrollTOTP mocks base method.
+func (m *MockAuth) EnrollTOTP(ctx context.Context, username string) (service.TOTPEnrollment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnrollTOTP", ctx, username)
+	ret0, _ := ret[0].(service.TOTPEnrollment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnrollTOTP indicates an expected call of EnrollTOTP.
+func (mr *MockAuthMockRecorder) EnrollTOTP(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnrollTOTP", reflect.TypeOf((*MockAuth)(nil).EnrollTOTP), ctx, username)
+}
+
+// GetAccessTokens mocks base method.
+func (m *MockAuth) GetAccessTokens(ctx context.Context, username string) ([]pgmodel.AccessToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccessTokens", ctx, username)
+	ret0, _ := ret[0].([]pgmodel.AccessToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccessTokens indicates an expected call of GetAccessTokens.
+func (mr *MockAuthMockRecorder) GetAccessTokens(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccessTokens", reflect.TypeOf((*MockAuth)(nil).GetAccessTokens), ctx, username)
+}
+
+// GetSessions mocks base method.
+func (m *MockAuth) GetSessions(ctx context.Context, username string) ([]service.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessions", ctx, username)
+	ret0, _ := ret[0].([]service.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessions indicates an expected call of GetSessions.
+func (mr *MockAuthMockRecorder) GetSessions(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessions", reflect.TypeOf((*MockAuth)(nil).GetSessions), ctx, username)
+}
+
+// JWKS mocks base method.
+func (m *MockAuth) JWKS(ctx context.Context) (jwk.Set, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "JWKS", ctx)
+	ret0, _ := ret[0].(jwk.Set)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// JWKS indicates an expected call of JWKS.
+func (mr *MockAuthMockRecorder) JWKS(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JWKS", reflect.TypeOf((*MockAuth)(nil).JWKS), ctx)
+}
+
 // RefreshToken mocks base method.
-func (m *MockAuth) RefreshToken(ctx context.Context, token string) (string, error) {
+func (m *MockAuth) RefreshToken(ctx context.Context, refreshToken string) (service.Tokens, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RefreshToken", ctx, token)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "RefreshToken", ctx, refreshToken)
+	ret0, _ := ret[0].(service.Tokens)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // RefreshToken indicates an expected call of RefreshToken.
-func (mr *MockAuthMockRecorder) RefreshToken(ctx, token interface{}) *gomock.Call {
+func (mr *MockAuthMockRecorder) RefreshToken(ctx, refreshToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshToken", reflect.TypeOf((*MockAuth)(nil).RefreshToken), ctx, refreshToken)
+}
+
+// RequestPasswordReset mocks base method.
+func (m *MockAuth) RequestPasswordReset(ctx context.Context, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestPasswordReset", ctx, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RequestPasswordReset indicates an expected call of RequestPasswordReset.
+func (mr *MockAuthMockRecorder) RequestPasswordReset(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestPasswordReset", reflect.TypeOf((*MockAuth)(nil).RequestPasswordReset), ctx, email)
+}
+
+// ResetPassword mocks base method.
+func (m *MockAuth) ResetPassword(ctx context.Context, input service.PasswordResetInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockAuthMockRecorder) ResetPassword(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockAuth)(nil).ResetPassword), ctx, input)
+}
+
+// RevokeAccessToken mocks base method.
+func (m *MockAuth) RevokeAccessToken(ctx context.Context, input service.AccessTokenRevokeInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAccessToken", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAccessToken indicates an expected call of RevokeAccessToken.
+func (mr *MockAuthMockRecorder) RevokeAccessToken(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAccessToken", reflect.TypeOf((*MockAuth)(nil).RevokeAccessToken), ctx, input)
+}
+
+// RevokeAllSessions mocks base method.
+func (m *MockAuth) RevokeAllSessions(ctx context.Context, username string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAllSessions", ctx, username)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAllSessions indicates an expected call of RevokeAllSessions.
+func (mr *MockAuthMockRecorder) RevokeAllSessions(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAllSessions", reflect.TypeOf((*MockAuth)(nil).RevokeAllSessions), ctx, username)
+}
+
+// RevokeSession mocks base method.
+func (m *MockAuth) RevokeSession(ctx context.Context, input service.SessionRevokeInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockAuthMockRecorder) RevokeSession(ctx, input interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshToken", reflect.TypeOf((*MockAuth)(nil).RefreshToken), ctx, token)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockAuth)(nil).RevokeSession), ctx, input)
+}
+
+// RevokeToken mocks base method.
+func (m *MockAuth) RevokeToken(ctx context.Context, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeToken", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeToken indicates an expected call of RevokeToken.
+func (mr *MockAuthMockRecorder) RevokeToken(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeToken", reflect.TypeOf((*MockAuth)(nil).RevokeToken), ctx, token)
+}
+
+// SendEmailConfirmation mocks base method.
+func (m *MockAuth) SendEmailConfirmation(ctx context.Context, username string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendEmailConfirmation", ctx, username)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendEmailConfirmation indicates an expected call of SendEmailConfirmation.
+func (mr *MockAuthMockRecorder) SendEmailConfirmation(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendEmailConfirmation", reflect.TypeOf((*MockAuth)(nil).SendEmailConfirmation), ctx, username)
+}
+
+// SignOut mocks base method.
+func (m *MockAuth) SignOut(ctx context.Context, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SignOut", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SignOut indicates an expected call of SignOut.
+func (mr *MockAuthMockRecorder) SignOut(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignOut", reflect.TypeOf((*MockAuth)(nil).SignOut), ctx, token)
 }
 
 // UpdateUsername mocks base method.
@@ -109,10 +369,10 @@ func (mr *MockAuthMockRecorder) UpdateUsername(ctx, input interface{}) *gomock.C
 }
 
 // ValidateToken mocks base method.
-func (m *MockAuth) ValidateToken(ctx context.Context, token string) (string, error) {
+func (m *MockAuth) ValidateToken(ctx context.Context, token string) (*service.TokenClaims, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ValidateToken", ctx, token)
-	ret0, _ := ret[0].(string)
+	ret0, _ := ret[0].(*service.TokenClaims)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -123,6 +383,21 @@ func (mr *MockAuthMockRecorder) ValidateToken(ctx, token interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateToken", reflect.TypeOf((*MockAuth)(nil).ValidateToken), ctx, token)
 }
 
+// VerifyTOTP mocks base method.
+func (m *MockAuth) VerifyTOTP(ctx context.Context, input service.TOTPVerifyInput) (service.Tokens, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyTOTP", ctx, input)
+	ret0, _ := ret[0].(service.Tokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyTOTP indicates an expected call of VerifyTOTP.
+func (mr *MockAuthMockRecorder) VerifyTOTP(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyTOTP", reflect.TypeOf((*MockAuth)(nil).VerifyTOTP), ctx, input)
+}
+
 // MockUser is a mock of User interface.
 type MockUser struct {
 	ctrl     *gomock.Controller
@@ -175,6 +450,130 @@ func (mr *MockUserMockRecorder) UpdateFullName(ctx, input interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateFullName", reflect.TypeOf((*MockUser)(nil).UpdateFullName), ctx, input)
 }
 
+// UpdateRole mocks base method.
+func (m *MockUser) UpdateRole(ctx context.Context, input service.UserUpdateRoleInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRole", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRole indicates an expected call of UpdateRole.
+func (mr *MockUserMockRecorder) UpdateRole(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRole", reflect.TypeOf((*MockUser)(nil).UpdateRole), ctx, input)
+}
+
+// MockAdmin is a mock of Admin interface.
+type MockAdmin struct {
+	ctrl     *gomock.Controller
+	recorder *MockAdminMockRecorder
+}
+
+// MockAdminMockRecorder is the mock recorder for MockAdmin.
+type MockAdminMockRecorder struct {
+	mock *MockAdmin
+}
+
+// NewMockAdmin creates a new mock instance.
+func NewMockAdmin(ctrl *gomock.Controller) *MockAdmin {
+	mock := &MockAdmin{ctrl: ctrl}
+	mock.recorder = &MockAdminMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAdmin) EXPECT() *MockAdminMockRecorder {
+	return m.recorder
+}
+
+// DeleteUser mocks base method.
+func (m *MockAdmin) DeleteUser(ctx context.Context, input service.AdminUserInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUser", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUser indicates an expected call of DeleteUser.
+func (mr *MockAdminMockRecorder) DeleteUser(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockAdmin)(nil).DeleteUser), ctx, input)
+}
+
+// GetUser mocks base method.
+func (m *MockAdmin) GetUser(ctx context.Context, username string) (pgmodel.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUser", ctx, username)
+	ret0, _ := ret[0].(pgmodel.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUser indicates an expected call of GetUser.
+func (mr *MockAdminMockRecorder) GetUser(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockAdmin)(nil).GetUser), ctx, username)
+}
+
+// GetUsers mocks base method.
+func (m *MockAdmin) GetUsers(ctx context.Context, query string, p repo.Pagination) ([]pgmodel.User, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsers", ctx, query, p)
+	ret0, _ := ret[0].([]pgmodel.User)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetUsers indicates an expected call of GetUsers.
+func (mr *MockAdminMockRecorder) GetUsers(ctx, query, p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsers", reflect.TypeOf((*MockAdmin)(nil).GetUsers), ctx, query, p)
+}
+
+// SignOutUser mocks base method.
+func (m *MockAdmin) SignOutUser(ctx context.Context, input service.AdminUserInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SignOutUser", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SignOutUser indicates an expected call of SignOutUser.
+func (mr *MockAdminMockRecorder) SignOutUser(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignOutUser", reflect.TypeOf((*MockAdmin)(nil).SignOutUser), ctx, input)
+}
+
+// SuspendUser mocks base method.
+func (m *MockAdmin) SuspendUser(ctx context.Context, input service.AdminUserInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SuspendUser", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SuspendUser indicates an expected call of SuspendUser.
+func (mr *MockAdminMockRecorder) SuspendUser(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SuspendUser", reflect.TypeOf((*MockAdmin)(nil).SuspendUser), ctx, input)
+}
+
+// UnsuspendUser mocks base method.
+func (m *MockAdmin) UnsuspendUser(ctx context.Context, input service.AdminUserInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnsuspendUser", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnsuspendUser indicates an expected call of UnsuspendUser.
+func (mr *MockAdminMockRecorder) UnsuspendUser(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnsuspendUser", reflect.TypeOf((*MockAdmin)(nil).UnsuspendUser), ctx, input)
+}
+
 // MockPost is a mock of Post interface.
 type MockPost struct {
 	ctrl     *gomock.Controller
@@ -213,6 +612,52 @@ func (mr *MockPostMockRecorder) CreatePost(ctx, input interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePost", reflect.TypeOf((*MockPost)(nil).CreatePost), ctx, input)
 }
 
+// DeletePost mocks base method.
+func (m *MockPost) DeletePost(ctx context.Context, input service.PostDeleteInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePost", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePost indicates an expected call of DeletePost.
+func (mr *MockPostMockRecorder) DeletePost(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePost", reflect.TypeOf((*MockPost)(nil).DeletePost), ctx, input)
+}
+
+// GetFeed mocks base method.
+func (m *MockPost) GetFeed(ctx context.Context, username string, p repo.Pagination) ([]service.FeedItem, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFeed", ctx, username, p)
+	ret0, _ := ret[0].([]service.FeedItem)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetFeed indicates an expected call of GetFeed.
+func (mr *MockPostMockRecorder) GetFeed(ctx, username, p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFeed", reflect.TypeOf((*MockPost)(nil).GetFeed), ctx, username, p)
+}
+
+// GetManyPosts mocks base method.
+func (m *MockPost) GetManyPosts(ctx context.Context, username string, p repo.Pagination) ([]pgmodel.Post, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetManyPosts", ctx, username, p)
+	ret0, _ := ret[0].([]pgmodel.Post)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetManyPosts indicates an expected call of GetManyPosts.
+func (mr *MockPostMockRecorder) GetManyPosts(ctx, username, p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetManyPosts", reflect.TypeOf((*MockPost)(nil).GetManyPosts), ctx, username, p)
+}
+
 // GetPostById mocks base method.
 func (m *MockPost) GetPostById(ctx context.Context, postId string) (pgmodel.Post, error) {
 	m.ctrl.T.Helper()
@@ -228,6 +673,20 @@ func (mr *MockPostMockRecorder) GetPostById(ctx, postId interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPostById", reflect.TypeOf((*MockPost)(nil).GetPostById), ctx, postId)
 }
 
+// UpdatePost mocks base method.
+func (m *MockPost) UpdatePost(ctx context.Context, input service.PostUpdateInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePost", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePost indicates an expected call of UpdatePost.
+func (mr *MockPostMockRecorder) UpdatePost(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePost", reflect.TypeOf((*MockPost)(nil).UpdatePost), ctx, input)
+}
+
 // MockReaction is a mock of Reaction interface.
 type MockReaction struct {
 	ctrl     *gomock.Controller
@@ -267,32 +726,33 @@ func (mr *MockReactionMockRecorder) CreateReaction(ctx, input interface{}) *gomo
 }
 
 // DeleteReaction mocks base method.
-func (m *MockReaction) DeleteReaction(ctx context.Context, reactionId string) error {
+func (m *MockReaction) DeleteReaction(ctx context.Context, input service.ReactionDeleteInput) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteReaction", ctx, reactionId)
+	ret := m.ctrl.Call(m, "DeleteReaction", ctx, input)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteReaction indicates an expected call of DeleteReaction.
-func (mr *MockReactionMockRecorder) DeleteReaction(ctx, reactionId interface{}) *gomock.Call {
+func (mr *MockReactionMockRecorder) DeleteReaction(ctx, input interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteReaction", reflect.TypeOf((*MockReaction)(nil).DeleteReaction), ctx, reactionId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteReaction", reflect.TypeOf((*MockReaction)(nil).DeleteReaction), ctx, input)
 }
 
 // GetManyReactions mocks base method.
-func (m *MockReaction) GetManyReactions(ctx context.Context, postId string) (map[string]string, error) {
+func (m *MockReaction) GetManyReactions(ctx context.Context, postId string, p repo.Pagination) ([]pgmodel.Reaction, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetManyReactions", ctx, postId)
-	ret0, _ := ret[0].(map[string]string)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "GetManyReactions", ctx, postId, p)
+	ret0, _ := ret[0].([]pgmodel.Reaction)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // GetManyReactions indicates an expected call of GetManyReactions.
-func (mr *MockReactionMockRecorder) GetManyReactions(ctx, postId interface{}) *gomock.Call {
+func (mr *MockReactionMockRecorder) GetManyReactions(ctx, postId, p interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetManyReactions", reflect.TypeOf((*MockReaction)(nil).GetManyReactions), ctx, postId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetManyReactions", reflect.TypeOf((*MockReaction)(nil).GetManyReactions), ctx, postId, p)
 }
 
 // GetReactionById mocks base method.
@@ -310,6 +770,36 @@ func (mr *MockReactionMockRecorder) GetReactionById(ctx, reactionId interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReactionById", reflect.TypeOf((*MockReaction)(nil).GetReactionById), ctx, reactionId)
 }
 
+// GetReactionSummary mocks base method.
+func (m *MockReaction) GetReactionSummary(ctx context.Context, postId string) (map[string]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReactionSummary", ctx, postId)
+	ret0, _ := ret[0].(map[string]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReactionSummary indicates an expected call of GetReactionSummary.
+func (mr *MockReactionMockRecorder) GetReactionSummary(ctx, postId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReactionSummary", reflect.TypeOf((*MockReaction)(nil).GetReactionSummary), ctx, postId)
+}
+
+// GetUserReaction mocks base method.
+func (m *MockReaction) GetUserReaction(ctx context.Context, postId, username string) (pgmodel.Reaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserReaction", ctx, postId, username)
+	ret0, _ := ret[0].(pgmodel.Reaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserReaction indicates an expected call of GetUserReaction.
+func (mr *MockReactionMockRecorder) GetUserReaction(ctx, postId, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserReaction", reflect.TypeOf((*MockReaction)(nil).GetUserReaction), ctx, postId, username)
+}
+
 // MockComment is a mock of Comment interface.
 type MockComment struct {
 	ctrl     *gomock.Controller
@@ -377,19 +867,36 @@ func (mr *MockCommentMockRecorder) GetCommentById(ctx, commentId interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCommentById", reflect.TypeOf((*MockComment)(nil).GetCommentById), ctx, commentId)
 }
 
+// GetCommentTree mocks base method.
+func (m *MockComment) GetCommentTree(ctx context.Context, postId string, depth int, p repo.Pagination) ([]service.CommentNode, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCommentTree", ctx, postId, depth, p)
+	ret0, _ := ret[0].([]service.CommentNode)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetCommentTree indicates an expected call of GetCommentTree.
+func (mr *MockCommentMockRecorder) GetCommentTree(ctx, postId, depth, p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCommentTree", reflect.TypeOf((*MockComment)(nil).GetCommentTree), ctx, postId, depth, p)
+}
+
 // GetManyComments mocks base method.
-func (m *MockComment) GetManyComments(ctx context.Context, filter, filterParams string) (map[string]string, error) {
+func (m *MockComment) GetManyComments(ctx context.Context, filter, filterParams string, p repo.Pagination) ([]pgmodel.Comment, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetManyComments", ctx, filter, filterParams)
-	ret0, _ := ret[0].(map[string]string)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "GetManyComments", ctx, filter, filterParams, p)
+	ret0, _ := ret[0].([]pgmodel.Comment)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // GetManyComments indicates an expected call of GetManyComments.
-func (mr *MockCommentMockRecorder) GetManyComments(ctx, filter, filterParams interface{}) *gomock.Call {
+func (mr *MockCommentMockRecorder) GetManyComments(ctx, filter, filterParams, p interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetManyComments", reflect.TypeOf((*MockComment)(nil).GetManyComments), ctx, filter, filterParams)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetManyComments", reflect.TypeOf((*MockComment)(nil).GetManyComments), ctx, filter, filterParams, p)
 }
 
 // UpdateComment mocks base method.
@@ -405,3 +912,139 @@ func (mr *MockCommentMockRecorder) UpdateComment(ctx, input interface{}) *gomock
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateComment", reflect.TypeOf((*MockComment)(nil).UpdateComment), ctx, input)
 }
+
+// MockFollow is a mock of Follow interface.
+type MockFollow struct {
+	ctrl     *gomock.Controller
+	recorder *MockFollowMockRecorder
+}
+
+// MockFollowMockRecorder is the mock recorder for MockFollow.
+type MockFollowMockRecorder struct {
+	mock *MockFollow
+}
+
+// NewMockFollow creates a new mock instance.
+func NewMockFollow(ctrl *gomock.Controller) *MockFollow {
+	mock := &MockFollow{ctrl: ctrl}
+	mock.recorder = &MockFollowMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFollow) EXPECT() *MockFollowMockRecorder {
+	return m.recorder
+}
+
+// Follow mocks base method.
+func (m *MockFollow) Follow(ctx context.Context, input service.FollowInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Follow", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Follow indicates an expected call of Follow.
+func (mr *MockFollowMockRecorder) Follow(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Follow", reflect.TypeOf((*MockFollow)(nil).Follow), ctx, input)
+}
+
+// GetFollowCounts mocks base method.
+func (m *MockFollow) GetFollowCounts(ctx context.Context, username string) (service.FollowCounts, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFollowCounts", ctx, username)
+	ret0, _ := ret[0].(service.FollowCounts)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFollowCounts indicates an expected call of GetFollowCounts.
+func (mr *MockFollowMockRecorder) GetFollowCounts(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFollowCounts", reflect.TypeOf((*MockFollow)(nil).GetFollowCounts), ctx, username)
+}
+
+// GetFollowers mocks base method.
+func (m *MockFollow) GetFollowers(ctx context.Context, username string, p repo.Pagination) ([]pgmodel.Follow, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFollowers", ctx, username, p)
+	ret0, _ := ret[0].([]pgmodel.Follow)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetFollowers indicates an expected call of GetFollowers.
+func (mr *MockFollowMockRecorder) GetFollowers(ctx, username, p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFollowers", reflect.TypeOf((*MockFollow)(nil).GetFollowers), ctx, username, p)
+}
+
+// GetFollowing mocks base method.
+func (m *MockFollow) GetFollowing(ctx context.Context, username string, p repo.Pagination) ([]pgmodel.Follow, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFollowing", ctx, username, p)
+	ret0, _ := ret[0].([]pgmodel.Follow)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetFollowing indicates an expected call of GetFollowing.
+func (mr *MockFollowMockRecorder) GetFollowing(ctx, username, p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFollowing", reflect.TypeOf((*MockFollow)(nil).GetFollowing), ctx, username, p)
+}
+
+// Unfollow mocks base method.
+func (m *MockFollow) Unfollow(ctx context.Context, input service.FollowInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unfollow", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unfollow indicates an expected call of Unfollow.
+func (mr *MockFollowMockRecorder) Unfollow(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unfollow", reflect.TypeOf((*MockFollow)(nil).Unfollow), ctx, input)
+}
+
+// MockPostEvents is a mock of PostEvents interface.
+type MockPostEvents struct {
+	ctrl     *gomock.Controller
+	recorder *MockPostEventsMockRecorder
+}
+
+// MockPostEventsMockRecorder is the mock recorder for MockPostEvents.
+type MockPostEventsMockRecorder struct {
+	mock *MockPostEvents
+}
+
+// NewMockPostEvents creates a new mock instance.
+func NewMockPostEvents(ctrl *gomock.Controller) *MockPostEvents {
+	mock := &MockPostEvents{ctrl: ctrl}
+	mock.recorder = &MockPostEventsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPostEvents) EXPECT() *MockPostEventsMockRecorder {
+	return m.recorder
+}
+
+// WatchPost mocks base method.
+func (m *MockPostEvents) WatchPost(ctx context.Context, postId string) (<-chan service.PostEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchPost", ctx, postId)
+	ret0, _ := ret[0].(<-chan service.PostEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WatchPost indicates an expected call of WatchPost.
+func (mr *MockPostEventsMockRecorder) WatchPost(ctx, postId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchPost", reflect.TypeOf((*MockPostEvents)(nil).WatchPost), ctx, postId)
+}
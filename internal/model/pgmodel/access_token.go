@@ -0,0 +1,16 @@
+package pgmodel
+
+import "time"
+
+// AccessToken is personal access token of user. Only hash of the token is stored
+type AccessToken struct {
+	Id         int        `db:"id"`
+	TokenId    string     `db:"token_id"`
+	Username   string     `db:"username"`
+	Name       string     `db:"name"`
+	TokenHash  string     `db:"token_hash"`
+	Scopes     []string   `db:"scopes"`
+	ExpiresAt  *time.Time `db:"expires_at"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
@@ -1,9 +1,14 @@
 package pgmodel
 
+import "time"
+
 type Comment struct {
-	Id        int    `db:"id"`
-	Username  string `db:"username"`
-	PostId    string `db:"post_id"`
-	CommentId string `db:"comment_id"`
-	Comment   string `db:"comment"`
+	Id        int       `db:"id"`
+	Username  string    `db:"username"`
+	PostId    string    `db:"post_id"`
+	CommentId string    `db:"comment_id"`
+	Comment   string    `db:"comment"`
+	ParentId  *string   `db:"parent_id"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
 }
@@ -0,0 +1,10 @@
+package pgmodel
+
+import "time"
+
+type Follow struct {
+	Id        int       `db:"id"`
+	Follower  string    `db:"follower"`
+	Followee  string    `db:"followee"`
+	CreatedAt time.Time `db:"created_at"`
+}
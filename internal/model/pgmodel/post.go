@@ -1,9 +1,13 @@
 package pgmodel
 
+import "time"
+
 type Post struct {
-	Id       int    `db:"id"`
-	Username string `db:"username"`
-	PostId   string `db:"post_id"`
-	Title    string `db:"title"`
-	Text     string `db:"text"`
+	Id        int       `db:"id"`
+	Username  string    `db:"username"`
+	PostId    string    `db:"post_id"`
+	Title     string    `db:"title"`
+	Text      string    `db:"text"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
 }
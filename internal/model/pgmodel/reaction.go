@@ -1,8 +1,13 @@
 package pgmodel
 
+import "time"
+
 type Reaction struct {
-	Id         int    `db:"id"`
-	PostId     string `db:"post_id"`
-	ReactionId string `db:"reaction_id"`
-	Reaction   string `db:"reaction"`
+	Id         int       `db:"id"`
+	PostId     string    `db:"post_id"`
+	ReactionId string    `db:"reaction_id"`
+	Reaction   string    `db:"reaction"`
+	Username   string    `db:"username"`
+	CreatedAt  time.Time `db:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at"`
 }
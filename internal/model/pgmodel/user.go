@@ -1,10 +1,23 @@
 package pgmodel
 
+import "time"
+
 type User struct {
-	Id        int    `db:"id"`
-	Username  string `db:"username"`
-	FirstName string `db:"first_name"`
-	LastName  string `db:"last_name"`
-	Email     string `db:"email"`
-	Password  string `db:"password"`
+	Id            int     `db:"id"`
+	Username      string  `db:"username"`
+	FirstName     string  `db:"first_name"`
+	LastName      string  `db:"last_name"`
+	Email         string  `db:"email"`
+	Password      string  `db:"password"`
+	EmailVerified bool    `db:"email_verified"`
+	PendingEmail  *string `db:"pending_email"`
+	TOTPSecret    *string `db:"totp_secret"`
+	TOTPEnabled   bool    `db:"totp_enabled"`
+	// RecoveryCodes are sha256 hashes of unused 2fa recovery codes
+	RecoveryCodes []string `db:"totp_recovery_codes"`
+	Role          string   `db:"role"`
+	// SuspendedAt is set while user is suspended by admin
+	SuspendedAt *time.Time `db:"suspended_at"`
+	CreatedAt   time.Time  `db:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at"`
 }
@@ -0,0 +1,49 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+)
+
+const DefaultLimit = 20
+
+// Sort is an order of items by creation time
+type Sort string
+
+const (
+	SortNewest Sort = "newest"
+	SortOldest Sort = "oldest"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Pagination is a keyset pagination params. Cursor is opaque for clients, it points to the last item of previous page
+type Pagination struct {
+	Limit  int
+	Cursor string
+	Sort   Sort // empty means default sort of the list
+}
+
+func (p Pagination) Size() int {
+	if p.Limit <= 0 {
+		return DefaultLimit
+	}
+	return p.Limit
+}
+
+func EncodeCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+func DecodeCursor(cursor string) (int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.Atoi(string(b))
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
+}
@@ -0,0 +1,141 @@
+package pgdb
+
+import (
+	"API_for_SN_go/internal/model/pgmodel"
+	"API_for_SN_go/internal/repo/pgerrs"
+	"API_for_SN_go/pkg/postgres"
+	"context"
+	"errors"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	log "github.com/sirupsen/logrus"
+)
+
+const accessTokenPrefixLog = "/pgdb/access_token"
+
+var accessTokenColumns = []string{"id", "token_id", "username", "name", "token_hash", "scopes", "expires_at", "last_used_at", "created_at"}
+
+type AccessTokenRepo struct {
+	*postgres.Postgres
+}
+
+func NewAccessTokenRepo(pg *postgres.Postgres) *AccessTokenRepo {
+	return &AccessTokenRepo{pg}
+}
+
+func (r *AccessTokenRepo) CreateAccessToken(ctx context.Context, t pgmodel.AccessToken) error {
+	sql, args, _ := r.Builder.
+		Insert("access_token").
+		Columns("token_id", "username", "name", "token_hash", "scopes", "expires_at").
+		Values(t.TokenId, t.Username, t.Name, t.TokenHash, t.Scopes, t.ExpiresAt).
+		ToSql()
+	if _, err := r.Pool.Exec(ctx, sql, args...); err != nil {
+		var pgErr *pgconn.PgError
+		if ok := errors.As(err, &pgErr); ok {
+			if pgErr.Code == "23505" {
+				return pgerrs.ErrAlreadyExists
+			}
+			if pgErr.Code == "23503" {
+				return pgerrs.ErrForeignKey
+			}
+		}
+		log.Errorf("%s/CreateAccessToken error exec stmt: %s", accessTokenPrefixLog, err)
+		return err
+	}
+	return nil
+}
+
+func (r *AccessTokenRepo) GetAccessTokenByHash(ctx context.Context, tokenHash string) (pgmodel.AccessToken, error) {
+	sql, args, _ := r.Builder.
+		Select(accessTokenColumns...).
+		From("access_token").
+		Where("token_hash = ?", tokenHash).
+		ToSql()
+
+	var t pgmodel.AccessToken
+	if err := scanAccessToken(r.Pool.QueryRow(ctx, sql, args...), &t); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgmodel.AccessToken{}, pgerrs.ErrNotFound
+		}
+		log.Errorf("%s/GetAccessTokenByHash error finding token: %s", accessTokenPrefixLog, err)
+		return pgmodel.AccessToken{}, err
+	}
+	return t, nil
+}
+
+// GetAccessTokens returns all user tokens, recent first
+func (r *AccessTokenRepo) GetAccessTokens(ctx context.Context, username string) ([]pgmodel.AccessToken, error) {
+	sql, args, _ := r.Builder.
+		Select(accessTokenColumns...).
+		From("access_token").
+		Where("username = ?", username).
+		OrderBy("id DESC").
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		log.Errorf("%s/GetAccessTokens error finding tokens: %s", accessTokenPrefixLog, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []pgmodel.AccessToken
+	for rows.Next() {
+		var t pgmodel.AccessToken
+		if err = scanAccessToken(rows, &t); err != nil {
+			log.Errorf("%s/GetAccessTokens error scan token: %s", accessTokenPrefixLog, err)
+			continue
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, nil
+}
+
+func (r *AccessTokenRepo) TouchAccessToken(ctx context.Context, tokenId string) error {
+	sql, args, _ := r.Builder.
+		Update("access_token").
+		Set("last_used_at", squirrel.Expr("now()")).
+		Where("token_id = ?", tokenId).
+		ToSql()
+	if _, err := r.Pool.Exec(ctx, sql, args...); err != nil {
+		log.Errorf("%s/TouchAccessToken error exec stmt: %s", accessTokenPrefixLog, err)
+		return err
+	}
+	return nil
+}
+
+func (r *AccessTokenRepo) DeleteAccessToken(ctx context.Context, username, tokenId string) error {
+	sql, args, _ := r.Builder.
+		Delete("access_token").
+		Where("username = ? AND token_id = ?", username, tokenId).
+		ToSql()
+
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		log.Errorf("%s/DeleteAccessToken error exec stmt: %s", accessTokenPrefixLog, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgerrs.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteUserAccessTokens deletes all personal access tokens of the user
+func (r *AccessTokenRepo) DeleteUserAccessTokens(ctx context.Context, username string) error {
+	sql, args, _ := r.Builder.
+		Delete("access_token").
+		Where("username = ?", username).
+		ToSql()
+
+	if _, err := r.Pool.Exec(ctx, sql, args...); err != nil {
+		log.Errorf("%s/DeleteUserAccessTokens error exec stmt: %s", accessTokenPrefixLog, err)
+		return err
+	}
+	return nil
+}
+
+func scanAccessToken(row pgx.Row, t *pgmodel.AccessToken) error {
+	return row.Scan(&t.Id, &t.TokenId, &t.Username, &t.Name, &t.TokenHash, &t.Scopes, &t.ExpiresAt, &t.LastUsedAt, &t.CreatedAt)
+}
@@ -2,18 +2,25 @@ package pgdb
 
 import (
 	"API_for_SN_go/internal/model/pgmodel"
+	"API_for_SN_go/internal/repo/pagination"
 	"API_for_SN_go/internal/repo/pgerrs"
 	"API_for_SN_go/pkg/postgres"
 	"context"
 	"errors"
 	"fmt"
+	"github.com/Masterminds/squirrel"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	log "github.com/sirupsen/logrus"
+	"slices"
+	"strings"
+	"time"
 )
 
 const commentPrefixLog = "/pgdb/comment"
 
+var commentColumns = []string{"id", "username", "post_id", "comment_id", "comment", "parent_id", "created_at", "updated_at"}
+
 type CommentRepo struct {
 	*postgres.Postgres
 }
@@ -22,44 +29,41 @@ func NewCommentRepo(pg *postgres.Postgres) *CommentRepo {
 	return &CommentRepo{pg}
 }
 
-func (r *CommentRepo) CreateComment(ctx context.Context, c pgmodel.Comment) error {
+// CreateComment saves comment and returns it with generated id and timestamps
+func (r *CommentRepo) CreateComment(ctx context.Context, c pgmodel.Comment) (pgmodel.Comment, error) {
 	sql, args, _ := r.Builder.
 		Insert("comment").
-		Columns("username", "post_id", "comment_id", "comment").
-		Values(c.Username, c.PostId, c.CommentId, c.Comment).
+		Columns("username", "post_id", "comment_id", "comment", "parent_id").
+		Values(c.Username, c.PostId, c.CommentId, c.Comment, c.ParentId).
+		Suffix("RETURNING " + strings.Join(commentColumns, ", ")).
 		ToSql()
-	if _, err := r.Pool.Exec(ctx, sql, args...); err != nil {
+	var comment pgmodel.Comment
+	if err := scanComment(r.Pool.QueryRow(ctx, sql, args...), &comment); err != nil {
 		var pgErr *pgconn.PgError
 		if ok := errors.As(err, &pgErr); ok {
 			if pgErr.Code == "23505" {
-				return pgerrs.ErrAlreadyExists
+				return pgmodel.Comment{}, pgerrs.ErrAlreadyExists
 			}
 			if pgErr.Code == "23503" {
-				return pgerrs.ErrForeignKey
+				return pgmodel.Comment{}, pgerrs.ErrForeignKey
 			}
 		}
 		log.Errorf("%s/CreateComment error exec stmt: %s", commentPrefixLog, err)
-		return err
+		return pgmodel.Comment{}, err
 	}
-	return nil
+	return comment, nil
 }
 
 func (r *CommentRepo) GetCommentById(ctx context.Context, commentId string) (pgmodel.Comment, error) {
 	sql, args, _ := r.Builder.
-		Select("*").
+		Select(commentColumns...).
 		From("comment").
 		Where("comment_id = ?", commentId).
 		ToSql()
 
 	var comment pgmodel.Comment
 
-	err := r.Pool.QueryRow(ctx, sql, args...).Scan(
-		&comment.Id,
-		&comment.Username,
-		&comment.PostId,
-		&comment.CommentId,
-		&comment.Comment,
-	)
+	err := scanComment(r.Pool.QueryRow(ctx, sql, args...), &comment)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return pgmodel.Comment{}, pgerrs.ErrNotFound
@@ -70,41 +74,143 @@ func (r *CommentRepo) GetCommentById(ctx context.Context, commentId string) (pgm
 	return comment, nil
 }
 
-func (r *CommentRepo) GetManyComments(ctx context.Context, filter, filterParams string) ([]pgmodel.Comment, error) {
-	sql, args, _ := r.Builder.
-		Select("*").
-		From("comment").
-		Where(fmt.Sprintf("%s = ?", filter), filterParams).
-		ToSql()
+func (r *CommentRepo) GetManyComments(ctx context.Context, filter, filterParams string, p pagination.Pagination) ([]pgmodel.Comment, string, error) {
+	b, err := paginate(r.Builder.Select(commentColumns...).From("comment").Where(fmt.Sprintf("%s = ?", filter), filterParams), p, "id", pagination.SortOldest)
+	if err != nil {
+		return nil, "", err
+	}
+	sql, args, _ := b.ToSql()
 
 	rows, err := r.Pool.Query(ctx, sql, args...)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, pgerrs.ErrNotFound
-		}
-		log.Errorf("%s/GetManyComments error finding reactions by %s: %s", commentPrefixLog, filter, err)
-		return nil, err
+		log.Errorf("%s/GetManyComments error finding comments by %s: %s", commentPrefixLog, filter, err)
+		return nil, "", err
 	}
+	defer rows.Close()
+
 	var comments []pgmodel.Comment
 	for rows.Next() {
 		var comment pgmodel.Comment
-		err = rows.Scan(&comment.Id, &comment.Username, &comment.PostId, &comment.CommentId, &comment.Comment)
-		if err != nil {
-			if errors.Is(err, pgx.ErrNoRows) {
-				return nil, pgerrs.ErrNotFound
-			}
+		if err = scanComment(rows, &comment); err != nil {
 			log.Errorf("%s/GetManyComments error finding comments by %s: %s", commentPrefixLog, filter, err)
 			continue
 		}
 		comments = append(comments, comment)
 	}
-	return comments, nil
+	comments, next := nextPage(comments, p, func(c pgmodel.Comment) int { return c.Id })
+	return comments, next, nil
+}
+
+// GetCommentTree returns page of root post comments with their replies up to depth level (root comments have level 1).
+// Sort is applied both to root comments and replies
+func (r *CommentRepo) GetCommentTree(ctx context.Context, postId string, depth int, p pagination.Pagination) ([]pgmodel.Comment, string, error) {
+	// плейсхолдеры подзапроса нумерует внешний билдер
+	roots, err := paginate(squirrel.
+		Select(commentColumns...).
+		From("comment").
+		Where("post_id = ? AND parent_id IS NULL", postId), p, "id", pagination.SortOldest)
+	if err != nil {
+		return nil, "", err
+	}
+	rootsSql, rootsArgs, _ := roots.ToSql()
+
+	sql, args, _ := r.Builder.
+		Select(commentColumns...).
+		From("tree").
+		Prefix("WITH RECURSIVE roots AS ("+rootsSql+"), tree AS (", rootsArgs...).
+		Prefix(`SELECT id, username, post_id, comment_id, comment, parent_id, created_at, updated_at, 1 AS depth FROM roots
+			UNION ALL
+			SELECT c.id, c.username, c.post_id, c.comment_id, c.comment, c.parent_id, c.created_at, c.updated_at, t.depth + 1
+			FROM comment c JOIN tree t ON c.parent_id = t.comment_id WHERE t.depth < ?
+		)`, depth).
+		OrderBy("id").
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		log.Errorf("%s/GetCommentTree error finding comments tree: %s", commentPrefixLog, err)
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var (
+		comments []pgmodel.Comment
+		rootIds  []int
+	)
+	for rows.Next() {
+		var comment pgmodel.Comment
+		if err = scanComment(rows, &comment); err != nil {
+			log.Errorf("%s/GetCommentTree error scan comment: %s", commentPrefixLog, err)
+			continue
+		}
+		if comment.ParentId == nil {
+			rootIds = append(rootIds, comment.Id)
+		}
+		comments = append(comments, comment)
+	}
+	desc := isDesc(p, pagination.SortOldest)
+	if desc {
+		slices.Reverse(rootIds)
+	}
+
+	if len(rootIds) > p.Size() {
+		// отбрасываем лишний корневой комментарий вместе с ответами на него.
+		// Ответ всегда создается позже родителя, поэтому родитель встречается в выборке раньше
+		extra := rootIds[p.Size()]
+		dropped := make(map[string]bool)
+		page := comments[:0]
+		for _, c := range comments {
+			if c.Id == extra || (c.ParentId != nil && dropped[*c.ParentId]) {
+				dropped[c.CommentId] = true
+				continue
+			}
+			page = append(page, c)
+		}
+		comments = page
+	}
+	_, next := nextPage(rootIds, p, func(id int) int { return id })
+	if desc {
+		slices.Reverse(comments)
+	}
+	return comments, next, nil
+}
+
+// CountCommentsByPosts returns number of comments (with replies) for several posts at once
+func (r *CommentRepo) CountCommentsByPosts(ctx context.Context, postIds []string) (map[string]int, error) {
+	sql, args, _ := r.Builder.
+		Select("post_id", "count(*)").
+		From("comment").
+		Where("post_id = ANY(?)", postIds).
+		GroupBy("post_id").
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		log.Errorf("%s/CountCommentsByPosts error counting comments: %s", commentPrefixLog, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var (
+			postId string
+			count  int
+		)
+		if err = rows.Scan(&postId, &count); err != nil {
+			log.Errorf("%s/CountCommentsByPosts error scan comments count: %s", commentPrefixLog, err)
+			return nil, err
+		}
+		counts[postId] = count
+	}
+	return counts, nil
 }
 
 func (r *CommentRepo) UpdateComment(ctx context.Context, username, commentId, newComment string) error {
 	sql, args, _ := r.Builder.
 		Update("comment").
 		Set("comment", newComment).
+		Set("updated_at", squirrel.Expr("now()")).
 		Where("username = ? AND comment_id = ?", username, commentId).
 		ToSql()
 	if _, err := r.Pool.Exec(ctx, sql, args...); err != nil {
@@ -117,17 +223,66 @@ func (r *CommentRepo) UpdateComment(ctx context.Context, username, commentId, ne
 	return nil
 }
 
-func (r *CommentRepo) DeleteComment(ctx context.Context, username, commentId string) error {
+// DeleteComment deletes comment of the user with all replies. It returns deleted comments ordered by id and time of deletion
+func (r *CommentRepo) DeleteComment(ctx context.Context, username, commentId string) ([]pgmodel.Comment, time.Time, error) {
+	comments, deletedAt, err := r.deleteCommentTree(ctx, squirrel.Eq{"username": username, "comment_id": commentId})
+	if err != nil && !errors.Is(err, pgerrs.ErrNotFound) {
+		log.Errorf("%s/DeleteComment error delete comments: %s", commentPrefixLog, err)
+	}
+	return comments, deletedAt, err
+}
+
+// DeleteCommentById deletes comment of any author with all replies, used for moderation
+func (r *CommentRepo) DeleteCommentById(ctx context.Context, commentId string) ([]pgmodel.Comment, time.Time, error) {
+	comments, deletedAt, err := r.deleteCommentTree(ctx, squirrel.Eq{"comment_id": commentId})
+	if err != nil && !errors.Is(err, pgerrs.ErrNotFound) {
+		log.Errorf("%s/DeleteCommentById error delete comments: %s", commentPrefixLog, err)
+	}
+	return comments, deletedAt, err
+}
+
+// deleteCommentTree deletes comment and its replies. Replies are deleted by cascade too,
+// but RETURNING does not return cascade deleted rows, so the tree is deleted explicitly
+func (r *CommentRepo) deleteCommentTree(ctx context.Context, root squirrel.Eq) ([]pgmodel.Comment, time.Time, error) {
+	// плейсхолдеры подзапроса нумерует внешний билдер
+	rootSql, rootArgs, _ := squirrel.Select("comment_id").From("comment").Where(root).ToSql()
 	sql, args, _ := r.Builder.
 		Delete("comment").
-		Where("username = ? AND comment_id = ?", username, commentId).
+		Prefix("WITH RECURSIVE tree AS ("+rootSql+`
+			UNION ALL
+			SELECT c.comment_id FROM comment c JOIN tree t ON c.parent_id = t.comment_id
+		)`, rootArgs...).
+		Where("comment_id IN (SELECT comment_id FROM tree)").
+		Suffix("RETURNING " + strings.Join(commentColumns, ", ") + ", now()").
 		ToSql()
-	if _, err := r.Pool.Exec(ctx, sql, args...); err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return pgerrs.ErrNotFound
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer rows.Close()
+
+	var (
+		comments  []pgmodel.Comment
+		deletedAt time.Time
+	)
+	for rows.Next() {
+		var c pgmodel.Comment
+		if err = rows.Scan(&c.Id, &c.Username, &c.PostId, &c.CommentId, &c.Comment, &c.ParentId, &c.CreatedAt, &c.UpdatedAt, &deletedAt); err != nil {
+			return nil, time.Time{}, err
 		}
-		log.Errorf("%s/DeleteComment error exec stmt: %s", commentPrefixLog, err)
-		return err
+		comments = append(comments, c)
 	}
-	return nil
+	if err = rows.Err(); err != nil {
+		return nil, time.Time{}, err
+	}
+	if len(comments) == 0 {
+		return nil, time.Time{}, pgerrs.ErrNotFound
+	}
+	slices.SortFunc(comments, func(a, b pgmodel.Comment) int { return a.Id - b.Id })
+	return comments, deletedAt, nil
+}
+
+func scanComment(row pgx.Row, c *pgmodel.Comment) error {
+	return row.Scan(&c.Id, &c.Username, &c.PostId, &c.CommentId, &c.Comment, &c.ParentId, &c.CreatedAt, &c.UpdatedAt)
 }
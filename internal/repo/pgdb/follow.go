@@ -0,0 +1,118 @@
+package pgdb
+
+import (
+	"API_for_SN_go/internal/model/pgmodel"
+	"API_for_SN_go/internal/repo/pagination"
+	"API_for_SN_go/internal/repo/pgerrs"
+	"API_for_SN_go/pkg/postgres"
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5/pgconn"
+	log "github.com/sirupsen/logrus"
+)
+
+const followPrefixLog = "/pgdb/follow"
+
+var followColumns = []string{"id", "follower", "followee", "created_at"}
+
+type FollowRepo struct {
+	*postgres.Postgres
+}
+
+func NewFollowRepo(pg *postgres.Postgres) *FollowRepo {
+	return &FollowRepo{pg}
+}
+
+func (r *FollowRepo) CreateFollow(ctx context.Context, follower, followee string) error {
+	sql, args, _ := r.Builder.
+		Insert("follow").
+		Columns("follower", "followee").
+		Values(follower, followee).
+		ToSql()
+	if _, err := r.Pool.Exec(ctx, sql, args...); err != nil {
+		var pgErr *pgconn.PgError
+		if ok := errors.As(err, &pgErr); ok {
+			if pgErr.Code == "23505" {
+				return pgerrs.ErrAlreadyExists
+			}
+			if pgErr.Code == "23503" {
+				return pgerrs.ErrForeignKey
+			}
+		}
+		log.Errorf("%s/CreateFollow error exec stmt: %s", followPrefixLog, err)
+		return err
+	}
+	return nil
+}
+
+func (r *FollowRepo) DeleteFollow(ctx context.Context, follower, followee string) error {
+	sql, args, _ := r.Builder.
+		Delete("follow").
+		Where("follower = ? AND followee = ?", follower, followee).
+		ToSql()
+
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		log.Errorf("%s/DeleteFollow error exec stmt: %s", followPrefixLog, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgerrs.ErrNotFound
+	}
+	return nil
+}
+
+// GetFollowers returns users who follow username, recent followers first by default
+func (r *FollowRepo) GetFollowers(ctx context.Context, username string, p pagination.Pagination) ([]pgmodel.Follow, string, error) {
+	return r.getMany(ctx, "followee", username, p)
+}
+
+// GetFollowing returns users followed by username, recent first by default
+func (r *FollowRepo) GetFollowing(ctx context.Context, username string, p pagination.Pagination) ([]pgmodel.Follow, string, error) {
+	return r.getMany(ctx, "follower", username, p)
+}
+
+func (r *FollowRepo) getMany(ctx context.Context, column, username string, p pagination.Pagination) ([]pgmodel.Follow, string, error) {
+	b, err := paginate(r.Builder.Select(followColumns...).From("follow").Where(column+" = ?", username), p, "id", pagination.SortNewest)
+	if err != nil {
+		return nil, "", err
+	}
+	sql, args, _ := b.ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		log.Errorf("%s/getMany error finding follows by %s: %s", followPrefixLog, column, err)
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var follows []pgmodel.Follow
+	for rows.Next() {
+		var f pgmodel.Follow
+		if err = rows.Scan(&f.Id, &f.Follower, &f.Followee, &f.CreatedAt); err != nil {
+			log.Errorf("%s/getMany error scan follow: %s", followPrefixLog, err)
+			continue
+		}
+		follows = append(follows, f)
+	}
+	follows, next := nextPage(follows, p, func(f pgmodel.Follow) int { return f.Id })
+	return follows, next, nil
+}
+
+// CountFollows returns number of followers and following of user
+func (r *FollowRepo) CountFollows(ctx context.Context, username string) (int, int, error) {
+	sql, args, _ := r.Builder.
+		Select().
+		Column("count(*) FILTER (WHERE followee = ?)", username).
+		Column("count(*) FILTER (WHERE follower = ?)", username).
+		From("follow").
+		Where("followee = ? OR follower = ?", username, username).
+		ToSql()
+
+	var followers, following int
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(&followers, &following); err != nil {
+		log.Errorf("%s/CountFollows error counting follows: %s", followPrefixLog, err)
+		return 0, 0, err
+	}
+	return followers, following, nil
+}
@@ -0,0 +1,44 @@
+package pgdb
+
+import (
+	"API_for_SN_go/internal/repo/pagination"
+	"github.com/Masterminds/squirrel"
+)
+
+// Keyset pagination by serial id column. Selects one extra row to find out if there is next page.
+// Serial id grows with creation time, so ordering by it is the same as ordering by created_at, but stable
+func paginate(b squirrel.SelectBuilder, p pagination.Pagination, column string, defaultSort pagination.Sort) (squirrel.SelectBuilder, error) {
+	desc := isDesc(p, defaultSort)
+	order := column
+	if desc {
+		order += " DESC"
+	}
+	if p.Cursor != "" {
+		id, err := pagination.DecodeCursor(p.Cursor)
+		if err != nil {
+			return b, err
+		}
+		if desc {
+			b = b.Where(column+" < ?", id)
+		} else {
+			b = b.Where(column+" > ?", id)
+		}
+	}
+	return b.OrderBy(order).Limit(uint64(p.Size() + 1)), nil
+}
+
+func isDesc(p pagination.Pagination, defaultSort pagination.Sort) bool {
+	if p.Sort == "" {
+		return defaultSort == pagination.SortNewest
+	}
+	return p.Sort == pagination.SortNewest
+}
+
+// Cuts extra row selected by paginate and returns cursor for next page (empty if it is the last page)
+func nextPage[T any](items []T, p pagination.Pagination, id func(T) int) ([]T, string) {
+	if len(items) <= p.Size() {
+		return items, ""
+	}
+	items = items[:p.Size()]
+	return items, pagination.EncodeCursor(id(items[len(items)-1]))
+}
@@ -2,17 +2,22 @@ package pgdb
 
 import (
 	"API_for_SN_go/internal/model/pgmodel"
+	"API_for_SN_go/internal/repo/pagination"
 	"API_for_SN_go/internal/repo/pgerrs"
 	"API_for_SN_go/pkg/postgres"
 	"context"
 	"errors"
+	"github.com/Masterminds/squirrel"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	log "github.com/sirupsen/logrus"
+	"time"
 )
 
 const postPrefixLog = "/pgdb/post"
 
+var postColumns = []string{"id", "username", "post_id", "title", "text", "created_at", "updated_at"}
+
 type PostRepo struct {
 	*postgres.Postgres
 }
@@ -45,7 +50,7 @@ func (r *PostRepo) CreatePost(ctx context.Context, p pgmodel.Post) error {
 
 func (r *PostRepo) GetPostById(ctx context.Context, postId string) (pgmodel.Post, error) {
 	sql, args, _ := r.Builder.
-		Select("*").
+		Select(postColumns...).
 		From("post").
 		Where("post_id = ?", postId).
 		ToSql()
@@ -58,6 +63,8 @@ func (r *PostRepo) GetPostById(ctx context.Context, postId string) (pgmodel.Post
 		&post.PostId,
 		&post.Title,
 		&post.Text,
+		&post.CreatedAt,
+		&post.UpdatedAt,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -68,3 +75,126 @@ func (r *PostRepo) GetPostById(ctx context.Context, postId string) (pgmodel.Post
 	}
 	return post, nil
 }
+
+func (r *PostRepo) GetManyPosts(ctx context.Context, username string, p pagination.Pagination) ([]pgmodel.Post, string, error) {
+	b, err := paginate(r.Builder.Select(postColumns...).From("post").Where("username = ?", username), p, "id", pagination.SortNewest)
+	if err != nil {
+		return nil, "", err
+	}
+	sql, args, _ := b.ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		log.Errorf("%s/GetManyPosts error finding posts by username: %s", postPrefixLog, err)
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var posts []pgmodel.Post
+	for rows.Next() {
+		var post pgmodel.Post
+		err = rows.Scan(&post.Id, &post.Username, &post.PostId, &post.Title, &post.Text, &post.CreatedAt, &post.UpdatedAt)
+		if err != nil {
+			log.Errorf("%s/GetManyPosts error scan post: %s", postPrefixLog, err)
+			continue
+		}
+		posts = append(posts, post)
+	}
+	posts, next := nextPage(posts, p, func(post pgmodel.Post) int { return post.Id })
+	return posts, next, nil
+}
+
+// GetFeed returns posts of users followed by username and user own posts
+func (r *PostRepo) GetFeed(ctx context.Context, username string, p pagination.Pagination) ([]pgmodel.Post, string, error) {
+	b, err := paginate(r.Builder.
+		Select(postColumns...).
+		From("post").
+		Where("username = ? OR username IN (SELECT followee FROM follow WHERE follower = ?)", username, username), p, "id", pagination.SortNewest)
+	if err != nil {
+		return nil, "", err
+	}
+	sql, args, _ := b.ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		log.Errorf("%s/GetFeed error finding feed posts: %s", postPrefixLog, err)
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var posts []pgmodel.Post
+	for rows.Next() {
+		var post pgmodel.Post
+		err = rows.Scan(&post.Id, &post.Username, &post.PostId, &post.Title, &post.Text, &post.CreatedAt, &post.UpdatedAt)
+		if err != nil {
+			log.Errorf("%s/GetFeed error scan post: %s", postPrefixLog, err)
+			continue
+		}
+		posts = append(posts, post)
+	}
+	posts, next := nextPage(posts, p, func(post pgmodel.Post) int { return post.Id })
+	return posts, next, nil
+}
+
+func (r *PostRepo) UpdatePost(ctx context.Context, username, postId, title, text string) error {
+	b := r.Builder.
+		Update("post").
+		Set("updated_at", squirrel.Expr("now()")).
+		Where("username = ? AND post_id = ?", username, postId)
+	if title != "" {
+		b = b.Set("title", title)
+	}
+	if text != "" {
+		b = b.Set("text", text)
+	}
+	sql, args, _ := b.ToSql()
+
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		log.Errorf("%s/UpdatePost error exec stmt: %s", postPrefixLog, err)
+		return err
+	}
+	// пост не найден или принадлежит другому пользователю
+	if tag.RowsAffected() == 0 {
+		return pgerrs.ErrNotFound
+	}
+	return nil
+}
+
+// DeletePost deletes post of the user with its comments and reactions and returns time of deletion
+func (r *PostRepo) DeletePost(ctx context.Context, username, postId string) (time.Time, error) {
+	sql, args, _ := r.Builder.
+		Delete("post").
+		Where("username = ? AND post_id = ?", username, postId).
+		Suffix("RETURNING now()").
+		ToSql()
+
+	var deletedAt time.Time
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(&deletedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return time.Time{}, pgerrs.ErrNotFound
+		}
+		log.Errorf("%s/DeletePost error exec stmt: %s", postPrefixLog, err)
+		return time.Time{}, err
+	}
+	return deletedAt, nil
+}
+
+// DeletePostById deletes post of any author, used for moderation
+func (r *PostRepo) DeletePostById(ctx context.Context, postId string) (time.Time, error) {
+	sql, args, _ := r.Builder.
+		Delete("post").
+		Where("post_id = ?", postId).
+		Suffix("RETURNING now()").
+		ToSql()
+
+	var deletedAt time.Time
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(&deletedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return time.Time{}, pgerrs.ErrNotFound
+		}
+		log.Errorf("%s/DeletePostById error exec stmt: %s", postPrefixLog, err)
+		return time.Time{}, err
+	}
+	return deletedAt, nil
+}
@@ -2,6 +2,7 @@ package pgdb
 
 import (
 	"API_for_SN_go/internal/model/pgmodel"
+	"API_for_SN_go/internal/repo/pagination"
 	"API_for_SN_go/internal/repo/pgerrs"
 	"API_for_SN_go/pkg/postgres"
 	"context"
@@ -9,10 +10,15 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
 )
 
 const reactionPrefixLog = "/pgdb/reaction"
 
+// реакции, созданные до появления авторства, не имеют username
+var reactionColumns = []string{"id", "post_id", "reaction_id", "reaction", "coalesce(username, '')", "created_at", "updated_at"}
+
 type ReactionRepo struct {
 	*postgres.Postgres
 }
@@ -21,31 +27,50 @@ func NewReactionRepo(pg *postgres.Postgres) *ReactionRepo {
 	return &ReactionRepo{pg}
 }
 
-func (r *ReactionRepo) CreateReaction(ctx context.Context, rn pgmodel.Reaction) error {
+// UpsertReaction saves user reaction for post. Previous reaction of the user is replaced keeping its reaction_id,
+// returned flag is false in this case
+func (r *ReactionRepo) UpsertReaction(ctx context.Context, rn pgmodel.Reaction) (pgmodel.Reaction, bool, error) {
 	sql, args, _ := r.Builder.
 		Insert("reaction").
-		Columns("post_id", "reaction_id", "reaction").
-		Values(rn.PostId, rn.ReactionId, rn.Reaction).
+		Columns("post_id", "reaction_id", "reaction", "username").
+		Values(rn.PostId, rn.ReactionId, rn.Reaction, rn.Username).
+		Suffix("ON CONFLICT (post_id, username) DO UPDATE SET reaction = excluded.reaction, updated_at = now()").
+		Suffix("RETURNING " + strings.Join(reactionColumns, ", ") + ", xmax = 0").
 		ToSql()
-	if _, err := r.Pool.Exec(ctx, sql, args...); err != nil {
+
+	var (
+		reaction pgmodel.Reaction
+		inserted bool
+	)
+	err := r.Pool.QueryRow(ctx, sql, args...).Scan(
+		&reaction.Id,
+		&reaction.PostId,
+		&reaction.ReactionId,
+		&reaction.Reaction,
+		&reaction.Username,
+		&reaction.CreatedAt,
+		&reaction.UpdatedAt,
+		&inserted,
+	)
+	if err != nil {
 		var pgErr *pgconn.PgError
 		if ok := errors.As(err, &pgErr); ok {
 			if pgErr.Code == "23505" {
-				return pgerrs.ErrAlreadyExists
+				return pgmodel.Reaction{}, false, pgerrs.ErrAlreadyExists
 			}
 			if pgErr.Code == "23503" {
-				return pgerrs.ErrForeignKey
+				return pgmodel.Reaction{}, false, pgerrs.ErrForeignKey
 			}
 		}
-		log.Errorf("%s/CreateReaction error exec stmt: %s", reactionPrefixLog, err)
-		return err
+		log.Errorf("%s/UpsertReaction error exec stmt: %s", reactionPrefixLog, err)
+		return pgmodel.Reaction{}, false, err
 	}
-	return nil
+	return reaction, inserted, nil
 }
 
 func (r *ReactionRepo) GetReactionById(ctx context.Context, reactionId string) (pgmodel.Reaction, error) {
 	sql, args, _ := r.Builder.
-		Select("*").
+		Select(reactionColumns...).
 		From("reaction").
 		Where("reaction_id = ?", reactionId).
 		ToSql()
@@ -56,6 +81,9 @@ func (r *ReactionRepo) GetReactionById(ctx context.Context, reactionId string) (
 		&reaction.PostId,
 		&reaction.ReactionId,
 		&reaction.Reaction,
+		&reaction.Username,
+		&reaction.CreatedAt,
+		&reaction.UpdatedAt,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -67,47 +95,179 @@ func (r *ReactionRepo) GetReactionById(ctx context.Context, reactionId string) (
 	return reaction, nil
 }
 
-func (r *ReactionRepo) GetManyReactions(ctx context.Context, postId string) ([]pgmodel.Reaction, error) {
-	sql, args, _ := r.Builder.
-		Select("reaction_id", "reaction").
-		From("reaction").
-		Where("post_id = ?", postId).
-		ToSql()
+func (r *ReactionRepo) GetManyReactions(ctx context.Context, postId string, p pagination.Pagination) ([]pgmodel.Reaction, string, error) {
+	b, err := paginate(r.Builder.Select(reactionColumns...).From("reaction").Where("post_id = ?", postId), p, "id", pagination.SortOldest)
+	if err != nil {
+		return nil, "", err
+	}
+	sql, args, _ := b.ToSql()
+
 	rows, err := r.Pool.Query(ctx, sql, args...)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, pgerrs.ErrNotFound
-		}
 		log.Errorf("%s/GetManyReactions error finding reactions by post id: %s", reactionPrefixLog, err)
-		return nil, err
+		return nil, "", err
 	}
+	defer rows.Close()
+
 	var reactions []pgmodel.Reaction
 	for rows.Next() {
 		var reaction pgmodel.Reaction
-		err = rows.Scan(&reaction.ReactionId, &reaction.Reaction)
+		err = rows.Scan(&reaction.Id, &reaction.PostId, &reaction.ReactionId, &reaction.Reaction, &reaction.Username, &reaction.CreatedAt, &reaction.UpdatedAt)
 		if err != nil {
-			if errors.Is(err, pgx.ErrNoRows) {
-				return nil, pgerrs.ErrNotFound
-			}
-			log.Errorf("%s/GetManyReactions error finding reactions by post id: %s", reactionPrefixLog, err)
+			log.Errorf("%s/GetManyReactions error scan reaction: %s", reactionPrefixLog, err)
 			continue
 		}
 		reactions = append(reactions, reaction)
 	}
-	return reactions, nil
+	reactions, next := nextPage(reactions, p, func(rn pgmodel.Reaction) int { return rn.Id })
+	return reactions, next, nil
 }
 
-func (r *ReactionRepo) DeleteReaction(ctx context.Context, reactionId string) error {
+func (r *ReactionRepo) CountReactions(ctx context.Context, postId string) (map[string]int, error) {
+	sql, args, _ := r.Builder.
+		Select("reaction", "count(*)").
+		From("reaction").
+		Where("post_id = ?", postId).
+		GroupBy("reaction").
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		log.Errorf("%s/CountReactions error counting reactions by post id: %s", reactionPrefixLog, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var (
+			reaction string
+			count    int
+		)
+		if err = rows.Scan(&reaction, &count); err != nil {
+			log.Errorf("%s/CountReactions error scan reaction count: %s", reactionPrefixLog, err)
+			return nil, err
+		}
+		counts[reaction] = count
+	}
+	return counts, nil
+}
+
+// CountReactionsByPosts returns number of reactions of each kind for several posts at once: post_id -> reaction -> count
+func (r *ReactionRepo) CountReactionsByPosts(ctx context.Context, postIds []string) (map[string]map[string]int, error) {
+	sql, args, _ := r.Builder.
+		Select("post_id", "reaction", "count(*)").
+		From("reaction").
+		Where("post_id = ANY(?)", postIds).
+		GroupBy("post_id", "reaction").
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		log.Errorf("%s/CountReactionsByPosts error counting reactions: %s", reactionPrefixLog, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]map[string]int)
+	for rows.Next() {
+		var (
+			postId   string
+			reaction string
+			count    int
+		)
+		if err = rows.Scan(&postId, &reaction, &count); err != nil {
+			log.Errorf("%s/CountReactionsByPosts error scan reaction count: %s", reactionPrefixLog, err)
+			return nil, err
+		}
+		if counts[postId] == nil {
+			counts[postId] = make(map[string]int)
+		}
+		counts[postId][reaction] = count
+	}
+	return counts, nil
+}
+
+func (r *ReactionRepo) GetUserReaction(ctx context.Context, postId, username string) (pgmodel.Reaction, error) {
+	sql, args, _ := r.Builder.
+		Select(reactionColumns...).
+		From("reaction").
+		Where("post_id = ? AND username = ?", postId, username).
+		ToSql()
+
+	var reaction pgmodel.Reaction
+	err := r.Pool.QueryRow(ctx, sql, args...).Scan(
+		&reaction.Id,
+		&reaction.PostId,
+		&reaction.ReactionId,
+		&reaction.Reaction,
+		&reaction.Username,
+		&reaction.CreatedAt,
+		&reaction.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgmodel.Reaction{}, pgerrs.ErrNotFound
+		}
+		log.Errorf("%s/GetUserReaction error finding user reaction: %s", reactionPrefixLog, err)
+		return pgmodel.Reaction{}, err
+	}
+	return reaction, nil
+}
+
+// DeleteReaction deletes reaction of the user and returns deleted reaction with time of deletion
+func (r *ReactionRepo) DeleteReaction(ctx context.Context, username, reactionId string) (pgmodel.Reaction, time.Time, error) {
 	sql, args, _ := r.Builder.
 		Delete("reaction").
-		Where("reaction_id = ?", reactionId).
+		Where("username = ? AND reaction_id = ?", username, reactionId).
+		Suffix("RETURNING " + strings.Join(reactionColumns, ", ") + ", now()").
 		ToSql()
-	if _, err := r.Pool.Exec(ctx, sql, args...); err != nil {
+
+	deleted, deletedAt, err := scanDeletedReaction(r.Pool.QueryRow(ctx, sql, args...))
+	if err != nil {
+		// реакция не найдена или принадлежит другому пользователю
 		if errors.Is(err, pgx.ErrNoRows) {
-			return pgerrs.ErrNotFound
+			return pgmodel.Reaction{}, time.Time{}, pgerrs.ErrNotFound
 		}
 		log.Errorf("%s/DeleteReaction error exec stmt: %s", reactionPrefixLog, err)
-		return err
+		return pgmodel.Reaction{}, time.Time{}, err
+	}
+	return deleted, deletedAt, nil
+}
+
+// DeleteUserReaction deletes reaction of the user for post if it is of the given kind and returns deleted reaction
+func (r *ReactionRepo) DeleteUserReaction(ctx context.Context, postId, username, reaction string) (pgmodel.Reaction, time.Time, error) {
+	sql, args, _ := r.Builder.
+		Delete("reaction").
+		Where("post_id = ? AND username = ? AND reaction = ?", postId, username, reaction).
+		Suffix("RETURNING " + strings.Join(reactionColumns, ", ") + ", now()").
+		ToSql()
+
+	deleted, deletedAt, err := scanDeletedReaction(r.Pool.QueryRow(ctx, sql, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgmodel.Reaction{}, time.Time{}, pgerrs.ErrNotFound
+		}
+		log.Errorf("%s/DeleteUserReaction error exec stmt: %s", reactionPrefixLog, err)
+		return pgmodel.Reaction{}, time.Time{}, err
 	}
-	return nil
+	return deleted, deletedAt, nil
+}
+
+func scanDeletedReaction(row pgx.Row) (pgmodel.Reaction, time.Time, error) {
+	var (
+		deleted   pgmodel.Reaction
+		deletedAt time.Time
+	)
+	err := row.Scan(
+		&deleted.Id,
+		&deleted.PostId,
+		&deleted.ReactionId,
+		&deleted.Reaction,
+		&deleted.Username,
+		&deleted.CreatedAt,
+		&deleted.UpdatedAt,
+		&deletedAt,
+	)
+	return deleted, deletedAt, err
 }
@@ -2,17 +2,25 @@ package pgdb
 
 import (
 	"API_for_SN_go/internal/model/pgmodel"
+	"API_for_SN_go/internal/repo/pagination"
 	"API_for_SN_go/internal/repo/pgerrs"
 	"API_for_SN_go/pkg/postgres"
 	"context"
 	"errors"
+	"github.com/Masterminds/squirrel"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	log "github.com/sirupsen/logrus"
+	"strings"
 )
 
 const userPrefixLog = "/pgdb/user"
 
+// likeEscaper escapes LIKE wildcards of user input
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+var userColumns = []string{"id", "username", "first_name", "last_name", "email", "password", "email_verified", "pending_email", "totp_secret", "totp_enabled", "totp_recovery_codes", "role", "suspended_at", "created_at", "updated_at"}
+
 type UserRepo struct {
 	*postgres.Postgres
 }
@@ -43,21 +51,13 @@ func (r *UserRepo) CreateUser(ctx context.Context, u pgmodel.User) error {
 
 func (r *UserRepo) GetUserByUsername(ctx context.Context, username string) (pgmodel.User, error) {
 	sql, args, _ := r.Builder.
-		Select("*").
+		Select(userColumns...).
 		From("\"user\"").
 		Where("username = ?", username).
 		ToSql()
 
 	var user pgmodel.User
-
-	err := r.Pool.QueryRow(ctx, sql, args...).Scan(
-		&user.Id,
-		&user.Username,
-		&user.FirstName,
-		&user.LastName,
-		&user.Email,
-		&user.Password,
-	)
+	err := scanUser(r.Pool.QueryRow(ctx, sql, args...), &user)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return pgmodel.User{}, pgerrs.ErrNotFound
@@ -68,10 +68,63 @@ func (r *UserRepo) GetUserByUsername(ctx context.Context, username string) (pgmo
 	return user, nil
 }
 
+// GetManyUsers returns users which username, name or email contains query, oldest first by default
+func (r *UserRepo) GetManyUsers(ctx context.Context, query string, p pagination.Pagination) ([]pgmodel.User, string, error) {
+	b := r.Builder.Select(userColumns...).From("\"user\"")
+	if query != "" {
+		like := "%" + likeEscaper.Replace(query) + "%"
+		b = b.Where("username ILIKE ? OR email ILIKE ? OR first_name ILIKE ? OR last_name ILIKE ?", like, like, like, like)
+	}
+	b, err := paginate(b, p, "id", pagination.SortOldest)
+	if err != nil {
+		return nil, "", err
+	}
+	sql, args, _ := b.ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		log.Errorf("%s/GetManyUsers error finding users: %s", userPrefixLog, err)
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var users []pgmodel.User
+	for rows.Next() {
+		var u pgmodel.User
+		if err = scanUser(rows, &u); err != nil {
+			log.Errorf("%s/GetManyUsers error scan user: %s", userPrefixLog, err)
+			continue
+		}
+		users = append(users, u)
+	}
+	users, next := nextPage(users, p, func(u pgmodel.User) int { return u.Id })
+	return users, next, nil
+}
+
+func (r *UserRepo) GetUserByEmail(ctx context.Context, email string) (pgmodel.User, error) {
+	sql, args, _ := r.Builder.
+		Select(userColumns...).
+		From("\"user\"").
+		Where("email = ?", email).
+		ToSql()
+
+	var user pgmodel.User
+	err := scanUser(r.Pool.QueryRow(ctx, sql, args...), &user)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgmodel.User{}, pgerrs.ErrNotFound
+		}
+		log.Errorf("%s/GetUserByEmail error finding user: %s", userPrefixLog, err)
+		return pgmodel.User{}, err
+	}
+	return user, nil
+}
+
 func (r *UserRepo) UpdateUsername(ctx context.Context, username, newUsername string) error {
 	sql, args, _ := r.Builder.
 		Update("\"user\"").
 		Set("username", newUsername).
+		Set("updated_at", squirrel.Expr("now()")).
 		Where("username = ?", username).
 		ToSql()
 	if _, err := r.Pool.Exec(ctx, sql, args...); err != nil {
@@ -89,6 +142,7 @@ func (r *UserRepo) UpdateFullName(ctx context.Context, username, firstName, last
 		Update("\"user\"").
 		Set("first_name = ?", firstName).
 		Set("last_name = ?", lastName).
+		Set("updated_at", squirrel.Expr("now()")).
 		Where("username = ?", username).
 		ToSql()
 	if _, err := r.Pool.Exec(ctx, sql, args...); err != nil {
@@ -101,6 +155,207 @@ func (r *UserRepo) UpdateFullName(ctx context.Context, username, firstName, last
 	return nil
 }
 
+func (r *UserRepo) UpdatePassword(ctx context.Context, username, password string) error {
+	sql, args, _ := r.Builder.
+		Update("\"user\"").
+		Set("password", password).
+		Set("updated_at", squirrel.Expr("now()")).
+		Where("username = ?", username).
+		ToSql()
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		log.Errorf("%s/UpdatePassword error exec stmt: %s", userPrefixLog, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgerrs.ErrNotFound
+	}
+	return nil
+}
+
+// SetEmailVerified marks email as verified only if it is still the user email
+func (r *UserRepo) SetEmailVerified(ctx context.Context, username, email string) error {
+	sql, args, _ := r.Builder.
+		Update("\"user\"").
+		Set("email_verified", true).
+		Set("updated_at", squirrel.Expr("now()")).
+		Where("username = ? AND email = ?", username, email).
+		ToSql()
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		log.Errorf("%s/SetEmailVerified error exec stmt: %s", userPrefixLog, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgerrs.ErrNotFound
+	}
+	return nil
+}
+
+func (r *UserRepo) SetPendingEmail(ctx context.Context, username, email string) error {
+	sql, args, _ := r.Builder.
+		Update("\"user\"").
+		Set("pending_email", email).
+		Set("updated_at", squirrel.Expr("now()")).
+		Where("username = ?", username).
+		ToSql()
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		log.Errorf("%s/SetPendingEmail error exec stmt: %s", userPrefixLog, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgerrs.ErrNotFound
+	}
+	return nil
+}
+
+// ConfirmPendingEmail replaces user email with pending one, if pending email is still the same
+func (r *UserRepo) ConfirmPendingEmail(ctx context.Context, username, email string) error {
+	sql, args, _ := r.Builder.
+		Update("\"user\"").
+		Set("email", squirrel.Expr("pending_email")).
+		Set("pending_email", nil).
+		Set("email_verified", true).
+		Set("updated_at", squirrel.Expr("now()")).
+		Where("username = ? AND pending_email = ?", username, email).
+		ToSql()
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if ok := errors.As(err, &pgErr); ok {
+			if pgErr.Code == "23505" {
+				return pgerrs.ErrAlreadyExists
+			}
+		}
+		log.Errorf("%s/ConfirmPendingEmail error exec stmt: %s", userPrefixLog, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgerrs.ErrNotFound
+	}
+	return nil
+}
+
+// SetTOTP stores not yet enabled 2fa secret and recovery codes, replacing previous ones
+func (r *UserRepo) SetTOTP(ctx context.Context, username, secret string, recoveryCodes []string) error {
+	sql, args, _ := r.Builder.
+		Update("\"user\"").
+		Set("totp_secret", secret).
+		Set("totp_enabled", false).
+		Set("totp_recovery_codes", recoveryCodes).
+		Set("updated_at", squirrel.Expr("now()")).
+		Where("username = ?", username).
+		ToSql()
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		log.Errorf("%s/SetTOTP error exec stmt: %s", userPrefixLog, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgerrs.ErrNotFound
+	}
+	return nil
+}
+
+func (r *UserRepo) UpdateRole(ctx context.Context, username, role string) error {
+	sql, args, _ := r.Builder.
+		Update("\"user\"").
+		Set("role", role).
+		Set("updated_at", squirrel.Expr("now()")).
+		Where("username = ?", username).
+		ToSql()
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		log.Errorf("%s/UpdateRole error exec stmt: %s", userPrefixLog, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgerrs.ErrNotFound
+	}
+	return nil
+}
+
+// SetSuspended suspends user or lifts suspension
+func (r *UserRepo) SetSuspended(ctx context.Context, username string, suspended bool) error {
+	var suspendedAt interface{}
+	if suspended {
+		suspendedAt = squirrel.Expr("now()")
+	}
+	sql, args, _ := r.Builder.
+		Update("\"user\"").
+		Set("suspended_at", suspendedAt).
+		Set("updated_at", squirrel.Expr("now()")).
+		Where("username = ?", username).
+		ToSql()
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		log.Errorf("%s/SetSuspended error exec stmt: %s", userPrefixLog, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgerrs.ErrNotFound
+	}
+	return nil
+}
+
+func (r *UserRepo) EnableTOTP(ctx context.Context, username string) error {
+	sql, args, _ := r.Builder.
+		Update("\"user\"").
+		Set("totp_enabled", true).
+		Set("updated_at", squirrel.Expr("now()")).
+		Where("username = ? AND totp_secret IS NOT NULL", username).
+		ToSql()
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		log.Errorf("%s/EnableTOTP error exec stmt: %s", userPrefixLog, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgerrs.ErrNotFound
+	}
+	return nil
+}
+
+func (r *UserRepo) DisableTOTP(ctx context.Context, username string) error {
+	sql, args, _ := r.Builder.
+		Update("\"user\"").
+		Set("totp_secret", nil).
+		Set("totp_enabled", false).
+		Set("totp_recovery_codes", squirrel.Expr("'{}'")).
+		Set("updated_at", squirrel.Expr("now()")).
+		Where("username = ?", username).
+		ToSql()
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		log.Errorf("%s/DisableTOTP error exec stmt: %s", userPrefixLog, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgerrs.ErrNotFound
+	}
+	return nil
+}
+
+// UseRecoveryCode removes recovery code hash, so each code works once. Returns pgerrs.ErrNotFound for unknown code
+func (r *UserRepo) UseRecoveryCode(ctx context.Context, username, codeHash string) error {
+	sql, args, _ := r.Builder.
+		Update("\"user\"").
+		Set("totp_recovery_codes", squirrel.Expr("array_remove(totp_recovery_codes, ?)", codeHash)).
+		Set("updated_at", squirrel.Expr("now()")).
+		Where("username = ? AND ? = ANY(totp_recovery_codes)", username, codeHash).
+		ToSql()
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		log.Errorf("%s/UseRecoveryCode error exec stmt: %s", userPrefixLog, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgerrs.ErrNotFound
+	}
+	return nil
+}
+
 func (r *UserRepo) DeleteUser(ctx context.Context, username string) error {
 	sql, args, _ := r.Builder.
 		Delete("\"user\"").
@@ -115,3 +370,7 @@ func (r *UserRepo) DeleteUser(ctx context.Context, username string) error {
 	}
 	return nil
 }
+
+func scanUser(row pgx.Row, u *pgmodel.User) error {
+	return row.Scan(&u.Id, &u.Username, &u.FirstName, &u.LastName, &u.Email, &u.Password, &u.EmailVerified, &u.PendingEmail, &u.TOTPSecret, &u.TOTPEnabled, &u.RecoveryCodes, &u.Role, &u.SuspendedAt, &u.CreatedAt, &u.UpdatedAt)
+}
@@ -2,37 +2,83 @@ package repo
 
 import (
 	"API_for_SN_go/internal/model/pgmodel"
+	"API_for_SN_go/internal/repo/pagination"
 	"API_for_SN_go/internal/repo/pgdb"
 	"API_for_SN_go/pkg/postgres"
 	"context"
+	"time"
 )
 
+// Pagination is a shared limit + cursor params of all list queries. Repository methods return cursor of the next page
+type Pagination = pagination.Pagination
+
 type User interface {
 	CreateUser(ctx context.Context, u pgmodel.User) error
 	GetUserByUsername(ctx context.Context, username string) (pgmodel.User, error)
+	GetUserByEmail(ctx context.Context, email string) (pgmodel.User, error)
+	GetManyUsers(ctx context.Context, query string, p Pagination) ([]pgmodel.User, string, error)
 	UpdateUsername(ctx context.Context, username, newUsername string) error
 	UpdateFullName(ctx context.Context, username, firstName, lastName string) error
+	UpdatePassword(ctx context.Context, username, password string) error
+	UpdateRole(ctx context.Context, username, role string) error
+	SetSuspended(ctx context.Context, username string, suspended bool) error
+	SetEmailVerified(ctx context.Context, username, email string) error
+	SetPendingEmail(ctx context.Context, username, email string) error
+	ConfirmPendingEmail(ctx context.Context, username, email string) error
+	SetTOTP(ctx context.Context, username, secret string, recoveryCodes []string) error
+	EnableTOTP(ctx context.Context, username string) error
+	DisableTOTP(ctx context.Context, username string) error
+	UseRecoveryCode(ctx context.Context, username, codeHash string) error
 	DeleteUser(ctx context.Context, username string) error
 }
 
 type Post interface {
 	CreatePost(ctx context.Context, p pgmodel.Post) error
 	GetPostById(ctx context.Context, postId string) (pgmodel.Post, error)
+	GetManyPosts(ctx context.Context, username string, p Pagination) ([]pgmodel.Post, string, error)
+	GetFeed(ctx context.Context, username string, p Pagination) ([]pgmodel.Post, string, error)
+	UpdatePost(ctx context.Context, username, postId, title, text string) error
+	DeletePost(ctx context.Context, username, postId string) (time.Time, error)
+	DeletePostById(ctx context.Context, postId string) (time.Time, error)
 }
 
 type Reaction interface {
-	CreateReaction(ctx context.Context, rn pgmodel.Reaction) error
+	UpsertReaction(ctx context.Context, rn pgmodel.Reaction) (pgmodel.Reaction, bool, error)
 	GetReactionById(ctx context.Context, reactionId string) (pgmodel.Reaction, error)
-	GetManyReactions(ctx context.Context, postId string) ([]pgmodel.Reaction, error)
-	DeleteReaction(ctx context.Context, reactionId string) error
+	GetManyReactions(ctx context.Context, postId string, p Pagination) ([]pgmodel.Reaction, string, error)
+	CountReactions(ctx context.Context, postId string) (map[string]int, error)
+	CountReactionsByPosts(ctx context.Context, postIds []string) (map[string]map[string]int, error)
+	GetUserReaction(ctx context.Context, postId, username string) (pgmodel.Reaction, error)
+	DeleteReaction(ctx context.Context, username, reactionId string) (pgmodel.Reaction, time.Time, error)
+	DeleteUserReaction(ctx context.Context, postId, username, reaction string) (pgmodel.Reaction, time.Time, error)
 }
 
 type Comment interface {
-	CreateComment(ctx context.Context, c pgmodel.Comment) error
+	CreateComment(ctx context.Context, c pgmodel.Comment) (pgmodel.Comment, error)
 	GetCommentById(ctx context.Context, commentId string) (pgmodel.Comment, error)
-	GetManyComments(ctx context.Context, filter, filterParams string) ([]pgmodel.Comment, error)
+	GetManyComments(ctx context.Context, filter, filterParams string, p Pagination) ([]pgmodel.Comment, string, error)
+	GetCommentTree(ctx context.Context, postId string, depth int, p Pagination) ([]pgmodel.Comment, string, error)
+	CountCommentsByPosts(ctx context.Context, postIds []string) (map[string]int, error)
 	UpdateComment(ctx context.Context, username, commentId, newComment string) error
-	DeleteComment(ctx context.Context, username, commentId string) error
+	DeleteComment(ctx context.Context, username, commentId string) ([]pgmodel.Comment, time.Time, error)
+	DeleteCommentById(ctx context.Context, commentId string) ([]pgmodel.Comment, time.Time, error)
+}
+
+type Follow interface {
+	CreateFollow(ctx context.Context, follower, followee string) error
+	DeleteFollow(ctx context.Context, follower, followee string) error
+	GetFollowers(ctx context.Context, username string, p Pagination) ([]pgmodel.Follow, string, error)
+	GetFollowing(ctx context.Context, username string, p Pagination) ([]pgmodel.Follow, string, error)
+	CountFollows(ctx context.Context, username string) (int, int, error)
+}
+
+type AccessToken interface {
+	CreateAccessToken(ctx context.Context, t pgmodel.AccessToken) error
+	GetAccessTokenByHash(ctx context.Context, tokenHash string) (pgmodel.AccessToken, error)
+	GetAccessTokens(ctx context.Context, username string) ([]pgmodel.AccessToken, error)
+	TouchAccessToken(ctx context.Context, tokenId string) error
+	DeleteAccessToken(ctx context.Context, username, tokenId string) error
+	DeleteUserAccessTokens(ctx context.Context, username string) error
 }
 
 type Repositories struct {
@@ -40,13 +86,17 @@ type Repositories struct {
 	Post
 	Reaction
 	Comment
+	Follow
+	AccessToken
 }
 
 func NewRepositories(pg *postgres.Postgres) *Repositories {
 	return &Repositories{
-		User:     pgdb.NewUserRepo(pg),
-		Post:     pgdb.NewPostRepo(pg),
-		Reaction: pgdb.NewReactionRepo(pg),
-		Comment:  pgdb.NewCommentRepo(pg),
+		User:        pgdb.NewUserRepo(pg),
+		Post:        pgdb.NewPostRepo(pg),
+		Reaction:    pgdb.NewReactionRepo(pg),
+		Comment:     pgdb.NewCommentRepo(pg),
+		Follow:      pgdb.NewFollowRepo(pg),
+		AccessToken: pgdb.NewAccessTokenRepo(pg),
 	}
 }
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"API_for_SN_go/internal/model/pgmodel"
+	"API_for_SN_go/internal/repo/pgerrs"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+// AccessTokenPrefix distinguishes personal access tokens from JWT in Authorization header
+const AccessTokenPrefix = "snpat_"
+
+// Scope of personal access token is "<resource>:<access>", e.g. posts:write
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+var scopeResources = map[string]bool{
+	"posts":      true,
+	"comments":   true,
+	"reactions":  true,
+	"users":      true,
+	"feed":       true,
+	"moderation": true,
+	"admin":      true,
+}
+
+// Scope returns scope of resource access
+func Scope(resource, access string) string {
+	return resource + ":" + access
+}
+
+func IsValidScope(scope string) bool {
+	resource, access, ok := strings.Cut(scope, ":")
+	return ok && scopeResources[resource] && (access == ScopeRead || access == ScopeWrite)
+}
+
+func (s *authService) CreateAccessToken(ctx context.Context, input AccessTokenCreateInput) (string, pgmodel.AccessToken, error) {
+	if len(input.Scopes) == 0 {
+		return "", pgmodel.AccessToken{}, ErrInvalidScope
+	}
+	for _, scope := range input.Scopes {
+		if !IsValidScope(scope) {
+			return "", pgmodel.AccessToken{}, ErrInvalidScope
+		}
+	}
+	random, err := newRandomToken()
+	if err != nil {
+		log.Errorf("%s/CreateAccessToken error generate token: %s", authServicePrefixLog, err)
+		return "", pgmodel.AccessToken{}, ErrCannotCreateToken
+	}
+	token := AccessTokenPrefix + random
+	t := pgmodel.AccessToken{
+		TokenId:   uuid.NewString(),
+		Username:  input.Username,
+		Name:      input.Name,
+		TokenHash: hashToken(token),
+		Scopes:    input.Scopes,
+		CreatedAt: time.Now(),
+	}
+	if input.TTL > 0 {
+		expiresAt := time.Now().Add(input.TTL)
+		t.ExpiresAt = &expiresAt
+	}
+	if err = s.accessTokenRepo.CreateAccessToken(ctx, t); err != nil {
+		if errors.Is(err, pgerrs.ErrForeignKey) {
+			return "", pgmodel.AccessToken{}, ErrUserNotFound
+		}
+		log.Errorf("%s/CreateAccessToken error save token: %s", authServicePrefixLog, err)
+		return "", pgmodel.AccessToken{}, ErrCannotCreateToken
+	}
+	return token, t, nil
+}
+
+func (s *authService) GetAccessTokens(ctx context.Context, username string) ([]pgmodel.AccessToken, error) {
+	tokens, err := s.accessTokenRepo.GetAccessTokens(ctx, username)
+	if err != nil {
+		log.Errorf("%s/GetAccessTokens error finding tokens: %s", authServicePrefixLog, err)
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (s *authService) RevokeAccessToken(ctx context.Context, input AccessTokenRevokeInput) error {
+	if err := s.accessTokenRepo.DeleteAccessToken(ctx, input.Username, input.TokenId); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrAccessTokenNotFound
+		}
+		log.Errorf("%s/RevokeAccessToken error delete token: %s", authServicePrefixLog, err)
+		return ErrCannotRevokeToken
+	}
+	return nil
+}
+
+// validateAccessToken returns claims of personal access token. Role is taken from the user, so it is always actual
+func (s *authService) validateAccessToken(ctx context.Context, token string) (*TokenClaims, error) {
+	t, err := s.accessTokenRepo.GetAccessTokenByHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return nil, ErrInvalidToken
+		}
+		log.Errorf("%s/validateAccessToken error find token: %s", authServicePrefixLog, err)
+		return nil, ErrCannotValidateToken
+	}
+	if t.ExpiresAt != nil && time.Now().After(*t.ExpiresAt) {
+		return nil, ErrExpiredToken
+	}
+	user, err := s.userRepo.GetUserByUsername(ctx, t.Username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return nil, ErrInvalidToken
+		}
+		log.Errorf("%s/validateAccessToken error find user: %s", authServicePrefixLog, err)
+		return nil, ErrCannotValidateToken
+	}
+	if user.SuspendedAt != nil {
+		return nil, ErrUserSuspended
+	}
+	// время последнего использования, как и у сессий, обновляется не чаще раза в минуту
+	if t.LastUsedAt == nil || time.Since(*t.LastUsedAt) >= lastSeenUpdateInterval {
+		if err = s.accessTokenRepo.TouchAccessToken(ctx, t.TokenId); err != nil {
+			log.Errorf("%s/validateAccessToken error update token last used: %s", authServicePrefixLog, err)
+		}
+	}
+	claims := &TokenClaims{
+		Username:      user.Username,
+		Role:          user.Role,
+		AccessTokenId: t.TokenId,
+		Scopes:        t.Scopes,
+	}
+	if t.ExpiresAt != nil {
+		claims.ExpiresAt = t.ExpiresAt.Unix()
+	}
+	return claims, nil
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"API_for_SN_go/internal/model/pgmodel"
+	"API_for_SN_go/internal/repo"
+	"API_for_SN_go/internal/repo/pagination"
+	"API_for_SN_go/internal/repo/pgerrs"
+	"context"
+	"errors"
+	goredis "github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	adminServicePrefixLog = "/service/admin"
+
+	// suspended:<username> -> user is suspended. Lets ValidateToken reject user without query to postgres
+	suspendedKeyPrefix = "suspended:"
+)
+
+type adminService struct {
+	userRepo repo.User
+	auth     *authService
+}
+
+func newAdminService(userRepo repo.User, auth *authService) *adminService {
+	return &adminService{userRepo: userRepo, auth: auth}
+}
+
+func (s *adminService) GetUsers(ctx context.Context, query string, p repo.Pagination) ([]pgmodel.User, string, error) {
+	users, next, err := s.userRepo.GetManyUsers(ctx, query, p)
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			return nil, "", ErrInvalidCursor
+		}
+		log.Errorf("%s/GetUsers error finding users: %s", adminServicePrefixLog, err)
+		return nil, "", err
+	}
+	return users, next, nil
+}
+
+func (s *adminService) GetUser(ctx context.Context, username string) (pgmodel.User, error) {
+	user, err := s.userRepo.GetUserByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return pgmodel.User{}, ErrUserNotFound
+		}
+		log.Errorf("%s/GetUser error finding user: %s", adminServicePrefixLog, err)
+		return pgmodel.User{}, err
+	}
+	return user, nil
+}
+
+func (s *adminService) SuspendUser(ctx context.Context, input AdminUserInput) error {
+	if input.Admin == input.Username {
+		return ErrCannotManageSelf
+	}
+	if err := s.userRepo.SetSuspended(ctx, input.Username, true); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		log.Errorf("%s/SuspendUser error suspend user: %s", adminServicePrefixLog, err)
+		return ErrCannotUpdateUser
+	}
+	if err := s.auth.setSuspended(ctx, input.Username, true); err != nil {
+		log.Errorf("%s/SuspendUser error save suspension: %s", adminServicePrefixLog, err)
+		// без метки токены пользователя продолжат работать, поэтому блокировка в базе отменяется
+		if err = s.userRepo.SetSuspended(ctx, input.Username, false); err != nil {
+			log.Errorf("%s/SuspendUser error rollback suspension: %s", adminServicePrefixLog, err)
+		}
+		return ErrCannotUpdateUser
+	}
+	return s.auth.RevokeAllSessions(ctx, input.Username)
+}
+
+func (s *adminService) UnsuspendUser(ctx context.Context, input AdminUserInput) error {
+	if err := s.userRepo.SetSuspended(ctx, input.Username, false); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		log.Errorf("%s/UnsuspendUser error unsuspend user: %s", adminServicePrefixLog, err)
+		return ErrCannotUpdateUser
+	}
+	if err := s.auth.setSuspended(ctx, input.Username, false); err != nil {
+		log.Errorf("%s/UnsuspendUser error delete suspension: %s", adminServicePrefixLog, err)
+		return ErrCannotUpdateUser
+	}
+	return nil
+}
+
+func (s *adminService) SignOutUser(ctx context.Context, input AdminUserInput) error {
+	if _, err := s.GetUser(ctx, input.Username); err != nil {
+		return err
+	}
+	if err := s.auth.RevokeAllSessions(ctx, input.Username); err != nil {
+		return err
+	}
+	if err := s.auth.accessTokenRepo.DeleteUserAccessTokens(ctx, input.Username); err != nil {
+		log.Errorf("%s/SignOutUser error delete access tokens: %s", adminServicePrefixLog, err)
+		return ErrCannotRevokeToken
+	}
+	return nil
+}
+
+func (s *adminService) DeleteUser(ctx context.Context, input AdminUserInput) error {
+	if input.Admin == input.Username {
+		return ErrCannotManageSelf
+	}
+	if _, err := s.GetUser(ctx, input.Username); err != nil {
+		return err
+	}
+	if err := s.auth.accessTokenRepo.DeleteUserAccessTokens(ctx, input.Username); err != nil {
+		log.Errorf("%s/DeleteUser error delete access tokens: %s", adminServicePrefixLog, err)
+		return ErrCannotDeleteUser
+	}
+	if err := s.userRepo.DeleteUser(ctx, input.Username); err != nil {
+		log.Errorf("%s/DeleteUser error delete user: %s", adminServicePrefixLog, err)
+		return ErrCannotDeleteUser
+	}
+	// сессии удаленного пользователя не должны пережить его, как и метка блокировки
+	if err := s.auth.setSuspended(ctx, input.Username, false); err != nil {
+		log.Errorf("%s/DeleteUser error delete suspension: %s", adminServicePrefixLog, err)
+	}
+	return s.auth.RevokeAllSessions(ctx, input.Username)
+}
+
+// setSuspended saves or deletes suspension mark checked by ValidateToken
+func (s *authService) setSuspended(ctx context.Context, username string, suspended bool) error {
+	if suspended {
+		return s.redis.Pool.Set(ctx, suspendedKeyPrefix+username, 1, 0).Err()
+	}
+	return s.redis.Pool.Del(ctx, suspendedKeyPrefix+username).Err()
+}
+
+// isSuspended checks suspension mark saved by SuspendUser
+func (s *authService) isSuspended(ctx context.Context, username string) (bool, error) {
+	err := s.redis.Pool.Get(ctx, suspendedKeyPrefix+username).Err()
+	if err != nil {
+		if errors.Is(err, goredis.Nil) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -5,79 +5,229 @@ import (
 	"API_for_SN_go/internal/repo"
 	"API_for_SN_go/internal/repo/pgerrs"
 	"API_for_SN_go/pkg/hasher"
+	"API_for_SN_go/pkg/jwk"
+	"API_for_SN_go/pkg/mailer"
 	"API_for_SN_go/pkg/redis"
 	"context"
 	"errors"
+	"fmt"
 	"github.com/golang-jwt/jwt"
+	goredis "github.com/redis/go-redis/v9"
 	log "github.com/sirupsen/logrus"
+	"strings"
 	"time"
 )
 
 const (
-	defaultKeyPrefix     = "jwt:"
 	authServicePrefixLog = "/service/auth"
 )
 
 type TokenClaims struct {
 	jwt.StandardClaims
-	Username string `json:"username"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	SessionId string `json:"sid"`
+	// AccessTokenId and Scopes are set instead of session for personal access token
+	AccessTokenId string   `json:"-"`
+	Scopes        []string `json:"-"`
+}
+
+type authConfig struct {
+	signKey string
+	// signAlgorithm of access tokens: HS256 with signKey, RS256 or EdDSA with rotated keys
+	signAlgorithm string
+	// keyEncryptionKey is AES-256 key which encrypts private signing keys in redis
+	keyEncryptionKey []byte
+	keyRotation      time.Duration
+	tokenTTL         time.Duration
+	refreshTokenTTL  time.Duration
+	resetTokenTTL    time.Duration
+	emailTokenTTL    time.Duration
+	// publicURL is used to build links in emails
+	publicURL    string
+	totpIssuer   string
+	challengeTTL time.Duration
+	lockout      LockoutConfig
 }
 
 type authService struct {
-	userRepo repo.User
-	hasher   hasher.PasswordHasher
-	redis    *redis.Redis
-	signKey  string
-	tokenTTL time.Duration
+	authConfig
+	// keys sign access tokens with RS256 or EdDSA, nil for HS256 with signKey
+	keys            *keyring
+	userRepo        repo.User
+	accessTokenRepo repo.AccessToken
+	hasher          hasher.PasswordHasher
+	redis           *redis.Redis
+	mailer          mailer.Mailer
 }
 
-func newAuthService(userRepo repo.User, hasher hasher.PasswordHasher, redis *redis.Redis, singKey string, tokenTTL time.Duration) *authService {
+func newAuthService(userRepo repo.User, accessTokenRepo repo.AccessToken, hasher hasher.PasswordHasher, redis *redis.Redis, mailer mailer.Mailer, cfg authConfig) *authService {
+	var keys *keyring
+	if cfg.signAlgorithm == jwk.RS256 || cfg.signAlgorithm == jwk.EdDSA {
+		// ключ проверяет токены еще время жизни access токена после окончания подписи
+		keys = newKeyring(redis, cfg.signAlgorithm, cfg.keyEncryptionKey, cfg.keyRotation, cfg.tokenTTL)
+	}
 	return &authService{
-		userRepo: userRepo,
-		hasher:   hasher,
-		redis:    redis,
-		signKey:  singKey,
-		tokenTTL: tokenTTL,
+		keys:            keys,
+		authConfig:      cfg,
+		userRepo:        userRepo,
+		accessTokenRepo: accessTokenRepo,
+		hasher:          hasher,
+		redis:           redis,
+		mailer:          mailer,
 	}
 }
-func (s *authService) CreateToken(ctx context.Context, input UserAuthInput) (string, error) {
-	ok, err := s.verifyPassword(ctx, input.Username, input.Password)
-	if !ok || err != nil {
-		return "", err
+
+// CreateToken starts new session for user device and returns short-lived access token with refresh token.
+// Sessions of other devices stay active
+func (s *authService) CreateToken(ctx context.Context, input UserAuthInput) (Tokens, error) {
+	user, err := s.verifyPassword(ctx, input.Username, input.Password, input.IP)
+	if err != nil {
+		return Tokens{}, err
 	}
-	return s.generateToken(ctx, input.Username)
+	if user.SuspendedAt != nil {
+		return Tokens{}, ErrUserSuspended
+	}
+	if user.TOTPEnabled {
+		// счетчик ошибок сбрасывается только после второго фактора, иначе код можно подбирать бесконечно
+		challenge, err := s.newChallengeToken(user.Username)
+		if err != nil {
+			return Tokens{}, err
+		}
+		return Tokens{ChallengeToken: challenge}, nil
+	}
+	s.resetFailures(ctx, input.Username)
+	return s.issueTokens(ctx, newSession(input.Username, input.Device, input.IP), user.Role)
 }
 
-func (s *authService) RefreshToken(ctx context.Context, token string) (string, error) {
-	claims, err := s.parseToken(token)
+// RefreshToken exchanges refresh token for new pair of tokens. Each refresh token can be used only once:
+// reuse of already exchanged token revokes the whole session
+func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (Tokens, error) {
+	hash := hashToken(refreshToken)
+	// токен забирается атомарно, поэтому при параллельных запросах обменять его сможет только один
+	sessionId, err := s.redis.Pool.GetDel(ctx, refreshKeyPrefix+hash).Result()
 	if err != nil {
-		return "", err
+		if errors.Is(err, goredis.Nil) {
+			return Tokens{}, s.detectRefreshReuse(ctx, hash)
+		}
+		log.Errorf("%s/RefreshToken error find refresh token: %s", authServicePrefixLog, err)
+		return Tokens{}, ErrCannotCreateToken
+	}
+	if err = s.redis.Pool.Set(ctx, usedRefreshKeyPrefix+hash, sessionId, s.refreshTokenTTL).Err(); err != nil {
+		log.Errorf("%s/RefreshToken error mark refresh token used: %s", authServicePrefixLog, err)
+	}
+	session, err := s.getSession(ctx, sessionId)
+	if err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			return Tokens{}, ErrExpiredToken
+		}
+		log.Errorf("%s/RefreshToken error find session: %s", authServicePrefixLog, err)
+		return Tokens{}, ErrCannotCreateToken
+	}
+	if session.RefreshHash != hash {
+		return Tokens{}, s.revokeReusedSession(ctx, session)
+	}
+	// роль берется из базы, чтобы ее изменение применялось при обновлении токена
+	user, err := s.userRepo.GetUserByUsername(ctx, session.Username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return Tokens{}, ErrInvalidToken
+		}
+		log.Errorf("%s/RefreshToken error find user: %s", authServicePrefixLog, err)
+		return Tokens{}, ErrCannotCreateToken
 	}
-	return s.generateToken(ctx, claims.Username)
+	if user.SuspendedAt != nil {
+		return Tokens{}, ErrUserSuspended
+	}
+	session.LastSeenAt = time.Now()
+	return s.issueTokens(ctx, session, user.Role)
 }
 
-func (s *authService) ValidateToken(ctx context.Context, token string) (string, error) {
-	claims, err := s.parseToken(token)
+// detectRefreshReuse is called for unknown refresh token. If the token was already exchanged its session is revoked
+func (s *authService) detectRefreshReuse(ctx context.Context, hash string) error {
+	sessionId, err := s.redis.Pool.Get(ctx, usedRefreshKeyPrefix+hash).Result()
 	if err != nil {
-		return "", err
+		if !errors.Is(err, goredis.Nil) {
+			log.Errorf("%s/detectRefreshReuse error find used refresh token: %s", authServicePrefixLog, err)
+		}
+		return ErrInvalidToken
 	}
-	redisToken, err := s.redis.Pool.Get(ctx, defaultKeyPrefix+claims.Username).Result()
-	if err != nil || redisToken != token {
-		if err != nil {
-			log.Errorf("%s/ValidateToken error find token from redis: %s", authServicePrefixLog, err)
+	session, err := s.getSession(ctx, sessionId)
+	if err != nil {
+		if !errors.Is(err, ErrSessionNotFound) {
+			log.Errorf("%s/detectRefreshReuse error find session: %s", authServicePrefixLog, err)
 		}
-		return "", ErrExpiredToken
+		return ErrInvalidToken
+	}
+	return s.revokeReusedSession(ctx, session)
+}
+
+// revokeReusedSession revokes session whose refresh token is used second time - probably the token is stolen
+func (s *authService) revokeReusedSession(ctx context.Context, session Session) error {
+	if err := s.deleteSessions(ctx, session.Username, session.Id); err != nil {
+		log.Errorf("%s/revokeReusedSession error revoke session: %s", authServicePrefixLog, err)
 	}
-	return claims.Username, nil
+	return ErrRefreshTokenReused
+}
+
+// ValidateToken checks token signature, that its session is not revoked and user is not suspended.
+// Personal access tokens are accepted too
+func (s *authService) ValidateToken(ctx context.Context, token string) (*TokenClaims, error) {
+	if strings.HasPrefix(token, AccessTokenPrefix) {
+		return s.validateAccessToken(ctx, token)
+	}
+	claims, err := s.parseToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	suspended, err := s.isSuspended(ctx, claims.Username)
+	if err != nil {
+		log.Errorf("%s/ValidateToken error check suspension: %s", authServicePrefixLog, err)
+		return nil, ErrCannotValidateToken
+	}
+	if suspended {
+		return nil, ErrUserSuspended
+	}
+	session, err := s.getSession(ctx, claims.SessionId)
+	if err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			return nil, ErrExpiredToken
+		}
+		log.Errorf("%s/ValidateToken error find session from redis: %s", authServicePrefixLog, err)
+		return nil, ErrCannotValidateToken
+	}
+	if session.Username != claims.Username {
+		return nil, ErrExpiredToken
+	}
+	s.touchSession(ctx, session)
+	return claims, nil
+}
+
+// JWKS returns public keys of access tokens. Set is empty for HS256
+func (s *authService) JWKS(ctx context.Context) (jwk.Set, error) {
+	if s.keys == nil {
+		return jwk.Set{Keys: []jwk.Key{}}, nil
+	}
+	set, err := s.keys.publicKeys(ctx)
+	if err != nil {
+		log.Errorf("%s/JWKS error find keys: %s", authServicePrefixLog, err)
+		return jwk.Set{}, err
+	}
+	return set, nil
 }
 
 func (s *authService) CreateUser(ctx context.Context, input UserCreateInput) error {
-	err := s.userRepo.CreateUser(ctx, pgmodel.User{
+	hashedPassword, err := s.hasher.Hash(input.Password)
+	if err != nil {
+		log.Errorf("%s/CreateUser error hash password: %s", authServicePrefixLog, err)
+		return ErrCannotCreateUser
+	}
+	err = s.userRepo.CreateUser(ctx, pgmodel.User{
 		Username:  input.Username,
 		FirstName: input.FirstName,
 		LastName:  input.LastName,
 		Email:     input.Email,
-		Password:  s.hasher.Hash(input.Password),
+		Password:  hashedPassword,
 	})
 	if err != nil {
 		if errors.Is(err, pgerrs.ErrAlreadyExists) {
@@ -86,79 +236,155 @@ func (s *authService) CreateUser(ctx context.Context, input UserCreateInput) err
 		log.Errorf("%s/CreateUser error create user: %s", authServicePrefixLog, err)
 		return ErrCannotCreateUser
 	}
+	// регистрация не зависит от доставки письма, его можно запросить повторно
+	if err = s.sendEmailConfirmation(ctx, input.Username, input.Email, false); err != nil {
+		log.Errorf("%s/CreateUser error send email confirmation: %s", authServicePrefixLog, err)
+	}
 	return nil
 }
 
 func (s *authService) DeleteUser(ctx context.Context, input UserDeleteInput) error {
-	ok, err := s.verifyPassword(ctx, input.Username, input.Password)
-	if !ok || err != nil {
+	_, err := s.verifyPassword(ctx, input.Username, input.Password, input.IP)
+	if err != nil {
 		return err
 	}
+	if err = s.accessTokenRepo.DeleteUserAccessTokens(ctx, input.Username); err != nil {
+		log.Errorf("%s/DeleteUser error delete access tokens: %s", authServicePrefixLog, err)
+		return ErrCannotDeleteUser
+	}
 	if err = s.userRepo.DeleteUser(ctx, input.Username); err != nil {
 		log.Errorf("%s/DeleteUser error delete user: %s", authServicePrefixLog, err)
 		return ErrCannotDeleteUser
 	}
-	if err = s.redis.Pool.Del(ctx, defaultKeyPrefix+input.Username).Err(); err != nil {
-		log.Errorf("%s/DeleteUser error delete user token from redis: %s", authServicePrefixLog, err)
+	// метка блокировки не должна достаться новому пользователю с тем же username
+	if err = s.setSuspended(ctx, input.Username, false); err != nil {
+		log.Errorf("%s/DeleteUser error delete suspension: %s", authServicePrefixLog, err)
+	}
+	if err = s.RevokeAllSessions(ctx, input.Username); err != nil {
+		log.Errorf("%s/DeleteUser error delete user sessions: %s", authServicePrefixLog, err)
 		return ErrCannotDeleteUser
 	}
 	return nil
 }
 
 func (s *authService) UpdateUsername(ctx context.Context, input UpdateUsernameInput) error {
-	ok, err := s.verifyPassword(ctx, input.Username, input.Password)
-	if !ok || err != nil {
+	user, err := s.verifyPassword(ctx, input.Username, input.Password, input.IP)
+	if err != nil {
 		return err
 	}
 	if err = s.userRepo.UpdateUsername(ctx, input.Username, input.NewUsername); err != nil {
 		log.Errorf("%s/UpdateUsername error update username: %s", authServicePrefixLog, err)
 		return ErrCannotUpdateUser
 	}
-	if err = s.redis.Pool.Del(ctx, defaultKeyPrefix+input.Username).Err(); err != nil {
-		log.Errorf("%s/UpdateUsername error delete user token from redis: %s", authServicePrefixLog, err)
+	// метка блокировки переходит на новый username
+	if user.SuspendedAt != nil {
+		if err = s.setSuspended(ctx, input.NewUsername, true); err != nil {
+			log.Errorf("%s/UpdateUsername error save suspension: %s", authServicePrefixLog, err)
+			return ErrCannotUpdateUser
+		}
+	}
+	if err = s.setSuspended(ctx, input.Username, false); err != nil {
+		log.Errorf("%s/UpdateUsername error delete suspension: %s", authServicePrefixLog, err)
+	}
+	// токены содержат старый username, поэтому все сессии завершаются
+	if err = s.RevokeAllSessions(ctx, input.Username); err != nil {
+		log.Errorf("%s/UpdateUsername error delete user sessions: %s", authServicePrefixLog, err)
 		return ErrCannotUpdateUser
 	}
 	return nil
 }
 
-func (s *authService) verifyPassword(ctx context.Context, username, password string) (bool, error) {
+// verifyPassword returns user if password is correct. Every password check is limited by sign-in lockout,
+// so no endpoint can be used to guess password
+func (s *authService) verifyPassword(ctx context.Context, username, password, ip string) (pgmodel.User, error) {
+	if err := s.checkLockout(ctx, username, ip); err != nil {
+		return pgmodel.User{}, err
+	}
 	user, err := s.userRepo.GetUserByUsername(ctx, username)
 	if err != nil {
 		if errors.Is(err, pgerrs.ErrNotFound) {
-			return false, ErrUserNotFound
+			s.registerFailure(ctx, username, ip)
+			return pgmodel.User{}, ErrUserNotFound
 		}
 		log.Errorf("%s/verifyPassword error verifying user password: %s", authServicePrefixLog, err)
-		return false, ErrIncorrectPassword
+		return pgmodel.User{}, ErrIncorrectPassword
 	}
 	if !s.hasher.Verify(password, user.Password) {
-		return false, ErrIncorrectPassword
+		s.registerFailure(ctx, username, ip)
+		return pgmodel.User{}, ErrIncorrectPassword
 	}
-	return true, nil
+	// пароль верный, заодно переводим старый хэш на текущий алгоритм
+	if s.hasher.NeedsRehash(user.Password) {
+		s.rehashPassword(ctx, username, password)
+	}
+	return user, nil
 }
 
-func (s *authService) generateToken(ctx context.Context, username string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &TokenClaims{
+// rehashPassword replaces outdated password hash. Errors are only logged, sign-in works with old hash too
+func (s *authService) rehashPassword(ctx context.Context, username, password string) {
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		log.Errorf("%s/rehashPassword error hash password: %s", authServicePrefixLog, err)
+		return
+	}
+	if err = s.userRepo.UpdatePassword(ctx, username, hashedPassword); err != nil {
+		log.Errorf("%s/rehashPassword error update password: %s", authServicePrefixLog, err)
+	}
+}
+
+func (s *authService) generateToken(ctx context.Context, username, role, sessionId string) (string, error) {
+	claims := &TokenClaims{
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: time.Now().Add(s.tokenTTL).Unix(),
 			IssuedAt:  time.Now().Unix(),
 		},
-		Username: username,
-	})
-	signedToken, err := token.SignedString([]byte(s.signKey))
+		Username:  username,
+		Role:      role,
+		SessionId: sessionId,
+	}
+	if s.keys == nil {
+		signedToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.signKey))
+		if err != nil {
+			log.Errorf("%s/generateToken error sign claims: %s", authServicePrefixLog, err)
+			return "", ErrCannotCreateToken
+		}
+		return signedToken, nil
+	}
+	key, err := s.keys.signingKey(ctx)
 	if err != nil {
-		log.Errorf("%s/generateToken error sign claims: %s", authServicePrefixLog, err)
+		log.Errorf("%s/generateToken error find signing key: %s", authServicePrefixLog, err)
 		return "", ErrCannotCreateToken
 	}
-	if err = s.redis.Pool.Set(ctx, defaultKeyPrefix+username, signedToken, s.tokenTTL).Err(); err != nil {
-		log.Errorf("%s/generateToken error save token to redis: %s", authServicePrefixLog, err)
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	signedToken, err := token.SignedString(key.private)
+	if err != nil {
+		log.Errorf("%s/generateToken error sign claims: %s", authServicePrefixLog, err)
 		return "", ErrCannotCreateToken
 	}
 	return signedToken, nil
 }
 
-func (s *authService) parseToken(tokenString string) (*TokenClaims, error) {
+// parseToken verifies access token. HS256 tokens are accepted only without asymmetric keys: after switching
+// algorithm clients get new access tokens with refresh tokens, and the shared secret cannot sign tokens anymore
+func (s *authService) parseToken(ctx context.Context, tokenString string) (*TokenClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return []byte(s.signKey), nil
+		if s.keys == nil {
+			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method %s", t.Header["alg"])
+			}
+			return []byte(s.signKey), nil
+		}
+		kid, _ := t.Header["kid"].(string)
+		key, err := s.keys.verificationKey(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		// алгоритм токена должен совпадать с алгоритмом ключа
+		if key.method.Alg() != t.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s for key %s", t.Header["alg"], kid)
+		}
+		return key.private.Public(), nil
 	})
 	if err != nil {
 		log.Errorf("%s/parseToken error parse token: %s", authServicePrefixLog, err)
@@ -3,30 +3,50 @@ package service
 import (
 	"API_for_SN_go/internal/model/pgmodel"
 	"API_for_SN_go/internal/repo"
+	"API_for_SN_go/internal/repo/pagination"
 	"API_for_SN_go/internal/repo/pgerrs"
 	"context"
 	"errors"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
+	"time"
 )
 
 const commentServicePrefixLog = "/service/comment"
 
 type commentService struct {
 	commentRepo repo.Comment
+	events      *eventBus
 }
 
-func newCommentService(commentRepo repo.Comment) *commentService {
-	return &commentService{commentRepo: commentRepo}
+func newCommentService(commentRepo repo.Comment, events *eventBus) *commentService {
+	return &commentService{commentRepo: commentRepo, events: events}
 }
 
 func (s *commentService) CreateComment(ctx context.Context, input CommentCreateInput) (string, error) {
-	commentId := uuid.NewString()
-	err := s.commentRepo.CreateComment(ctx, pgmodel.Comment{
+	var parentId *string
+	if input.ParentId != "" {
+		parent, err := s.commentRepo.GetCommentById(ctx, input.ParentId)
+		if err != nil {
+			if errors.Is(err, pgerrs.ErrNotFound) {
+				return "", ErrParentCommentNotFound
+			}
+			log.Errorf("%s/CreateComment error find parent comment: %s", commentServicePrefixLog, err)
+			return "", ErrCannotCreateComment
+		}
+		// отвечать можно только на комментарии того же поста
+		if parent.PostId != input.PostId {
+			return "", ErrParentCommentNotFound
+		}
+		parentId = &input.ParentId
+	}
+
+	comment, err := s.commentRepo.CreateComment(ctx, pgmodel.Comment{
 		Username:  input.Username,
 		PostId:    input.PostId,
-		CommentId: commentId,
+		CommentId: uuid.NewString(),
 		Comment:   input.Comment,
+		ParentId:  parentId,
 	})
 	if err != nil {
 		if errors.Is(err, pgerrs.ErrAlreadyExists) {
@@ -38,7 +58,8 @@ func (s *commentService) CreateComment(ctx context.Context, input CommentCreateI
 		log.Errorf("%s/CreateComment error create comment: %s", commentServicePrefixLog, err)
 		return "", ErrCannotCreateComment
 	}
-	return commentId, nil
+	s.events.publish(ctx, PostEvent{Type: EventCommentCreated, PostId: comment.PostId, Comment: &comment, CreatedAt: comment.CreatedAt})
+	return comment.CommentId, nil
 }
 
 func (s *commentService) GetCommentById(ctx context.Context, commentId string) (pgmodel.Comment, error) {
@@ -52,20 +73,48 @@ func (s *commentService) GetCommentById(ctx context.Context, commentId string) (
 	return comment, nil
 }
 
-func (s *commentService) GetManyComments(ctx context.Context, filter, filterParams string) (map[string]string, error) {
-	comments, err := s.commentRepo.GetManyComments(ctx, filter, filterParams)
+func (s *commentService) GetManyComments(ctx context.Context, filter, filterParams string, p repo.Pagination) ([]pgmodel.Comment, string, error) {
+	comments, next, err := s.commentRepo.GetManyComments(ctx, filter, filterParams, p)
 	if err != nil {
-		if errors.Is(err, pgerrs.ErrNotFound) {
-			return nil, ErrCommentNotFound
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			return nil, "", ErrInvalidCursor
 		}
 		log.Errorf("%s/GetManyComments error finding comments: %s", commentServicePrefixLog, err)
-		return nil, err
+		return nil, "", err
+	}
+	return comments, next, nil
+}
+
+func (s *commentService) GetCommentTree(ctx context.Context, postId string, depth int, p repo.Pagination) ([]CommentNode, string, error) {
+	comments, next, err := s.commentRepo.GetCommentTree(ctx, postId, depth, p)
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			return nil, "", ErrInvalidCursor
+		}
+		log.Errorf("%s/GetCommentTree error finding comments tree: %s", commentServicePrefixLog, err)
+		return nil, "", err
 	}
-	res := make(map[string]string)
+	var roots []pgmodel.Comment
+	replies := make(map[string][]pgmodel.Comment)
 	for _, comment := range comments {
-		res[comment.CommentId] = comment.Comment
+		if comment.ParentId == nil {
+			roots = append(roots, comment)
+			continue
+		}
+		replies[*comment.ParentId] = append(replies[*comment.ParentId], comment)
+	}
+	return buildCommentTree(roots, replies), next, nil
+}
+
+func buildCommentTree(comments []pgmodel.Comment, replies map[string][]pgmodel.Comment) []CommentNode {
+	nodes := make([]CommentNode, 0, len(comments))
+	for _, comment := range comments {
+		nodes = append(nodes, CommentNode{
+			Comment: comment,
+			Replies: buildCommentTree(replies[comment.CommentId], replies),
+		})
 	}
-	return res, nil
+	return nodes
 }
 
 func (s *commentService) UpdateComment(ctx context.Context, input CommentUpdateInput) error {
@@ -76,16 +125,35 @@ func (s *commentService) UpdateComment(ctx context.Context, input CommentUpdateI
 		}
 		return ErrCannotCreateComment
 	}
+	comment, err := s.commentRepo.GetCommentById(ctx, input.CommentId)
+	if err != nil {
+		log.Errorf("%s/UpdateComment error find updated comment: %s", commentServicePrefixLog, err)
+		return nil
+	}
+	s.events.publish(ctx, PostEvent{Type: EventCommentUpdated, PostId: comment.PostId, Comment: &comment, CreatedAt: comment.UpdatedAt})
 	return nil
 }
 
 func (s *commentService) DeleteComment(ctx context.Context, input CommentDeleteInput) error {
-	err := s.commentRepo.DeleteComment(ctx, input.Username, input.CommentId)
+	var (
+		deleted   []pgmodel.Comment
+		deletedAt time.Time
+		err       error
+	)
+	if input.Moderate {
+		deleted, deletedAt, err = s.commentRepo.DeleteCommentById(ctx, input.CommentId)
+	} else {
+		deleted, deletedAt, err = s.commentRepo.DeleteComment(ctx, input.Username, input.CommentId)
+	}
 	if err != nil {
 		if errors.Is(err, pgerrs.ErrNotFound) {
 			return ErrCommentNotFound
 		}
 		return ErrCannotDeleteComment
 	}
+	// ответы удаляются вместе с комментарием, событие отправляется для каждого из них
+	for i := range deleted {
+		s.events.publish(ctx, PostEvent{Type: EventCommentDeleted, PostId: deleted[i].PostId, Comment: &deleted[i], CreatedAt: deletedAt})
+	}
 	return nil
 }
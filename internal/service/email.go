@@ -0,0 +1,145 @@
+package service
+
+import (
+	"API_for_SN_go/internal/repo/pgerrs"
+	"API_for_SN_go/pkg/mailer"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	log "github.com/sirupsen/logrus"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// emailConfirmAudience отличает токен подтверждения от access токена, подписанного тем же ключом
+	emailConfirmAudience = "email_confirmation"
+	emailConfirmPath     = "/auth/email/confirm"
+
+	emailConfirmSubject = "Confirm your email"
+	emailChangeSubject  = "Email change requested"
+)
+
+type emailConfirmClaims struct {
+	jwt.StandardClaims
+	Email string `json:"email"`
+	// Pending is set for the new address of email change
+	Pending bool `json:"pending,omitempty"`
+}
+
+func (s *authService) SendEmailConfirmation(ctx context.Context, username string) error {
+	user, err := s.userRepo.GetUserByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		log.Errorf("%s/SendEmailConfirmation error find user: %s", authServicePrefixLog, err)
+		return ErrCannotVerifyEmail
+	}
+	// при смене почты повторно отправляется письмо на новый адрес
+	if user.PendingEmail != nil {
+		err = s.sendEmailConfirmation(ctx, user.Username, *user.PendingEmail, true)
+	} else if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	} else {
+		err = s.sendEmailConfirmation(ctx, user.Username, user.Email, false)
+	}
+	if err != nil {
+		log.Errorf("%s/SendEmailConfirmation error send email confirmation: %s", authServicePrefixLog, err)
+		return ErrCannotVerifyEmail
+	}
+	return nil
+}
+
+// ConfirmEmail verifies user email by signed token. Token is bound to the email, so it stops working after email change
+func (s *authService) ConfirmEmail(ctx context.Context, token string) error {
+	t, err := jwt.ParseWithClaims(token, &emailConfirmClaims{}, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %s", t.Header["alg"])
+		}
+		return []byte(s.signKey), nil
+	})
+	if err != nil {
+		return ErrInvalidToken
+	}
+	claims, ok := t.Claims.(*emailConfirmClaims)
+	if !ok || !t.Valid || !claims.VerifyAudience(emailConfirmAudience, true) {
+		return ErrInvalidToken
+	}
+	if claims.Pending {
+		err = s.userRepo.ConfirmPendingEmail(ctx, claims.Subject, claims.Email)
+	} else {
+		err = s.userRepo.SetEmailVerified(ctx, claims.Subject, claims.Email)
+	}
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrInvalidToken
+		}
+		if errors.Is(err, pgerrs.ErrAlreadyExists) {
+			return ErrEmailAlreadyTaken
+		}
+		log.Errorf("%s/ConfirmEmail error set email verified: %s", authServicePrefixLog, err)
+		return ErrCannotVerifyEmail
+	}
+	return nil
+}
+
+func (s *authService) ChangeEmail(ctx context.Context, input EmailChangeInput) error {
+	user, err := s.verifyPassword(ctx, input.Username, input.Password, input.IP)
+	if err != nil {
+		return err
+	}
+	// текущий адрес пользователя тоже считается занятым
+	_, err = s.userRepo.GetUserByEmail(ctx, input.NewEmail)
+	if err == nil {
+		return ErrEmailAlreadyTaken
+	}
+	if !errors.Is(err, pgerrs.ErrNotFound) {
+		log.Errorf("%s/ChangeEmail error find user by email: %s", authServicePrefixLog, err)
+		return ErrCannotChangeEmail
+	}
+	if err = s.userRepo.SetPendingEmail(ctx, input.Username, input.NewEmail); err != nil {
+		log.Errorf("%s/ChangeEmail error set pending email: %s", authServicePrefixLog, err)
+		return ErrCannotChangeEmail
+	}
+	if err = s.sendEmailConfirmation(ctx, input.Username, input.NewEmail, true); err != nil {
+		log.Errorf("%s/ChangeEmail error send email confirmation: %s", authServicePrefixLog, err)
+		return ErrCannotChangeEmail
+	}
+	// старый адрес узнает о смене, если ее запросил не владелец
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: emailChangeSubject,
+		Body: fmt.Sprintf("Hi, %s!\n\nEmail change to %s was requested for your account. It takes effect after confirmation from the new address.\nIf it was not you, change your password.",
+			user.Username, input.NewEmail),
+	})
+	if err != nil {
+		log.Errorf("%s/ChangeEmail error notify old email: %s", authServicePrefixLog, err)
+	}
+	return nil
+}
+
+func (s *authService) sendEmailConfirmation(ctx context.Context, username, email string, pending bool) error {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &emailConfirmClaims{
+		StandardClaims: jwt.StandardClaims{
+			Audience:  emailConfirmAudience,
+			Subject:   username,
+			ExpiresAt: time.Now().Add(s.emailTokenTTL).Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+		Email:   email,
+		Pending: pending,
+	}).SignedString([]byte(s.signKey))
+	if err != nil {
+		return fmt.Errorf("sign confirmation token: %w", err)
+	}
+	link := fmt.Sprintf("%s%s?token=%s", strings.TrimRight(s.publicURL, "/"), emailConfirmPath, url.QueryEscape(token))
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: emailConfirmSubject,
+		Body: fmt.Sprintf("Hi, %s!\n\nConfirm your email by following the link: %s\nThe link expires in %s.",
+			username, link, s.emailTokenTTL),
+	})
+}
@@ -1,6 +1,10 @@
 package service
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
 	ErrUserAlreadyExists = errors.New("user already exists")
@@ -9,22 +13,80 @@ var (
 	ErrCannotUpdateUser  = errors.New("cannot update user info")
 	ErrUserNotFound      = errors.New("user not found")
 	ErrIncorrectPassword = errors.New("incorrect user password")
+	ErrInvalidRole       = errors.New("invalid role")
+	ErrUserSuspended     = errors.New("user is suspended")
+	ErrCannotManageSelf  = errors.New("cannot apply this action to own account")
+
+	ErrCannotCreateToken   = errors.New("cannot create token")
+	ErrInvalidToken        = errors.New("invalid token")
+	ErrExpiredToken        = errors.New("expired token")
+	ErrCannotParseToken    = errors.New("cannot parse token")
+	ErrCannotValidateToken = errors.New("cannot validate token")
+
+	ErrRefreshTokenReused = errors.New("refresh token already used, session revoked")
+
+	ErrSignInLocked = errors.New("too many failed sign-in attempts")
+
+	ErrCannotResetPassword = errors.New("cannot reset password")
+
+	ErrEmailAlreadyVerified = errors.New("email already verified")
+	ErrEmailNotVerified     = errors.New("email is not verified")
+	ErrCannotVerifyEmail    = errors.New("cannot verify email")
+	ErrEmailAlreadyTaken    = errors.New("email already taken")
+	ErrCannotChangeEmail    = errors.New("cannot change email")
+
+	ErrTOTPAlreadyEnabled  = errors.New("two-factor auth already enabled")
+	ErrTOTPNotEnrolled     = errors.New("two-factor auth is not enrolled")
+	ErrTOTPNotEnabled      = errors.New("two-factor auth is not enabled")
+	ErrInvalidTOTPCode     = errors.New("invalid two-factor code")
+	ErrTooManyTOTPAttempts = errors.New("too many two-factor attempts, sign in again")
+	ErrCannotUpdateTOTP    = errors.New("cannot update two-factor auth")
 
-	ErrCannotCreateToken = errors.New("cannot create token")
-	ErrInvalidToken      = errors.New("invalid token")
-	ErrExpiredToken      = errors.New("expired token")
-	ErrCannotParseToken  = errors.New("cannot parse token")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrCannotRevokeSession = errors.New("cannot revoke session")
+
+	ErrInvalidScope          = errors.New("invalid access token scope")
+	ErrAccessTokenNotFound   = errors.New("access token not found")
+	ErrCannotRevokeToken     = errors.New("cannot revoke access token")
+	ErrAccessTokenNotAllowed = errors.New("personal access token is not allowed here")
 
 	ErrCannotCreatePost  = errors.New("cannot create post")
 	ErrPostAlreadyExists = errors.New("post already exists")
 	ErrPostNotFound      = errors.New("post not found")
+	ErrCannotUpdatePost  = errors.New("cannot update post")
+	ErrCannotDeletePost  = errors.New("cannot delete post")
 
 	ErrReactionAlreadyExists = errors.New("reaction already exists")
 	ErrReactionNotFound      = errors.New("reaction not found")
 	ErrCannotCreateReaction  = errors.New("cannot create reaction")
+	ErrCannotGetReactions    = errors.New("cannot get reactions")
+	ErrCannotDeleteReaction  = errors.New("cannot delete reaction")
 
 	ErrCommentAlreadyExists = errors.New("comment already exists")
 	ErrCannotCreateComment  = errors.New("cannot create comment")
 	ErrCommentNotFound      = errors.New("comment not found")
 	ErrCannotDeleteComment  = errors.New("cannot delete comment")
+
+	ErrParentCommentNotFound = errors.New("parent comment not found")
+
+	ErrCannotFollowSelf = errors.New("cannot follow yourself")
+	ErrAlreadyFollowing = errors.New("already following")
+	ErrNotFollowing     = errors.New("not following")
+	ErrCannotFollow     = errors.New("cannot follow user")
+	ErrCannotUnfollow   = errors.New("cannot unfollow user")
+
+	ErrInvalidCursor = errors.New("invalid cursor")
 )
+
+// LockedError is returned while sign-in is locked, errors.Is(err, ErrSignInLocked) is true for it
+type LockedError struct {
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s, locked until %s", ErrSignInLocked, e.Until.UTC().Format(time.RFC3339))
+}
+
+func (e *LockedError) Is(target error) bool {
+	return target == ErrSignInLocked
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"API_for_SN_go/internal/model/pgmodel"
+	"API_for_SN_go/pkg/redis"
+	"context"
+	"encoding/json"
+	goredis "github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const (
+	eventBusPrefixLog = "/service/events"
+
+	postEventsChannelPrefix = "post_events:" // post_events:<post id> -> redis channel of post events
+	postEventsBuffer        = 64
+)
+
+// Types of post events
+const (
+	EventCommentCreated  = "comment_created"
+	EventCommentUpdated  = "comment_updated"
+	EventCommentDeleted  = "comment_deleted"
+	EventReactionCreated = "reaction_created"
+	EventReactionUpdated = "reaction_updated"
+	EventReactionDeleted = "reaction_deleted"
+	EventPostDeleted     = "post_deleted"
+)
+
+// PostEvent is a change of post comments or reactions. Comment is set for comment events, Reaction - for reaction events.
+// CreatedAt is time of the change in database
+type PostEvent struct {
+	Type      string            `json:"type"`
+	PostId    string            `json:"post_id"`
+	Comment   *pgmodel.Comment  `json:"comment,omitempty"`
+	Reaction  *pgmodel.Reaction `json:"reaction,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// eventBus delivers post events to watchers of all instances. Events are published to redis channel of the post,
+// each instance subscribes to the channel while it has watchers of the post and passes events to them
+type eventBus struct {
+	redis *redis.Redis
+
+	mu       sync.Mutex
+	watchers map[string]map[chan PostEvent]struct{}
+
+	// subMu orders subscribe and unsubscribe calls to redis. They are made without mu, so slow redis does not stop dispatch
+	subMu      sync.Mutex
+	pubsub     *goredis.PubSub
+	subscribed map[string]bool
+}
+
+func newEventBus(redis *redis.Redis) *eventBus {
+	return &eventBus{
+		redis:      redis,
+		watchers:   make(map[string]map[chan PostEvent]struct{}),
+		subscribed: make(map[string]bool),
+	}
+}
+
+// publish sends event to all instances. Errors are only logged, the change itself is already saved
+func (b *eventBus) publish(ctx context.Context, event PostEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Errorf("%s/publish error marshal event: %s", eventBusPrefixLog, err)
+		return
+	}
+	if err = b.redis.Pool.Publish(ctx, postEventsChannelPrefix+event.PostId, data).Err(); err != nil {
+		log.Errorf("%s/publish error publish event: %s", eventBusPrefixLog, err)
+		// redis недоступен - событие получат хотя бы подписчики этого экземпляра
+		b.dispatch(event)
+	}
+}
+
+func (b *eventBus) WatchPost(ctx context.Context, postId string) (<-chan PostEvent, error) {
+	ch := make(chan PostEvent, postEventsBuffer)
+
+	b.mu.Lock()
+	if b.watchers[postId] == nil {
+		b.watchers[postId] = make(map[chan PostEvent]struct{})
+	}
+	b.watchers[postId][ch] = struct{}{}
+	b.mu.Unlock()
+
+	// канал отдается только после подписки, иначе наблюдатель пропустит события
+	if err := b.syncSubscription(ctx, postId); err != nil {
+		log.Errorf("%s/WatchPost error subscribe to post events: %s", eventBusPrefixLog, err)
+		b.unwatch(postId, ch)
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		b.unwatch(postId, ch)
+	}()
+	return ch, nil
+}
+
+// unwatch closes watcher channel and unsubscribes from the post channel after its last watcher
+func (b *eventBus) unwatch(postId string, ch chan PostEvent) {
+	b.mu.Lock()
+	last := b.remove(postId, ch)
+	b.mu.Unlock()
+	if last {
+		b.unsubscribe(postId)
+	}
+}
+
+func (b *eventBus) unsubscribe(postId string) {
+	if err := b.syncSubscription(context.Background(), postId); err != nil {
+		log.Errorf("%s/unsubscribe error unsubscribe from post events: %s", eventBusPrefixLog, err)
+	}
+}
+
+// syncSubscription subscribes to redis channel of the post if it has watchers, and unsubscribes if it has not.
+// Watchers are checked under subMu, so concurrent calls for the same post leave the right state
+func (b *eventBus) syncSubscription(ctx context.Context, postId string) error {
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+
+	b.mu.Lock()
+	_, watched := b.watchers[postId]
+	b.mu.Unlock()
+
+	if watched == b.subscribed[postId] {
+		return nil
+	}
+	if !watched {
+		delete(b.subscribed, postId)
+		return b.pubsub.Unsubscribe(ctx, postEventsChannelPrefix+postId)
+	}
+	if b.pubsub == nil {
+		// одно соединение на все посты, go-redis сам переподключается и восстанавливает подписки
+		b.pubsub = b.redis.Pool.Subscribe(context.Background())
+		go b.listen(b.pubsub.Channel())
+	}
+	if err := b.pubsub.Subscribe(ctx, postEventsChannelPrefix+postId); err != nil {
+		return err
+	}
+	b.subscribed[postId] = true
+	return nil
+}
+
+func (b *eventBus) listen(messages <-chan *goredis.Message) {
+	for msg := range messages {
+		var event PostEvent
+		if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+			log.Errorf("%s/listen error unmarshal event: %s", eventBusPrefixLog, err)
+			continue
+		}
+		b.dispatch(event)
+	}
+}
+
+func (b *eventBus) dispatch(event PostEvent) {
+	b.mu.Lock()
+	var last bool
+	for ch := range b.watchers[event.PostId] {
+		select {
+		case ch <- event:
+			// после удаления поста событий больше не будет
+			if event.Type == EventPostDeleted {
+				last = b.remove(event.PostId, ch)
+			}
+		default:
+			// наблюдатель не успевает читать события: закрываем канал, чтобы он переподписался и перечитал пост
+			last = b.remove(event.PostId, ch)
+		}
+	}
+	b.mu.Unlock()
+	if last {
+		// dispatch вызывается из listen, отписка через то же соединение не должна его задерживать
+		go b.unsubscribe(event.PostId)
+	}
+}
+
+// remove closes watcher channel and reports whether it was the last watcher of the post. Must be called under lock
+func (b *eventBus) remove(postId string, ch chan PostEvent) bool {
+	watchers, ok := b.watchers[postId]
+	if !ok {
+		return false
+	}
+	if _, ok = watchers[ch]; !ok {
+		return false
+	}
+	delete(watchers, ch)
+	close(ch)
+	if len(watchers) != 0 {
+		return false
+	}
+	delete(b.watchers, postId)
+	return true
+}
@@ -0,0 +1,379 @@
+package service
+
+import (
+	"API_for_SN_go/internal/model/pgmodel"
+	"API_for_SN_go/pkg/redis"
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pubSubServer is redis server of event bus tests, it supports only PING, SUBSCRIBE, UNSUBSCRIBE and PUBLISH
+type pubSubServer struct {
+	ln net.Listener
+
+	mu   sync.Mutex
+	subs map[string]map[*pubSubConn]struct{}
+}
+
+type pubSubConn struct {
+	mu       sync.Mutex
+	w        *bufio.Writer
+	channels map[string]bool
+}
+
+func newPubSubServer(t *testing.T) *pubSubServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &pubSubServer{ln: ln, subs: make(map[string]map[*pubSubConn]struct{})}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *pubSubServer) addr() string {
+	return s.ln.Addr().String()
+}
+
+// channels returns channels with subscribers
+func (s *pubSubServer) channels() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var channels []string
+	for ch, conns := range s.subs {
+		if len(conns) != 0 {
+			channels = append(channels, ch)
+		}
+	}
+	return channels
+}
+
+func (s *pubSubServer) serve(conn net.Conn) {
+	defer conn.Close()
+	c := &pubSubConn{w: bufio.NewWriter(conn), channels: make(map[string]bool)}
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for ch := range c.channels {
+			delete(s.subs[ch], c)
+		}
+	}()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		switch strings.ToUpper(args[0]) {
+		case "PING":
+			if len(c.channels) != 0 {
+				c.write("pong", "")
+			} else {
+				c.writeRaw("+PONG\r\n")
+			}
+		case "SUBSCRIBE":
+			for _, ch := range args[1:] {
+				s.mu.Lock()
+				if s.subs[ch] == nil {
+					s.subs[ch] = make(map[*pubSubConn]struct{})
+				}
+				s.subs[ch][c] = struct{}{}
+				c.channels[ch] = true
+				s.mu.Unlock()
+				c.write("subscribe", ch, len(c.channels))
+			}
+		case "UNSUBSCRIBE":
+			for _, ch := range args[1:] {
+				s.mu.Lock()
+				delete(s.subs[ch], c)
+				delete(c.channels, ch)
+				s.mu.Unlock()
+				c.write("unsubscribe", ch, len(c.channels))
+			}
+		case "PUBLISH":
+			s.mu.Lock()
+			receivers := make([]*pubSubConn, 0, len(s.subs[args[1]]))
+			for sub := range s.subs[args[1]] {
+				receivers = append(receivers, sub)
+			}
+			s.mu.Unlock()
+			for _, sub := range receivers {
+				sub.write("message", args[1], args[2])
+			}
+			c.writeRaw(fmt.Sprintf(":%d\r\n", len(receivers)))
+		default:
+			c.writeRaw("-ERR unknown command\r\n")
+		}
+	}
+}
+
+func (c *pubSubConn) write(items ...interface{}) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(items))
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(v), v)
+		case int:
+			fmt.Fprintf(&b, ":%d\r\n", v)
+		}
+	}
+	c.writeRaw(b.String())
+}
+
+func (c *pubSubConn) writeRaw(data string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, _ = c.w.WriteString(data)
+	_ = c.w.Flush()
+}
+
+// readCommand reads command sent by go-redis as array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	n, err := readLength(r, '*')
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		size, err := readLength(r, '$')
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func readLength(r *bufio.Reader, prefix byte) (int, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return 0, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if len(line) == 0 || line[0] != prefix {
+		return 0, fmt.Errorf("unexpected line %q", line)
+	}
+	return strconv.Atoi(line[1:])
+}
+
+func newTestEventBus(t *testing.T) (*eventBus, *pubSubServer) {
+	server := newPubSubServer(t)
+	rdb := redis.NewRedis(server.addr())
+	t.Cleanup(func() { _ = rdb.Pool.Close() })
+	return newEventBus(rdb), server
+}
+
+// watch starts watching post and waits until redis subscription is done
+func watch(t *testing.T, ctx context.Context, b *eventBus, server *pubSubServer, postId string) <-chan PostEvent {
+	events, err := b.WatchPost(ctx, postId)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]string{postEventsChannelPrefix + postId}, server.channels())
+	}, time.Second, 5*time.Millisecond)
+	return events
+}
+
+func receive(t *testing.T, events <-chan PostEvent) (PostEvent, bool) {
+	select {
+	case event, ok := <-events:
+		return event, ok
+	case <-time.After(time.Second):
+		t.Fatal("no post event")
+		return PostEvent{}, false
+	}
+}
+
+func (b *eventBus) watched(postId string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.watchers[postId]
+	return ok
+}
+
+func TestEventBus_publish(t *testing.T) {
+	b, server := newTestEventBus(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first := watch(t, ctx, b, server, "post1")
+	second, err := b.WatchPost(ctx, "post1")
+	require.NoError(t, err)
+
+	// время события берется из сохраненного комментария, а не из момента отправки
+	createdAt := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	comment := pgmodel.Comment{PostId: "post1", CommentId: "comment1", Username: "vasek", Comment: "hi", CreatedAt: createdAt, UpdatedAt: createdAt}
+	b.publish(context.Background(), PostEvent{Type: EventCommentCreated, PostId: "post1", Comment: &comment, CreatedAt: createdAt})
+
+	for _, events := range []<-chan PostEvent{first, second} {
+		event, ok := receive(t, events)
+		require.True(t, ok)
+		assert.Equal(t, EventCommentCreated, event.Type)
+		assert.Equal(t, "post1", event.PostId)
+		assert.True(t, createdAt.Equal(event.CreatedAt))
+		require.NotNil(t, event.Comment)
+		assert.Equal(t, "comment1", event.Comment.CommentId)
+		assert.Nil(t, event.Reaction)
+	}
+
+	// события другого поста не приходят
+	b.publish(context.Background(), PostEvent{Type: EventReactionCreated, PostId: "post2", CreatedAt: createdAt})
+	select {
+	case event := <-first:
+		t.Fatalf("unexpected event %v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// Without redis events are dispatched to watchers of this instance
+func TestEventBus_publishWithoutRedis(t *testing.T) {
+	rdb, _ := newMemoryRedis()
+	b := newEventBus(rdb)
+	ch := make(chan PostEvent, postEventsBuffer)
+	b.watchers["post1"] = map[chan PostEvent]struct{}{ch: {}}
+
+	createdAt := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	b.publish(context.Background(), PostEvent{Type: EventReactionDeleted, PostId: "post1", CreatedAt: createdAt})
+
+	event, ok := receive(t, ch)
+	require.True(t, ok)
+	assert.Equal(t, EventReactionDeleted, event.Type)
+	assert.Equal(t, createdAt, event.CreatedAt)
+}
+
+func TestEventBus_slowWatcher(t *testing.T) {
+	b, server := newTestEventBus(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	slow := watch(t, ctx, b, server, "post1")
+	fast, err := b.WatchPost(ctx, "post1")
+	require.NoError(t, err)
+
+	for i := 0; i <= postEventsBuffer; i++ {
+		b.dispatch(PostEvent{Type: EventReactionCreated, PostId: "post1"})
+		_, ok := receive(t, fast)
+		require.True(t, ok)
+	}
+
+	// медленный наблюдатель получает события из буфера, затем канал закрыт
+	for i := 0; i < postEventsBuffer; i++ {
+		_, ok := receive(t, slow)
+		require.True(t, ok)
+	}
+	_, ok := receive(t, slow)
+	assert.False(t, ok)
+
+	// быстрый наблюдатель продолжает получать события
+	b.dispatch(PostEvent{Type: EventReactionDeleted, PostId: "post1"})
+	event, ok := receive(t, fast)
+	require.True(t, ok)
+	assert.Equal(t, EventReactionDeleted, event.Type)
+	assert.Equal(t, []string{postEventsChannelPrefix + "post1"}, server.channels())
+}
+
+func TestEventBus_slowLastWatcher(t *testing.T) {
+	b, server := newTestEventBus(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	slow := watch(t, ctx, b, server, "post1")
+	for i := 0; i <= postEventsBuffer; i++ {
+		b.dispatch(PostEvent{Type: EventReactionCreated, PostId: "post1"})
+	}
+	for i := 0; i < postEventsBuffer; i++ {
+		<-slow
+	}
+	_, ok := receive(t, slow)
+	assert.False(t, ok)
+
+	// после последнего наблюдателя экземпляр отписывается от поста
+	assert.False(t, b.watched("post1"))
+	assert.Eventually(t, func() bool { return len(server.channels()) == 0 }, time.Second, 5*time.Millisecond)
+}
+
+func TestEventBus_postDeleted(t *testing.T) {
+	b, server := newTestEventBus(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first := watch(t, ctx, b, server, "post1")
+	second, err := b.WatchPost(ctx, "post1")
+	require.NoError(t, err)
+
+	deletedAt := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	b.publish(context.Background(), PostEvent{Type: EventPostDeleted, PostId: "post1", CreatedAt: deletedAt})
+
+	// событие удаления последнее, после него каналы закрыты
+	for _, events := range []<-chan PostEvent{first, second} {
+		event, ok := receive(t, events)
+		require.True(t, ok)
+		assert.Equal(t, EventPostDeleted, event.Type)
+		assert.True(t, deletedAt.Equal(event.CreatedAt))
+		_, ok = receive(t, events)
+		assert.False(t, ok)
+	}
+	assert.False(t, b.watched("post1"))
+	assert.Eventually(t, func() bool { return len(server.channels()) == 0 }, time.Second, 5*time.Millisecond)
+}
+
+func TestEventBus_watchPostCleanup(t *testing.T) {
+	b, server := newTestEventBus(t)
+
+	firstCtx, firstCancel := context.WithCancel(context.Background())
+	defer firstCancel()
+	first := watch(t, firstCtx, b, server, "post1")
+	secondCtx, secondCancel := context.WithCancel(context.Background())
+	defer secondCancel()
+	second, err := b.WatchPost(secondCtx, "post1")
+	require.NoError(t, err)
+
+	// канал закрывается после отмены контекста, подписка остается для другого наблюдателя
+	firstCancel()
+	_, ok := receive(t, first)
+	assert.False(t, ok)
+	assert.True(t, b.watched("post1"))
+	assert.Equal(t, []string{postEventsChannelPrefix + "post1"}, server.channels())
+
+	// после последнего наблюдателя экземпляр отписывается от поста
+	secondCancel()
+	_, ok = receive(t, second)
+	assert.False(t, ok)
+	assert.False(t, b.watched("post1"))
+	assert.Eventually(t, func() bool { return len(server.channels()) == 0 }, time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		b.subMu.Lock()
+		defer b.subMu.Unlock()
+		return len(b.subscribed) == 0
+	}, time.Second, 5*time.Millisecond)
+
+	// новый наблюдатель подписывается снова
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	again := watch(t, ctx, b, server, "post1")
+	b.publish(context.Background(), PostEvent{Type: EventCommentDeleted, PostId: "post1"})
+	event, ok := receive(t, again)
+	require.True(t, ok)
+	assert.Equal(t, EventCommentDeleted, event.Type)
+}
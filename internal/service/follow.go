@@ -0,0 +1,84 @@
+package service
+
+import (
+	"API_for_SN_go/internal/model/pgmodel"
+	"API_for_SN_go/internal/repo"
+	"API_for_SN_go/internal/repo/pagination"
+	"API_for_SN_go/internal/repo/pgerrs"
+	"context"
+	"errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const followServicePrefixLog = "/service/follow"
+
+type followService struct {
+	followRepo repo.Follow
+}
+
+func newFollowService(followRepo repo.Follow) *followService {
+	return &followService{followRepo: followRepo}
+}
+
+func (s *followService) Follow(ctx context.Context, input FollowInput) error {
+	if input.Follower == input.Followee {
+		return ErrCannotFollowSelf
+	}
+	err := s.followRepo.CreateFollow(ctx, input.Follower, input.Followee)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrAlreadyExists) {
+			return ErrAlreadyFollowing
+		}
+		if errors.Is(err, pgerrs.ErrForeignKey) {
+			return ErrUserNotFound
+		}
+		log.Errorf("%s/Follow error create follow: %s", followServicePrefixLog, err)
+		return ErrCannotFollow
+	}
+	return nil
+}
+
+func (s *followService) Unfollow(ctx context.Context, input FollowInput) error {
+	err := s.followRepo.DeleteFollow(ctx, input.Follower, input.Followee)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrNotFollowing
+		}
+		log.Errorf("%s/Unfollow error delete follow: %s", followServicePrefixLog, err)
+		return ErrCannotUnfollow
+	}
+	return nil
+}
+
+func (s *followService) GetFollowers(ctx context.Context, username string, p repo.Pagination) ([]pgmodel.Follow, string, error) {
+	follows, next, err := s.followRepo.GetFollowers(ctx, username, p)
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			return nil, "", ErrInvalidCursor
+		}
+		log.Errorf("%s/GetFollowers error finding followers: %s", followServicePrefixLog, err)
+		return nil, "", err
+	}
+	return follows, next, nil
+}
+
+func (s *followService) GetFollowing(ctx context.Context, username string, p repo.Pagination) ([]pgmodel.Follow, string, error) {
+	follows, next, err := s.followRepo.GetFollowing(ctx, username, p)
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			return nil, "", ErrInvalidCursor
+		}
+		log.Errorf("%s/GetFollowing error finding following: %s", followServicePrefixLog, err)
+		return nil, "", err
+	}
+	return follows, next, nil
+}
+
+func (s *followService) GetFollowCounts(ctx context.Context, username string) (FollowCounts, error) {
+	followers, following, err := s.followRepo.CountFollows(ctx, username)
+	if err != nil {
+		log.Errorf("%s/GetFollowCounts error counting follows: %s", followServicePrefixLog, err)
+		return FollowCounts{}, err
+	}
+	return FollowCounts{Followers: followers, Following: following}, nil
+}
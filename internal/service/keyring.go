@@ -0,0 +1,363 @@
+package service
+
+import (
+	"API_for_SN_go/pkg/jwk"
+	"API_for_SN_go/pkg/redis"
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	signingKeyPrefix = "jwk:" // jwk:<kid> -> signing key
+	signingKeysKey   = "jwks" // set of signing key ids
+
+	keyRotationLockKey = "jwk_rotation_lock"
+	keyRotationLockTTL = 10 * time.Second
+	keyRotationWait    = 100 * time.Millisecond
+	keyRotationTries   = 20
+
+	// набор ключей перечитывается не чаще интервала, в том числе из-за неизвестного kid,
+	// иначе поддельные токены со случайными kid нагрузят redis и заблокируют проверку токенов
+	keyReloadInterval = 10 * time.Second
+
+	rsaKeyBits = 2048
+)
+
+var errUnknownKey = errors.New("unknown signing key")
+
+// storedKey is signing key record in redis
+type storedKey struct {
+	Id  string `json:"kid"`
+	Alg string `json:"alg"`
+	// Private is PKCS #8 DER private key sealed with AES-GCM by key encryption key, nonce goes first
+	Private   []byte    `json:"private"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type signingKey struct {
+	id        string
+	method    jwt.SigningMethod
+	private   crypto.Signer
+	createdAt time.Time
+}
+
+// keyring keeps asymmetric keys of access tokens in redis, so all instances sign and verify with the same keys.
+// The newest key signs tokens during rotation interval, then it only verifies tokens until they expire.
+// Private keys are stored encrypted with kek, so they are not readable from redis alone
+type keyring struct {
+	redis     *redis.Redis
+	alg       string
+	kek       []byte
+	rotation  time.Duration
+	verifyTTL time.Duration
+	now       func() time.Time
+
+	mu       sync.RWMutex
+	keys     map[string]signingKey
+	loadedAt time.Time
+}
+
+func newKeyring(redis *redis.Redis, alg string, kek []byte, rotation, verifyTTL time.Duration) *keyring {
+	return &keyring{
+		redis:     redis,
+		alg:       alg,
+		kek:       kek,
+		rotation:  rotation,
+		verifyTTL: verifyTTL,
+		now:       time.Now,
+		keys:      make(map[string]signingKey),
+	}
+}
+
+// signingKey returns current key, rotating it when rotation interval is over
+func (k *keyring) signingKey(ctx context.Context) (signingKey, error) {
+	k.mu.RLock()
+	key, ok := k.current()
+	k.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for i := 0; i < keyRotationTries; i++ {
+		if err := k.load(ctx); err != nil {
+			return signingKey{}, err
+		}
+		if key, ok = k.current(); ok {
+			return key, nil
+		}
+		locked, err := k.redis.Pool.SetNX(ctx, keyRotationLockKey, 1, keyRotationLockTTL).Result()
+		if err != nil {
+			return signingKey{}, err
+		}
+		if locked {
+			key, err = k.rotate(ctx)
+			if delErr := k.redis.Pool.Del(ctx, keyRotationLockKey).Err(); delErr != nil {
+				log.Errorf("%s/signingKey error release rotation lock: %s", authServicePrefixLog, delErr)
+			}
+			return key, err
+		}
+		// ключ создает другой экземпляр сервиса, ждем его в redis
+		time.Sleep(keyRotationWait)
+	}
+	return signingKey{}, errors.New("signing key rotation timeout")
+}
+
+// verificationKey returns key by kid, including keys which do not sign anymore.
+// Unknown kid is looked up in redis, because another instance may have just rotated the key,
+// but keys are reloaded not more often than keyReloadInterval whatever kid is
+func (k *keyring) verificationKey(ctx context.Context, kid string) (signingKey, error) {
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	reloaded := k.reloaded()
+	k.mu.RUnlock()
+	if ok {
+		return k.valid(key)
+	}
+	// kid всегда uuid, остальные значения не ищем в redis
+	if _, err := uuid.Parse(kid); err != nil || reloaded {
+		return signingKey{}, errUnknownKey
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	// ключи мог перечитать другой запрос, пока ждали блокировку
+	if key, ok = k.keys[kid]; ok {
+		return k.valid(key)
+	}
+	if k.reloaded() {
+		return signingKey{}, errUnknownKey
+	}
+	if err := k.load(ctx); err != nil {
+		return signingKey{}, err
+	}
+	if key, ok = k.keys[kid]; ok {
+		return k.valid(key)
+	}
+	return signingKey{}, errUnknownKey
+}
+
+// publicKeys returns JWK set of all keys which verify tokens
+func (k *keyring) publicKeys(ctx context.Context) (jwk.Set, error) {
+	// в наборе всегда есть текущий ключ
+	if _, err := k.signingKey(ctx); err != nil {
+		return jwk.Set{}, err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if !k.reloaded() {
+		if err := k.load(ctx); err != nil {
+			return jwk.Set{}, err
+		}
+	}
+	keys := make([]signingKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		if !k.expired(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].createdAt.After(keys[j].createdAt) })
+
+	set := jwk.Set{Keys: make([]jwk.Key, 0, len(keys))}
+	for _, key := range keys {
+		pub, err := jwk.New(key.id, key.private.Public())
+		if err != nil {
+			return jwk.Set{}, err
+		}
+		set.Keys = append(set.Keys, pub)
+	}
+	return set, nil
+}
+
+// current returns the newest key of configured algorithm if it may still sign. Must be called under lock
+func (k *keyring) current() (signingKey, bool) {
+	var newest signingKey
+	for _, key := range k.keys {
+		if key.method.Alg() == k.alg && key.createdAt.After(newest.createdAt) {
+			newest = key
+		}
+	}
+	if newest.id == "" || k.now().Sub(newest.createdAt) >= k.rotation {
+		return signingKey{}, false
+	}
+	return newest, true
+}
+
+// reloaded reports whether keys were loaded from redis during the last keyReloadInterval. Must be called under lock
+func (k *keyring) reloaded() bool {
+	return k.now().Sub(k.loadedAt) < keyReloadInterval
+}
+
+func (k *keyring) expired(key signingKey) bool {
+	return k.now().Sub(key.createdAt) >= k.rotation+k.verifyTTL
+}
+
+func (k *keyring) valid(key signingKey) (signingKey, error) {
+	if k.expired(key) {
+		return signingKey{}, errUnknownKey
+	}
+	return key, nil
+}
+
+// load replaces cached keys with keys from redis. Must be called under write lock
+func (k *keyring) load(ctx context.Context) error {
+	ids, err := k.redis.Pool.SMembers(ctx, signingKeysKey).Result()
+	if err != nil {
+		return err
+	}
+	keys := make(map[string]signingKey, len(ids))
+	for _, id := range ids {
+		data, err := k.redis.Pool.Get(ctx, signingKeyPrefix+id).Bytes()
+		if err != nil {
+			if errors.Is(err, goredis.Nil) {
+				// срок ключа истек
+				if err = k.redis.Pool.SRem(ctx, signingKeysKey, id).Err(); err != nil {
+					log.Errorf("%s/load error delete expired key id: %s", authServicePrefixLog, err)
+				}
+				continue
+			}
+			return err
+		}
+		key, err := k.parseStoredKey(data)
+		if err != nil {
+			log.Errorf("%s/load error parse key %s: %s", authServicePrefixLog, id, err)
+			continue
+		}
+		keys[key.id] = key
+	}
+	k.keys = keys
+	k.loadedAt = k.now()
+	return nil
+}
+
+// rotate creates new signing key. Must be called under write lock and redis rotation lock
+func (k *keyring) rotate(ctx context.Context) (signingKey, error) {
+	var private crypto.Signer
+	var err error
+	switch k.alg {
+	case jwk.RS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case jwk.EdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		err = fmt.Errorf("unsupported signing algorithm %s", k.alg)
+	}
+	if err != nil {
+		return signingKey{}, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return signingKey{}, err
+	}
+	stored := storedKey{
+		Id:        uuid.NewString(),
+		Alg:       k.alg,
+		CreatedAt: k.now(),
+	}
+	if stored.Private, err = k.seal(stored.Id, stored.Alg, der); err != nil {
+		return signingKey{}, err
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return signingKey{}, err
+	}
+	ttl := k.rotation + k.verifyTTL
+	if err = k.redis.Pool.Set(ctx, signingKeyPrefix+stored.Id, data, ttl).Err(); err != nil {
+		return signingKey{}, err
+	}
+	if err = k.redis.Pool.SAdd(ctx, signingKeysKey, stored.Id).Err(); err != nil {
+		return signingKey{}, err
+	}
+	// множество живет не меньше самого нового ключа
+	if err = k.redis.Pool.Expire(ctx, signingKeysKey, ttl).Err(); err != nil {
+		return signingKey{}, err
+	}
+	key := signingKey{
+		id:        stored.Id,
+		method:    jwt.GetSigningMethod(stored.Alg),
+		private:   private,
+		createdAt: stored.CreatedAt,
+	}
+	k.keys[key.id] = key
+	return key, nil
+}
+
+func (k *keyring) parseStoredKey(data []byte) (signingKey, error) {
+	var stored storedKey
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return signingKey{}, err
+	}
+	method := jwt.GetSigningMethod(stored.Alg)
+	if method == nil {
+		return signingKey{}, fmt.Errorf("unsupported signing algorithm %s", stored.Alg)
+	}
+	der, err := k.open(stored.Id, stored.Alg, stored.Private)
+	if err != nil {
+		return signingKey{}, err
+	}
+	private, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return signingKey{}, err
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return signingKey{}, jwk.ErrUnsupportedKey
+	}
+	return signingKey{
+		id:        stored.Id,
+		method:    method,
+		private:   signer,
+		createdAt: stored.CreatedAt,
+	}, nil
+}
+
+// seal encrypts private key with kek. Kid and algorithm are authenticated, so sealed key cannot be moved to another record
+func (k *keyring) seal(kid, alg string, der []byte) ([]byte, error) {
+	aead, err := k.aead()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(der)+aead.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, der, []byte(kid+":"+alg)), nil
+}
+
+// open decrypts private key sealed by seal
+func (k *keyring) open(kid, alg string, sealed []byte) ([]byte, error) {
+	aead, err := k.aead()
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("sealed key is too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, []byte(kid+":"+alg))
+}
+
+func (k *keyring) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k.kek)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
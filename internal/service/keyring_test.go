@@ -0,0 +1,352 @@
+package service
+
+import (
+	"API_for_SN_go/pkg/jwk"
+	"API_for_SN_go/pkg/redis"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryPool is in-memory redis of service tests without pub/sub, key ttl is ignored
+type memoryPool struct {
+	mu     sync.Mutex
+	values map[string]string
+	sets   map[string]map[string]struct{}
+	// loads counts SMEMBERS calls, each of them is reload of keyring
+	loads int
+}
+
+func newMemoryRedis() (*redis.Redis, *memoryPool) {
+	pool := &memoryPool{values: make(map[string]string), sets: make(map[string]map[string]struct{})}
+	return &redis.Redis{Pool: pool}, pool
+}
+
+func (p *memoryPool) Set(ctx context.Context, key string, value interface{}, _ time.Duration) *goredis.StatusCmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.values[key] = toString(value)
+	cmd := goredis.NewStatusCmd(ctx)
+	cmd.SetVal("OK")
+	return cmd
+}
+
+func (p *memoryPool) Get(ctx context.Context, key string) *goredis.StringCmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cmd := goredis.NewStringCmd(ctx)
+	value, ok := p.values[key]
+	if !ok {
+		cmd.SetErr(goredis.Nil)
+	}
+	cmd.SetVal(value)
+	return cmd
+}
+
+func (p *memoryPool) GetDel(ctx context.Context, key string) *goredis.StringCmd {
+	cmd := p.Get(ctx, key)
+	p.Del(ctx, key)
+	return cmd
+}
+
+func (p *memoryPool) SetNX(ctx context.Context, key string, value interface{}, _ time.Duration) *goredis.BoolCmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cmd := goredis.NewBoolCmd(ctx)
+	if _, ok := p.values[key]; ok {
+		return cmd
+	}
+	p.values[key] = toString(value)
+	cmd.SetVal(true)
+	return cmd
+}
+
+func (p *memoryPool) Incr(ctx context.Context, key string) *goredis.IntCmd {
+	cmd := goredis.NewIntCmd(ctx)
+	cmd.SetErr(errors.New("not implemented"))
+	return cmd
+}
+
+func (p *memoryPool) Del(ctx context.Context, keys ...string) *goredis.IntCmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var deleted int64
+	for _, key := range keys {
+		if _, ok := p.values[key]; ok {
+			deleted++
+		}
+		delete(p.values, key)
+		delete(p.sets, key)
+	}
+	cmd := goredis.NewIntCmd(ctx)
+	cmd.SetVal(deleted)
+	return cmd
+}
+
+func (p *memoryPool) Expire(ctx context.Context, _ string, _ time.Duration) *goredis.BoolCmd {
+	cmd := goredis.NewBoolCmd(ctx)
+	cmd.SetVal(true)
+	return cmd
+}
+
+func (p *memoryPool) SAdd(ctx context.Context, key string, members ...interface{}) *goredis.IntCmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sets[key] == nil {
+		p.sets[key] = make(map[string]struct{})
+	}
+	for _, m := range members {
+		p.sets[key][toString(m)] = struct{}{}
+	}
+	cmd := goredis.NewIntCmd(ctx)
+	cmd.SetVal(int64(len(members)))
+	return cmd
+}
+
+func (p *memoryPool) SMembers(ctx context.Context, key string) *goredis.StringSliceCmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.loads++
+	members := make([]string, 0, len(p.sets[key]))
+	for m := range p.sets[key] {
+		members = append(members, m)
+	}
+	cmd := goredis.NewStringSliceCmd(ctx)
+	cmd.SetVal(members)
+	return cmd
+}
+
+func (p *memoryPool) SRem(ctx context.Context, key string, members ...interface{}) *goredis.IntCmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, m := range members {
+		delete(p.sets[key], toString(m))
+	}
+	cmd := goredis.NewIntCmd(ctx)
+	cmd.SetVal(int64(len(members)))
+	return cmd
+}
+
+func (p *memoryPool) Eval(ctx context.Context, _ string, _ []string, _ ...interface{}) *goredis.Cmd {
+	cmd := goredis.NewCmd(ctx)
+	cmd.SetErr(errors.New("not implemented"))
+	return cmd
+}
+
+func (p *memoryPool) Publish(ctx context.Context, _ string, _ interface{}) *goredis.IntCmd {
+	cmd := goredis.NewIntCmd(ctx)
+	cmd.SetErr(errors.New("not implemented"))
+	return cmd
+}
+
+func (p *memoryPool) Subscribe(context.Context, ...string) *goredis.PubSub {
+	return nil
+}
+
+func (p *memoryPool) Close() error {
+	return nil
+}
+
+func (p *memoryPool) reloads() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.loads
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+var testKEK = []byte("0123456789abcdef0123456789abcdef")
+
+// clock is time of keyring tests which moves only by hand
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestKeyring(rdb *redis.Redis, alg string, c *clock) *keyring {
+	k := newKeyring(rdb, alg, testKEK, time.Hour, 30*time.Minute)
+	k.now = c.Now
+	return k
+}
+
+func signTestToken(t *testing.T, k *keyring) string {
+	key, err := k.signingKey(context.Background())
+	require.NoError(t, err)
+	token := jwt.NewWithClaims(key.method, jwt.StandardClaims{Subject: "user"})
+	token.Header["kid"] = key.id
+	signed, err := token.SignedString(key.private)
+	require.NoError(t, err)
+	return signed
+}
+
+func verifyTestToken(k *keyring, tokenString string) error {
+	_, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := k.verificationKey(context.Background(), kid)
+		if err != nil {
+			return nil, err
+		}
+		return key.private.Public(), nil
+	})
+	// jwt/v3 не раскрывает ошибку ключа через errors.Is
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) && validationErr.Inner != nil {
+		return validationErr.Inner
+	}
+	return err
+}
+
+func TestKeyring_rotation(t *testing.T) {
+	for _, alg := range []string{jwk.RS256, jwk.EdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			rdb, _ := newMemoryRedis()
+			c := &clock{now: time.Now()}
+			k := newTestKeyring(rdb, alg, c)
+
+			old := signTestToken(t, k)
+			oldKey, err := k.signingKey(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, alg, oldKey.method.Alg())
+
+			// ключ подписывает токены весь интервал ротации
+			c.Add(time.Hour - time.Second)
+			key, err := k.signingKey(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, oldKey.id, key.id)
+
+			// после ротации подписывает новый ключ, старый еще проверяет токены
+			c.Add(time.Second)
+			current := signTestToken(t, k)
+			key, err = k.signingKey(context.Background())
+			require.NoError(t, err)
+			assert.NotEqual(t, oldKey.id, key.id)
+			assert.NoError(t, verifyTestToken(k, old))
+			assert.NoError(t, verifyTestToken(k, current))
+
+			set, err := k.publicKeys(context.Background())
+			require.NoError(t, err)
+			assert.Len(t, set.Keys, 2)
+
+			c.Add(30*time.Minute - time.Second)
+			assert.NoError(t, verifyTestToken(k, old))
+
+			// через время жизни токена после ротации старый ключ не принимается
+			c.Add(time.Second)
+			assert.ErrorIs(t, verifyTestToken(k, old), errUnknownKey)
+			assert.NoError(t, verifyTestToken(k, current))
+
+			c.Add(keyReloadInterval)
+			set, err = k.publicKeys(context.Background())
+			require.NoError(t, err)
+			require.Len(t, set.Keys, 1)
+			assert.Equal(t, key.id, set.Keys[0].Kid)
+		})
+	}
+}
+
+// Key rotated by another instance is found by unknown kid, but unknown kids do not reload keys more often than interval
+func TestKeyring_unknownKid(t *testing.T) {
+	rdb, pool := newMemoryRedis()
+	c := &clock{now: time.Now()}
+	verifier := newTestKeyring(rdb, jwk.EdDSA, c)
+	signer := newTestKeyring(rdb, jwk.EdDSA, c)
+
+	first := signTestToken(t, signer)
+	assert.NoError(t, verifyTestToken(verifier, first))
+
+	c.Add(time.Hour)
+	second := signTestToken(t, signer)
+	assert.NoError(t, verifyTestToken(verifier, second))
+
+	// неизвестные kid перечитывают ключи не чаще интервала, даже если каждый kid новый
+	loads := pool.reloads()
+	for i := 0; i < 100; i++ {
+		_, err := verifier.verificationKey(context.Background(), uuid.NewString())
+		assert.ErrorIs(t, err, errUnknownKey)
+	}
+	assert.Equal(t, loads, pool.reloads())
+
+	// одновременные запросы тоже перечитывают ключи один раз
+	c.Add(keyReloadInterval)
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := verifier.verificationKey(context.Background(), uuid.NewString())
+			assert.ErrorIs(t, err, errUnknownKey)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, loads+1, pool.reloads())
+
+	c.Add(keyReloadInterval)
+	_, err := verifier.verificationKey(context.Background(), uuid.NewString())
+	assert.ErrorIs(t, err, errUnknownKey)
+	assert.Equal(t, loads+2, pool.reloads())
+
+	// kid не в формате uuid не ищется в redis
+	_, err = verifier.verificationKey(context.Background(), "random")
+	assert.ErrorIs(t, err, errUnknownKey)
+	assert.Equal(t, loads+2, pool.reloads())
+}
+
+func TestKeyring_encryptedKeys(t *testing.T) {
+	rdb, _ := newMemoryRedis()
+	c := &clock{now: time.Now()}
+	k := newTestKeyring(rdb, jwk.EdDSA, c)
+	token := signTestToken(t, k)
+	key, err := k.signingKey(context.Background())
+	require.NoError(t, err)
+
+	// в redis нет открытого ключа
+	stored, err := rdb.Pool.Get(context.Background(), signingKeyPrefix+key.id).Result()
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key.private)
+	require.NoError(t, err)
+	assert.NotContains(t, stored, base64.StdEncoding.EncodeToString(der))
+
+	// ключ с другим kek не расшифровывается
+	other := newKeyring(rdb, jwk.EdDSA, []byte("fedcba9876543210fedcba9876543210"), time.Hour, 30*time.Minute)
+	other.now = c.Now
+	_, err = other.parseStoredKey([]byte(stored))
+	assert.Error(t, err)
+	assert.ErrorIs(t, verifyTestToken(other, token), errUnknownKey)
+
+	// запечатанный ключ нельзя перенести в запись с другим kid
+	moved := strings.Replace(stored, key.id, "00000000-0000-0000-0000-000000000000", 1)
+	_, err = k.parseStoredKey([]byte(moved))
+	assert.Error(t, err)
+}
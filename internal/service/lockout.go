@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	goredis "github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"time"
+)
+
+const (
+	signInFailuresKeyPrefix = "sign_in_failures:" // sign_in_failures:<user|ip>:<value> -> failed attempts in window
+	signInLockKeyPrefix     = "sign_in_lock:"     // sign_in_lock:<user|ip>:<value> -> unix time of unlock
+
+	lockoutByUser = "user:"
+	lockoutByIP   = "ip:"
+
+	// ограничение удвоений без максимума в конфиге, защищает duration от переполнения
+	maxLockoutDoublings = 20
+)
+
+type lockoutTarget struct {
+	key         string
+	maxFailures int
+}
+
+// lockoutTargets returns counters enabled in config, empty values (e.g. unknown ip) are not counted
+func (s *authService) lockoutTargets(username, ip string) []lockoutTarget {
+	var targets []lockoutTarget
+	if s.lockout.MaxUserFailures > 0 && username != "" {
+		targets = append(targets, lockoutTarget{key: lockoutByUser + username, maxFailures: s.lockout.MaxUserFailures})
+	}
+	if s.lockout.MaxIPFailures > 0 && ip != "" {
+		targets = append(targets, lockoutTarget{key: lockoutByIP + ip, maxFailures: s.lockout.MaxIPFailures})
+	}
+	return targets
+}
+
+// checkLockout returns *LockedError with the latest unlock time if username or ip is locked
+func (s *authService) checkLockout(ctx context.Context, username, ip string) error {
+	var until time.Time
+	for _, target := range s.lockoutTargets(username, ip) {
+		value, err := s.redis.Pool.Get(ctx, signInLockKeyPrefix+target.key).Result()
+		if err != nil {
+			if !errors.Is(err, goredis.Nil) {
+				// redis недоступен - вход не блокируем, пароль все равно проверяется
+				log.Errorf("%s/checkLockout error find lock: %s", authServicePrefixLog, err)
+			}
+			continue
+		}
+		unix, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		if t := time.Unix(unix, 0); t.After(until) {
+			until = t
+		}
+	}
+	if until.After(time.Now()) {
+		return &LockedError{Until: until}
+	}
+	return nil
+}
+
+// registerFailure counts failed attempt and locks sign-in when threshold is reached.
+// Each failure above threshold doubles lock duration
+func (s *authService) registerFailure(ctx context.Context, username, ip string) {
+	for _, target := range s.lockoutTargets(username, ip) {
+		failuresKey := signInFailuresKeyPrefix + target.key
+		failures, err := s.redis.Pool.Incr(ctx, failuresKey).Result()
+		if err != nil {
+			log.Errorf("%s/registerFailure error count failure: %s", authServicePrefixLog, err)
+			continue
+		}
+		ttl := s.lockout.FailureWindow
+		if excess := int(failures) - target.maxFailures; excess >= 0 && s.lockout.BaseLockout > 0 {
+			lock := s.lockoutDuration(excess)
+			until := time.Now().Add(lock)
+			if err = s.redis.Pool.Set(ctx, signInLockKeyPrefix+target.key, until.Unix(), lock).Err(); err != nil {
+				log.Errorf("%s/registerFailure error set lock: %s", authServicePrefixLog, err)
+			}
+			// счетчик переживает блокировку, чтобы следующая ошибка удвоила ее
+			ttl += lock
+		}
+		if err = s.redis.Pool.Expire(ctx, failuresKey, ttl).Err(); err != nil {
+			log.Errorf("%s/registerFailure error set failures ttl: %s", authServicePrefixLog, err)
+		}
+	}
+}
+
+// resetFailures forgets username failures after successful sign-in. Ip counter is kept, ip may be shared by many users
+func (s *authService) resetFailures(ctx context.Context, username string) {
+	if s.lockout.MaxUserFailures <= 0 {
+		return
+	}
+	if err := s.redis.Pool.Del(ctx, signInFailuresKeyPrefix+lockoutByUser+username).Err(); err != nil {
+		log.Errorf("%s/resetFailures error delete failures: %s", authServicePrefixLog, err)
+	}
+}
+
+func (s *authService) lockoutDuration(excess int) time.Duration {
+	lock := s.lockout.BaseLockout
+	for i := 0; i < excess && i < maxLockoutDoublings; i++ {
+		if s.lockout.MaxLockout > 0 && lock >= s.lockout.MaxLockout {
+			break
+		}
+		lock *= 2
+	}
+	if s.lockout.MaxLockout > 0 && lock > s.lockout.MaxLockout {
+		return s.lockout.MaxLockout
+	}
+	return lock
+}
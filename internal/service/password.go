@@ -0,0 +1,132 @@
+package service
+
+import (
+	"API_for_SN_go/internal/repo/pgerrs"
+	"API_for_SN_go/pkg/mailer"
+	"context"
+	"errors"
+	"fmt"
+	goredis "github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	passwordResetKeyPrefix = "password_reset:" // password_reset:<reset token hash> -> username
+
+	passwordResetSubject = "Password reset"
+)
+
+func (s *authService) ChangePassword(ctx context.Context, input PasswordChangeInput) error {
+	_, err := s.verifyPassword(ctx, input.Username, input.Password, input.IP)
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := s.hasher.Hash(input.NewPassword)
+	if err != nil {
+		log.Errorf("%s/ChangePassword error hash password: %s", authServicePrefixLog, err)
+		return ErrCannotUpdateUser
+	}
+	if err = s.userRepo.UpdatePassword(ctx, input.Username, hashedPassword); err != nil {
+		log.Errorf("%s/ChangePassword error update password: %s", authServicePrefixLog, err)
+		return ErrCannotUpdateUser
+	}
+	if err = s.revokeOtherSessions(ctx, input.Username, input.SessionId); err != nil {
+		log.Errorf("%s/ChangePassword error delete user sessions: %s", authServicePrefixLog, err)
+		return ErrCannotUpdateUser
+	}
+	// токены доступа выпущены со старым паролем и тоже отзываются
+	if err = s.accessTokenRepo.DeleteUserAccessTokens(ctx, input.Username); err != nil {
+		log.Errorf("%s/ChangePassword error delete access tokens: %s", authServicePrefixLog, err)
+		return ErrCannotUpdateUser
+	}
+	return nil
+}
+
+func (s *authService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return nil
+		}
+		log.Errorf("%s/RequestPasswordReset error find user: %s", authServicePrefixLog, err)
+		return ErrCannotResetPassword
+	}
+	token, err := newRandomToken()
+	if err != nil {
+		log.Errorf("%s/RequestPasswordReset error generate reset token: %s", authServicePrefixLog, err)
+		return ErrCannotResetPassword
+	}
+	if err = s.redis.Pool.Set(ctx, passwordResetKeyPrefix+hashToken(token), user.Username, s.resetTokenTTL).Err(); err != nil {
+		log.Errorf("%s/RequestPasswordReset error save reset token: %s", authServicePrefixLog, err)
+		return ErrCannotResetPassword
+	}
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: passwordResetSubject,
+		Body: fmt.Sprintf("Hi, %s!\n\nUse this token to reset your password: %s\nIt expires in %s. If you did not request a reset, ignore this message.",
+			user.Username, token, s.resetTokenTTL),
+	})
+	if err != nil {
+		log.Errorf("%s/RequestPasswordReset error send reset mail: %s", authServicePrefixLog, err)
+		return ErrCannotResetPassword
+	}
+	return nil
+}
+
+// ResetPassword sets new password by reset token and revokes all user sessions and personal access tokens. Token can be used only once
+func (s *authService) ResetPassword(ctx context.Context, input PasswordResetInput) error {
+	key := passwordResetKeyPrefix + hashToken(input.Token)
+	username, err := s.redis.Pool.Get(ctx, key).Result()
+	if err != nil {
+		if errors.Is(err, goredis.Nil) {
+			return ErrInvalidToken
+		}
+		log.Errorf("%s/ResetPassword error find reset token: %s", authServicePrefixLog, err)
+		return ErrCannotResetPassword
+	}
+	// хэш считается до удаления токена, чтобы при ошибке токен можно было использовать повторно
+	hashedPassword, err := s.hasher.Hash(input.NewPassword)
+	if err != nil {
+		log.Errorf("%s/ResetPassword error hash password: %s", authServicePrefixLog, err)
+		return ErrCannotResetPassword
+	}
+	// удаляет токен только один из параллельных запросов, остальные получают ошибку
+	deleted, err := s.redis.Pool.Del(ctx, key).Result()
+	if err != nil {
+		log.Errorf("%s/ResetPassword error delete reset token: %s", authServicePrefixLog, err)
+		return ErrCannotResetPassword
+	}
+	if deleted == 0 {
+		return ErrInvalidToken
+	}
+	if err = s.userRepo.UpdatePassword(ctx, username, hashedPassword); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		log.Errorf("%s/ResetPassword error update password: %s", authServicePrefixLog, err)
+		return ErrCannotResetPassword
+	}
+	if err = s.RevokeAllSessions(ctx, username); err != nil {
+		log.Errorf("%s/ResetPassword error delete user sessions: %s", authServicePrefixLog, err)
+		return ErrCannotResetPassword
+	}
+	if err = s.accessTokenRepo.DeleteUserAccessTokens(ctx, username); err != nil {
+		log.Errorf("%s/ResetPassword error delete access tokens: %s", authServicePrefixLog, err)
+		return ErrCannotResetPassword
+	}
+	return nil
+}
+
+func (s *authService) revokeOtherSessions(ctx context.Context, username, currentSessionId string) error {
+	ids, err := s.redis.Pool.SMembers(ctx, userSessionsKeyPrefix+username).Result()
+	if err != nil {
+		return err
+	}
+	other := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id != currentSessionId {
+			other = append(other, id)
+		}
+	}
+	return s.deleteSessions(ctx, username, other...)
+}
@@ -3,11 +3,13 @@ package service
 import (
 	"API_for_SN_go/internal/model/pgmodel"
 	"API_for_SN_go/internal/repo"
+	"API_for_SN_go/internal/repo/pagination"
 	"API_for_SN_go/internal/repo/pgerrs"
 	"context"
 	"errors"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
+	"time"
 )
 
 const (
@@ -15,11 +17,19 @@ const (
 )
 
 type postService struct {
-	postRepo repo.Post
+	postRepo     repo.Post
+	reactionRepo repo.Reaction
+	commentRepo  repo.Comment
+	events       *eventBus
 }
 
-func newPostService(postRepo repo.Post) *postService {
-	return &postService{postRepo: postRepo}
+func newPostService(postRepo repo.Post, reactionRepo repo.Reaction, commentRepo repo.Comment, events *eventBus) *postService {
+	return &postService{
+		postRepo:     postRepo,
+		reactionRepo: reactionRepo,
+		commentRepo:  commentRepo,
+		events:       events,
+	}
 }
 
 func (s *postService) CreatePost(ctx context.Context, input PostCreateInput) (string, error) {
@@ -54,3 +64,93 @@ func (s *postService) GetPostById(ctx context.Context, postId string) (pgmodel.P
 	}
 	return post, nil
 }
+
+func (s *postService) GetManyPosts(ctx context.Context, username string, p repo.Pagination) ([]pgmodel.Post, string, error) {
+	posts, next, err := s.postRepo.GetManyPosts(ctx, username, p)
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			return nil, "", ErrInvalidCursor
+		}
+		log.Errorf("%s/GetManyPosts error find posts by username: %s", postServicePrefixLog, err)
+		return nil, "", err
+	}
+	return posts, next, nil
+}
+
+func (s *postService) GetFeed(ctx context.Context, username string, p repo.Pagination) ([]FeedItem, string, error) {
+	posts, next, err := s.postRepo.GetFeed(ctx, username, p)
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			return nil, "", ErrInvalidCursor
+		}
+		log.Errorf("%s/GetFeed error find feed posts: %s", postServicePrefixLog, err)
+		return nil, "", err
+	}
+	if len(posts) == 0 {
+		return []FeedItem{}, next, nil
+	}
+
+	// счетчики для всей страницы получаем двумя запросами, а не по запросу на пост
+	postIds := make([]string, 0, len(posts))
+	for _, post := range posts {
+		postIds = append(postIds, post.PostId)
+	}
+	reactions, err := s.reactionRepo.CountReactionsByPosts(ctx, postIds)
+	if err != nil {
+		log.Errorf("%s/GetFeed error count reactions: %s", postServicePrefixLog, err)
+		return nil, "", err
+	}
+	comments, err := s.commentRepo.CountCommentsByPosts(ctx, postIds)
+	if err != nil {
+		log.Errorf("%s/GetFeed error count comments: %s", postServicePrefixLog, err)
+		return nil, "", err
+	}
+
+	items := make([]FeedItem, 0, len(posts))
+	for _, post := range posts {
+		r := reactions[post.PostId]
+		if r == nil {
+			r = map[string]int{}
+		}
+		items = append(items, FeedItem{
+			Post:          post,
+			Reactions:     r,
+			CommentsCount: comments[post.PostId],
+		})
+	}
+	return items, next, nil
+}
+
+func (s *postService) UpdatePost(ctx context.Context, input PostUpdateInput) error {
+	err := s.postRepo.UpdatePost(ctx, input.Username, input.PostId, input.Title, input.Text)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrPostNotFound
+		}
+		log.Errorf("%s/UpdatePost error update post: %s", postServicePrefixLog, err)
+		return ErrCannotUpdatePost
+	}
+	return nil
+}
+
+func (s *postService) DeletePost(ctx context.Context, input PostDeleteInput) error {
+	var (
+		deletedAt time.Time
+		err       error
+	)
+	if input.Moderate {
+		deletedAt, err = s.postRepo.DeletePostById(ctx, input.PostId)
+	} else {
+		deletedAt, err = s.postRepo.DeletePost(ctx, input.Username, input.PostId)
+	}
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrPostNotFound
+		}
+		log.Errorf("%s/DeletePost error delete post: %s", postServicePrefixLog, err)
+		return ErrCannotDeletePost
+	}
+	// комментарии и реакции удаляются вместе с постом, отдельных событий для них нет
+	s.events.publish(ctx, PostEvent{Type: EventPostDeleted, PostId: input.PostId, CreatedAt: deletedAt})
+	return nil
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"API_for_SN_go/internal/model/pgmodel"
 	"API_for_SN_go/internal/repo"
+	"API_for_SN_go/internal/repo/pagination"
 	"API_for_SN_go/internal/repo/pgerrs"
 	"context"
 	"errors"
@@ -14,18 +15,31 @@ const reactionServicePrefixLog = "/service/reaction"
 
 type reactionService struct {
 	reactionRepo repo.Reaction
+	events       *eventBus
 }
 
-func newReactionService(reactionRepo repo.Reaction) *reactionService {
-	return &reactionService{reactionRepo: reactionRepo}
+func newReactionService(reactionRepo repo.Reaction, events *eventBus) *reactionService {
+	return &reactionService{reactionRepo: reactionRepo, events: events}
 }
 
 func (s *reactionService) CreateReaction(ctx context.Context, input ReactionCreateInput) (string, error) {
-	reactionId := uuid.NewString()
-	err := s.reactionRepo.CreateReaction(ctx, pgmodel.Reaction{
+	// повторная такая же реакция снимается
+	removed, removedAt, err := s.reactionRepo.DeleteUserReaction(ctx, input.PostId, input.Username, input.Reaction)
+	if err == nil {
+		s.events.publish(ctx, PostEvent{Type: EventReactionDeleted, PostId: removed.PostId, Reaction: &removed, CreatedAt: removedAt})
+		return "", nil
+	}
+	if !errors.Is(err, pgerrs.ErrNotFound) {
+		log.Errorf("%s/CreateReaction error delete same reaction: %s", reactionServicePrefixLog, err)
+		return "", ErrCannotCreateReaction
+	}
+
+	// у пользователя может быть только одна реакция на пост, другая реакция заменяет ее
+	reaction, inserted, err := s.reactionRepo.UpsertReaction(ctx, pgmodel.Reaction{
 		PostId:     input.PostId,
-		ReactionId: reactionId,
+		ReactionId: uuid.NewString(),
 		Reaction:   input.Reaction,
+		Username:   input.Username,
 	})
 	if err != nil {
 		if errors.Is(err, pgerrs.ErrAlreadyExists) {
@@ -34,26 +48,39 @@ func (s *reactionService) CreateReaction(ctx context.Context, input ReactionCrea
 		if errors.Is(err, pgerrs.ErrForeignKey) {
 			return "", ErrPostNotFound // нарушение внешнего ключа возможно только если пост не существует
 		}
-		log.Errorf("%s/CreateReaction error create reaction: %s", reactionServicePrefixLog, err)
+		log.Errorf("%s/CreateReaction error save reaction: %s", reactionServicePrefixLog, err)
 		return "", ErrCannotCreateReaction
 	}
-	return reactionId, nil
+	eventType := EventReactionCreated
+	if !inserted {
+		eventType = EventReactionUpdated
+	}
+	s.events.publish(ctx, PostEvent{Type: eventType, PostId: reaction.PostId, Reaction: &reaction, CreatedAt: reaction.UpdatedAt})
+	return reaction.ReactionId, nil
 }
 
-func (s *reactionService) GetManyReactions(ctx context.Context, postId string) (map[string]string, error) {
-	reactions, err := s.reactionRepo.GetManyReactions(ctx, postId)
+func (s *reactionService) GetManyReactions(ctx context.Context, postId string, p repo.Pagination) ([]pgmodel.Reaction, string, error) {
+	reactions, next, err := s.reactionRepo.GetManyReactions(ctx, postId, p)
 	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			return nil, "", ErrInvalidCursor
+		}
 		if errors.Is(err, pgerrs.ErrNotFound) {
-			return nil, ErrReactionNotFound
+			return nil, "", ErrReactionNotFound
 		}
 		log.Errorf("%s/GetManyReactions error find reactions for post: %s", reactionServicePrefixLog, err)
-		return nil, ErrReactionNotFound
+		return nil, "", ErrCannotGetReactions
 	}
-	res := make(map[string]string)
-	for _, reaction := range reactions {
-		res[reaction.ReactionId] = reaction.Reaction
+	return reactions, next, nil
+}
+
+func (s *reactionService) GetReactionSummary(ctx context.Context, postId string) (map[string]int, error) {
+	counts, err := s.reactionRepo.CountReactions(ctx, postId)
+	if err != nil {
+		log.Errorf("%s/GetReactionSummary error count reactions for post: %s", reactionServicePrefixLog, err)
+		return nil, err
 	}
-	return res, nil
+	return counts, nil
 }
 
 func (s *reactionService) GetReactionById(ctx context.Context, reactionId string) (pgmodel.Reaction, error) {
@@ -68,14 +95,27 @@ func (s *reactionService) GetReactionById(ctx context.Context, reactionId string
 	return reaction, nil
 }
 
-func (s *reactionService) DeleteReaction(ctx context.Context, reactionId string) error {
-	err := s.reactionRepo.DeleteReaction(ctx, reactionId)
+func (s *reactionService) GetUserReaction(ctx context.Context, postId, username string) (pgmodel.Reaction, error) {
+	reaction, err := s.reactionRepo.GetUserReaction(ctx, postId, username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return pgmodel.Reaction{}, ErrReactionNotFound
+		}
+		log.Errorf("%s/GetUserReaction error find user reaction: %s", reactionServicePrefixLog, err)
+		return pgmodel.Reaction{}, err
+	}
+	return reaction, nil
+}
+
+func (s *reactionService) DeleteReaction(ctx context.Context, input ReactionDeleteInput) error {
+	reaction, deletedAt, err := s.reactionRepo.DeleteReaction(ctx, input.Username, input.ReactionId)
 	if err != nil {
 		if errors.Is(err, pgerrs.ErrNotFound) {
 			return ErrReactionNotFound
 		}
 		log.Errorf("%s/DeleteReaction error delete reaction: %s", reactionServicePrefixLog, err)
-		return err
+		return ErrCannotDeleteReaction
 	}
+	s.events.publish(ctx, PostEvent{Type: EventReactionDeleted, PostId: reaction.PostId, Reaction: &reaction, CreatedAt: deletedAt})
 	return nil
 }
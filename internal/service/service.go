@@ -4,11 +4,20 @@ import (
 	"API_for_SN_go/internal/model/pgmodel"
 	"API_for_SN_go/internal/repo"
 	"API_for_SN_go/pkg/hasher"
+	"API_for_SN_go/pkg/jwk"
+	"API_for_SN_go/pkg/mailer"
 	"API_for_SN_go/pkg/redis"
 	"context"
 	"time"
 )
 
+// User roles. Moderators can delete content of any user, admins can also manage user roles
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
 type (
 	UserCreateInput struct {
 		Username  string
@@ -20,33 +29,167 @@ type (
 	UserAuthInput struct {
 		Username string
 		Password string
+		Device   string
+		IP       string
 	}
 	UserDeleteInput struct {
 		Username string
 		Password string
+		IP       string
 	}
 	UpdateUsernameInput struct {
 		Username    string
 		NewUsername string
 		Password    string
+		IP          string
 	}
 	UserUpdateFullNameInput struct {
 		Username  string
 		FirstName string
 		LastName  string
 	}
+	UserUpdateRoleInput struct {
+		Username string
+		Role     string
+	}
+	Tokens struct {
+		AccessToken  string
+		RefreshToken string
+		// ChallengeToken is set instead of access and refresh tokens when user has to pass two-factor auth
+		ChallengeToken string
+	}
+	Session struct {
+		Id          string    `json:"id"`
+		Username    string    `json:"username"`
+		Device      string    `json:"device"`
+		IP          string    `json:"ip"`
+		CreatedAt   time.Time `json:"created_at"`
+		LastSeenAt  time.Time `json:"last_seen_at"`
+		RefreshHash string    `json:"refresh_hash"`
+	}
+	SessionRevokeInput struct {
+		Username  string
+		SessionId string
+	}
+	PasswordChangeInput struct {
+		Username    string
+		SessionId   string
+		Password    string
+		NewPassword string
+		IP          string
+	}
+	EmailChangeInput struct {
+		Username string
+		Password string
+		NewEmail string
+		IP       string
+	}
+	TOTPEnrollment struct {
+		Secret        string
+		URI           string
+		RecoveryCodes []string
+	}
+	TOTPConfirmInput struct {
+		Username string
+		Code     string
+	}
+	TOTPDisableInput struct {
+		Username string
+		Password string
+		IP       string
+	}
+	TOTPVerifyInput struct {
+		ChallengeToken string
+		// Code is a current authenticator code or one of recovery codes
+		Code   string
+		Device string
+		IP     string
+	}
+	AccessTokenCreateInput struct {
+		Username string
+		Name     string
+		Scopes   []string
+		// TTL of the token, zero TTL - token does not expire
+		TTL time.Duration
+	}
+	AccessTokenRevokeInput struct {
+		Username string
+		TokenId  string
+	}
+	PasswordResetInput struct {
+		Token       string
+		NewPassword string
+	}
 	Auth interface {
-		CreateToken(ctx context.Context, input UserAuthInput) (string, error)
-		ValidateToken(ctx context.Context, token string) (string, error)
-		RefreshToken(ctx context.Context, token string) (string, error)
+		// CreateToken signs user in. If user has enabled two-factor auth, only Tokens.ChallengeToken is set,
+		// sign-in is finished by VerifyTOTP. Repeated failures lock sign-in by username and ip with *LockedError
+		CreateToken(ctx context.Context, input UserAuthInput) (Tokens, error)
+		ValidateToken(ctx context.Context, token string) (*TokenClaims, error)
+		// JWKS returns public keys which verify access tokens. Set is empty for HS256 signing
+		JWKS(ctx context.Context) (jwk.Set, error)
+		RefreshToken(ctx context.Context, refreshToken string) (Tokens, error)
+		SignOut(ctx context.Context, token string) error
+		// RevokeToken ends session of access token or deletes personal access token
+		RevokeToken(ctx context.Context, token string) error
+
+		GetSessions(ctx context.Context, username string) ([]Session, error)
+		RevokeSession(ctx context.Context, input SessionRevokeInput) error
+		RevokeAllSessions(ctx context.Context, username string) error
 
 		CreateUser(ctx context.Context, input UserCreateInput) error
 		DeleteUser(ctx context.Context, input UserDeleteInput) error
 		UpdateUsername(ctx context.Context, input UpdateUsernameInput) error
+
+		// ChangePassword sets new password and revokes all user sessions except the current one and all personal access tokens
+		ChangePassword(ctx context.Context, input PasswordChangeInput) error
+		// RequestPasswordReset mails single-use reset token to the user with this email.
+		// Unknown email is not an error, so the method does not reveal registered addresses
+		RequestPasswordReset(ctx context.Context, email string) error
+		ResetPassword(ctx context.Context, input PasswordResetInput) error
+
+		// SendEmailConfirmation mails signed link which confirms the current user email
+		SendEmailConfirmation(ctx context.Context, username string) error
+		ConfirmEmail(ctx context.Context, token string) error
+		// ChangeEmail stores new email as pending and mails confirmation link to it.
+		// Email is replaced only after the link is confirmed
+		ChangeEmail(ctx context.Context, input EmailChangeInput) error
+
+		// EnrollTOTP generates new two-factor secret and recovery codes. Two-factor auth is enabled after ConfirmTOTP
+		EnrollTOTP(ctx context.Context, username string) (TOTPEnrollment, error)
+		ConfirmTOTP(ctx context.Context, input TOTPConfirmInput) error
+		DisableTOTP(ctx context.Context, input TOTPDisableInput) error
+		VerifyTOTP(ctx context.Context, input TOTPVerifyInput) (Tokens, error)
+
+		// CreateAccessToken returns new personal access token with its info. Only token hash is stored, so it cannot be shown again
+		CreateAccessToken(ctx context.Context, input AccessTokenCreateInput) (string, pgmodel.AccessToken, error)
+		GetAccessTokens(ctx context.Context, username string) ([]pgmodel.AccessToken, error)
+		RevokeAccessToken(ctx context.Context, input AccessTokenRevokeInput) error
 	}
 	User interface {
 		UpdateFullName(ctx context.Context, input UserUpdateFullNameInput) error
 		GetUserByUsername(ctx context.Context, username string) (pgmodel.User, error)
+		// UpdateRole changes user role. Issued access tokens keep the old role until they are refreshed
+		UpdateRole(ctx context.Context, input UserUpdateRoleInput) error
+	}
+)
+
+type (
+	// AdminUserInput is admin action on user account
+	AdminUserInput struct {
+		Admin    string
+		Username string
+	}
+	Admin interface {
+		// GetUsers returns users which username, name or email contains query (all users for empty query), oldest first by default
+		GetUsers(ctx context.Context, query string, p repo.Pagination) ([]pgmodel.User, string, error)
+		GetUser(ctx context.Context, username string) (pgmodel.User, error)
+		// SuspendUser forbids sign-in and revokes all user sessions until UnsuspendUser
+		SuspendUser(ctx context.Context, input AdminUserInput) error
+		UnsuspendUser(ctx context.Context, input AdminUserInput) error
+		// SignOutUser revokes all user sessions and personal access tokens
+		SignOutUser(ctx context.Context, input AdminUserInput) error
+		// DeleteUser deletes user with all content without password confirmation
+		DeleteUser(ctx context.Context, input AdminUserInput) error
 	}
 )
 
@@ -56,22 +199,53 @@ type (
 		Title    string
 		Text     string
 	}
+	PostUpdateInput struct {
+		Username string
+		PostId   string
+		Title    string
+		Text     string
+	}
+	PostDeleteInput struct {
+		Username string
+		PostId   string
+		// Moderate allows to delete post of any author
+		Moderate bool
+	}
+	FeedItem struct {
+		pgmodel.Post
+		Reactions     map[string]int
+		CommentsCount int
+	}
 	Post interface {
 		CreatePost(ctx context.Context, input PostCreateInput) (string, error)
 		GetPostById(ctx context.Context, postId string) (pgmodel.Post, error)
+		GetManyPosts(ctx context.Context, username string, p repo.Pagination) ([]pgmodel.Post, string, error)
+		// GetFeed returns posts of followed users and user own posts, newest first by default
+		GetFeed(ctx context.Context, username string, p repo.Pagination) ([]FeedItem, string, error)
+		UpdatePost(ctx context.Context, input PostUpdateInput) error
+		DeletePost(ctx context.Context, input PostDeleteInput) error
 	}
 )
 
 type (
 	ReactionCreateInput struct {
+		Username string
 		PostId   string
 		Reaction string
 	}
+	ReactionDeleteInput struct {
+		Username   string
+		ReactionId string
+	}
 	Reaction interface {
+		// CreateReaction sets user reaction for post, replacing previous one with the same reaction id.
+		// Repeated same reaction removes it, in this case returned reaction id is empty
 		CreateReaction(ctx context.Context, input ReactionCreateInput) (string, error)
-		GetManyReactions(ctx context.Context, postId string) (map[string]string, error)
+		GetManyReactions(ctx context.Context, postId string, p repo.Pagination) ([]pgmodel.Reaction, string, error)
+		GetReactionSummary(ctx context.Context, postId string) (map[string]int, error)
 		GetReactionById(ctx context.Context, reactionId string) (pgmodel.Reaction, error)
-		DeleteReaction(ctx context.Context, reactionId string) error
+		GetUserReaction(ctx context.Context, postId, username string) (pgmodel.Reaction, error)
+		DeleteReaction(ctx context.Context, input ReactionDeleteInput) error
 	}
 )
 
@@ -79,8 +253,13 @@ type (
 	CommentCreateInput struct {
 		Username string
 		PostId   string
+		ParentId string
 		Comment  string
 	}
+	CommentNode struct {
+		pgmodel.Comment
+		Replies []CommentNode
+	}
 	CommentUpdateInput struct {
 		Username   string
 		CommentId  string
@@ -89,16 +268,48 @@ type (
 	CommentDeleteInput struct {
 		Username  string
 		CommentId string
+		// Moderate allows to delete comment of any author
+		Moderate bool
 	}
 	Comment interface {
 		CreateComment(ctx context.Context, input CommentCreateInput) (string, error)
 		GetCommentById(ctx context.Context, commentId string) (pgmodel.Comment, error)
-		GetManyComments(ctx context.Context, filter, filterParams string) (map[string]string, error)
+		// GetManyComments and GetCommentTree return page of comments and cursor of the next page (empty if it is the last one).
+		// Tree is paginated by root comments
+		GetManyComments(ctx context.Context, filter, filterParams string, p repo.Pagination) ([]pgmodel.Comment, string, error)
+		GetCommentTree(ctx context.Context, postId string, depth int, p repo.Pagination) ([]CommentNode, string, error)
 		UpdateComment(ctx context.Context, input CommentUpdateInput) error
 		DeleteComment(ctx context.Context, input CommentDeleteInput) error
 	}
 )
 
+type (
+	FollowInput struct {
+		Follower string
+		Followee string
+	}
+	FollowCounts struct {
+		Followers int
+		Following int
+	}
+	Follow interface {
+		Follow(ctx context.Context, input FollowInput) error
+		Unfollow(ctx context.Context, input FollowInput) error
+		GetFollowers(ctx context.Context, username string, p repo.Pagination) ([]pgmodel.Follow, string, error)
+		GetFollowing(ctx context.Context, username string, p repo.Pagination) ([]pgmodel.Follow, string, error)
+		GetFollowCounts(ctx context.Context, username string) (FollowCounts, error)
+	}
+)
+
+type (
+	PostEvents interface {
+		// WatchPost returns events of post comments and reactions made on any instance. Channel is closed when ctx is done,
+		// after post_deleted event, which is the last event of the post, or when the watcher does not keep up with events,
+		// in this case it should reload the post and watch again
+		WatchPost(ctx context.Context, postId string) (<-chan PostEvent, error)
+	}
+)
+
 type (
 	Services struct {
 		Auth     Auth
@@ -106,22 +317,64 @@ type (
 		Post     Post
 		Reaction Reaction
 		Comment  Comment
+		Follow   Follow
+		Admin    Admin
+		Events   PostEvents
 	}
 	ServicesDependencies struct {
-		Repos    *repo.Repositories
-		Hasher   hasher.PasswordHasher
-		Redis    *redis.Redis
-		SignKey  string
-		TokenTTL time.Duration
+		Repos         *repo.Repositories
+		Hasher        hasher.PasswordHasher
+		Redis         *redis.Redis
+		Mailer        mailer.Mailer
+		SignKey       string
+		SignAlgorithm string
+		// KeyEncryptionKey is AES-256 key for signing keys of RS256 and EdDSA
+		KeyEncryptionKey []byte
+		KeyRotation      time.Duration
+		TokenTTL         time.Duration
+		RefreshTokenTTL  time.Duration
+		ResetTokenTTL    time.Duration
+		EmailTokenTTL    time.Duration
+		PublicURL        string
+		TOTPIssuer       string
+		ChallengeTTL     time.Duration
+		Lockout          LockoutConfig
+	}
+	// LockoutConfig sets sign-in brute-force protection. Zero max failures disables counting by username or ip
+	LockoutConfig struct {
+		MaxUserFailures int
+		MaxIPFailures   int
+		FailureWindow   time.Duration
+		BaseLockout     time.Duration
+		MaxLockout      time.Duration
 	}
 )
 
 func NewServices(d ServicesDependencies) *Services {
+	authCfg := authConfig{
+		signKey:          d.SignKey,
+		signAlgorithm:    d.SignAlgorithm,
+		keyEncryptionKey: d.KeyEncryptionKey,
+		keyRotation:      d.KeyRotation,
+		tokenTTL:         d.TokenTTL,
+		refreshTokenTTL:  d.RefreshTokenTTL,
+		resetTokenTTL:    d.ResetTokenTTL,
+		emailTokenTTL:    d.EmailTokenTTL,
+		publicURL:        d.PublicURL,
+		totpIssuer:       d.TOTPIssuer,
+		challengeTTL:     d.ChallengeTTL,
+		lockout:          d.Lockout,
+	}
+	auth := newAuthService(d.Repos.User, d.Repos.AccessToken, d.Hasher, d.Redis, d.Mailer, authCfg)
+	events := newEventBus(d.Redis)
 	return &Services{
-		Auth:     newAuthService(d.Repos.User, d.Hasher, d.Redis, d.SignKey, d.TokenTTL),
+		Auth:     auth,
 		User:     newUserService(d.Repos.User),
-		Post:     newPostService(d.Repos.Post),
-		Reaction: newReactionService(d.Repos.Reaction),
-		Comment:  newCommentService(d.Repos.Comment),
+		Post:     newPostService(d.Repos.Post, d.Repos.Reaction, d.Repos.Comment, events),
+		Reaction: newReactionService(d.Repos.Reaction, events),
+		Comment:  newCommentService(d.Repos.Comment, events),
+		Follow:   newFollowService(d.Repos.Follow),
+		Admin:    newAdminService(d.Repos.User, auth),
+		Events:   events,
 	}
 }
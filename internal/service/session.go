@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"time"
+)
+
+const (
+	sessionKeyPrefix      = "session:"      // session:<session id> -> session info
+	userSessionsKeyPrefix = "sessions:"     // sessions:<username> -> set of user session ids
+	refreshKeyPrefix      = "refresh:"      // refresh:<refresh token hash> -> session id
+	usedRefreshKeyPrefix  = "refresh_used:" // refresh_used:<refresh token hash> -> session id, exchanged refresh tokens
+
+	randomTokenLength = 32
+
+	// last seen обновляется не чаще раза в минуту, чтобы не писать в redis на каждый запрос
+	lastSeenUpdateInterval = time.Minute
+)
+
+// touchSessionScript sets last_seen_at of session only if it still exists with the same refresh token hash,
+// so revoked session is not recreated and just rotated refresh token hash is not overwritten.
+// KEYS[1] - session key, ARGV[1] - refresh token hash, ARGV[2] - last seen time
+const touchSessionScript = `
+local data = redis.call('GET', KEYS[1])
+if not data then
+	return 0
+end
+local session = cjson.decode(data)
+if session.refresh_hash ~= ARGV[1] then
+	return 0
+end
+session.last_seen_at = ARGV[2]
+redis.call('SET', KEYS[1], cjson.encode(session), 'KEEPTTL')
+return 1
+`
+
+// rotateSessionScript replaces session only if it still exists with the previous refresh token hash,
+// so session revoked during refresh is not recreated.
+// KEYS[1] - session key, ARGV[1] - previous refresh token hash, ARGV[2] - session, ARGV[3] - ttl in milliseconds
+const rotateSessionScript = `
+local data = redis.call('GET', KEYS[1])
+if not data or cjson.decode(data).refresh_hash ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1
+`
+
+func newSession(username, device, ip string) Session {
+	now := time.Now()
+	return Session{
+		Id:         uuid.NewString(),
+		Username:   username,
+		Device:     device,
+		IP:         ip,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+}
+
+// issueTokens rotates session refresh token and signs new access token. Session lives as long as its refresh token.
+// Existing session is saved only if it is not revoked and its refresh token is not rotated by another request
+func (s *authService) issueTokens(ctx context.Context, session Session, role string) (Tokens, error) {
+	refreshToken, err := newRandomToken()
+	if err != nil {
+		log.Errorf("%s/issueTokens error generate refresh token: %s", authServicePrefixLog, err)
+		return Tokens{}, ErrCannotCreateToken
+	}
+
+	// в redis хранится только хэш, обмененные хэши остаются до истечения ttl для обнаружения повторного использования
+	previousHash := session.RefreshHash
+	session.RefreshHash = hashToken(refreshToken)
+	saved := true
+	if previousHash == "" {
+		err = s.saveSession(ctx, session, s.refreshTokenTTL)
+	} else {
+		saved, err = s.rotateSession(ctx, session, previousHash)
+	}
+	if err != nil {
+		log.Errorf("%s/issueTokens error save session: %s", authServicePrefixLog, err)
+		return Tokens{}, ErrCannotCreateToken
+	}
+	if !saved {
+		return Tokens{}, ErrExpiredToken
+	}
+	if err = s.redis.Pool.Set(ctx, refreshKeyPrefix+session.RefreshHash, session.Id, s.refreshTokenTTL).Err(); err != nil {
+		log.Errorf("%s/issueTokens error save refresh token: %s", authServicePrefixLog, err)
+		return Tokens{}, ErrCannotCreateToken
+	}
+	accessToken, err := s.generateToken(ctx, session.Username, role, session.Id)
+	if err != nil {
+		return Tokens{}, err
+	}
+	return Tokens{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// newRandomToken returns url-safe opaque token, used for refresh and password reset tokens
+func newRandomToken() (string, error) {
+	b := make([]byte, randomTokenLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is used to store opaque tokens, so leaked redis data cannot be used as credentials
+func hashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+// saveSession stores new session with ttl
+func (s *authService) saveSession(ctx context.Context, session Session, ttl time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	if err = s.redis.Pool.Set(ctx, sessionKeyPrefix+session.Id, data, ttl).Err(); err != nil {
+		return err
+	}
+	return s.addUserSession(ctx, session, ttl)
+}
+
+// rotateSession stores session with new refresh token hash. It returns false if session is revoked
+// or its refresh token is already rotated
+func (s *authService) rotateSession(ctx context.Context, session Session, previousHash string) (bool, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return false, err
+	}
+	saved, err := s.redis.Pool.Eval(ctx, rotateSessionScript, []string{sessionKeyPrefix + session.Id},
+		previousHash, data, s.refreshTokenTTL.Milliseconds()).Bool()
+	if err != nil || !saved {
+		return false, err
+	}
+	return true, s.addUserSession(ctx, session, s.refreshTokenTTL)
+}
+
+func (s *authService) addUserSession(ctx context.Context, session Session, ttl time.Duration) error {
+	userKey := userSessionsKeyPrefix + session.Username
+	if err := s.redis.Pool.SAdd(ctx, userKey, session.Id).Err(); err != nil {
+		return err
+	}
+	// множество живет не меньше самой долгой сессии пользователя
+	return s.redis.Pool.Expire(ctx, userKey, ttl).Err()
+}
+
+func (s *authService) getSession(ctx context.Context, sessionId string) (Session, error) {
+	data, err := s.redis.Pool.Get(ctx, sessionKeyPrefix+sessionId).Bytes()
+	if err != nil {
+		if errors.Is(err, goredis.Nil) {
+			return Session{}, ErrSessionNotFound
+		}
+		return Session{}, err
+	}
+	var session Session
+	if err = json.Unmarshal(data, &session); err != nil {
+		return Session{}, err
+	}
+	return session, nil
+}
+
+func (s *authService) touchSession(ctx context.Context, session Session) {
+	if time.Since(session.LastSeenAt) < lastSeenUpdateInterval {
+		return
+	}
+	// меняется только last seen, остальные поля сессии могли измениться после чтения
+	lastSeen := time.Now().Format(time.RFC3339Nano)
+	err := s.redis.Pool.Eval(ctx, touchSessionScript, []string{sessionKeyPrefix + session.Id}, session.RefreshHash, lastSeen).Err()
+	if err != nil {
+		log.Errorf("%s/touchSession error update session last seen: %s", authServicePrefixLog, err)
+	}
+}
+
+func (s *authService) deleteSessions(ctx context.Context, username string, sessionIds ...string) error {
+	if len(sessionIds) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, 2*len(sessionIds))
+	members := make([]interface{}, 0, len(sessionIds))
+	for _, id := range sessionIds {
+		keys = append(keys, sessionKeyPrefix+id)
+		members = append(members, id)
+		// refresh токен удаляется вместе с сессией, чтобы по нему нельзя было восстановить сессию
+		session, err := s.getSession(ctx, id)
+		if err != nil {
+			if errors.Is(err, ErrSessionNotFound) {
+				continue
+			}
+			return err
+		}
+		if session.RefreshHash != "" {
+			keys = append(keys, refreshKeyPrefix+session.RefreshHash)
+		}
+	}
+	if err := s.redis.Pool.Del(ctx, keys...).Err(); err != nil {
+		return err
+	}
+	return s.redis.Pool.SRem(ctx, userSessionsKeyPrefix+username, members...).Err()
+}
+
+func (s *authService) GetSessions(ctx context.Context, username string) ([]Session, error) {
+	ids, err := s.redis.Pool.SMembers(ctx, userSessionsKeyPrefix+username).Result()
+	if err != nil {
+		log.Errorf("%s/GetSessions error find user sessions: %s", authServicePrefixLog, err)
+		return nil, err
+	}
+	sessions := make([]Session, 0, len(ids))
+	var expired []interface{}
+	for _, id := range ids {
+		session, err := s.getSession(ctx, id)
+		if err != nil {
+			if errors.Is(err, ErrSessionNotFound) {
+				expired = append(expired, id)
+				continue
+			}
+			log.Errorf("%s/GetSessions error find session: %s", authServicePrefixLog, err)
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	// истекшие по ttl сессии остаются в множестве, убираем их
+	if len(expired) != 0 {
+		if err = s.redis.Pool.SRem(ctx, userSessionsKeyPrefix+username, expired...).Err(); err != nil {
+			log.Errorf("%s/GetSessions error remove expired sessions: %s", authServicePrefixLog, err)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
+
+func (s *authService) RevokeSession(ctx context.Context, input SessionRevokeInput) error {
+	session, err := s.getSession(ctx, input.SessionId)
+	if err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			return ErrSessionNotFound
+		}
+		log.Errorf("%s/RevokeSession error find session: %s", authServicePrefixLog, err)
+		return ErrCannotRevokeSession
+	}
+	// чужая сессия для пользователя не существует
+	if session.Username != input.Username {
+		return ErrSessionNotFound
+	}
+	if err = s.deleteSessions(ctx, input.Username, input.SessionId); err != nil {
+		log.Errorf("%s/RevokeSession error delete session: %s", authServicePrefixLog, err)
+		return ErrCannotRevokeSession
+	}
+	return nil
+}
+
+// SignOut ends session of the token, so the token and session refresh token stop working immediately.
+// It is used by sign-out of both REST and grpc api
+func (s *authService) SignOut(ctx context.Context, token string) error {
+	claims, err := s.ValidateToken(ctx, token)
+	if err != nil {
+		return err
+	}
+	if claims.AccessTokenId != "" {
+		return ErrAccessTokenNotAllowed
+	}
+	if err = s.deleteSessions(ctx, claims.Username, claims.SessionId); err != nil {
+		log.Errorf("%s/SignOut error delete session: %s", authServicePrefixLog, err)
+		return ErrCannotRevokeSession
+	}
+	return nil
+}
+
+func (s *authService) RevokeToken(ctx context.Context, token string) error {
+	claims, err := s.ValidateToken(ctx, token)
+	if err != nil {
+		return err
+	}
+	if claims.AccessTokenId != "" {
+		return s.RevokeAccessToken(ctx, AccessTokenRevokeInput{Username: claims.Username, TokenId: claims.AccessTokenId})
+	}
+	if err = s.deleteSessions(ctx, claims.Username, claims.SessionId); err != nil {
+		log.Errorf("%s/RevokeToken error delete session: %s", authServicePrefixLog, err)
+		return ErrCannotRevokeSession
+	}
+	return nil
+}
+
+func (s *authService) RevokeAllSessions(ctx context.Context, username string) error {
+	ids, err := s.redis.Pool.SMembers(ctx, userSessionsKeyPrefix+username).Result()
+	if err != nil {
+		log.Errorf("%s/RevokeAllSessions error find user sessions: %s", authServicePrefixLog, err)
+		return ErrCannotRevokeSession
+	}
+	if err = s.deleteSessions(ctx, username, ids...); err != nil {
+		log.Errorf("%s/RevokeAllSessions error delete sessions: %s", authServicePrefixLog, err)
+		return ErrCannotRevokeSession
+	}
+	return nil
+}
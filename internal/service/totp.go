@@ -0,0 +1,244 @@
+package service
+
+import (
+	"API_for_SN_go/internal/repo/pgerrs"
+	"API_for_SN_go/pkg/totp"
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+const (
+	totpChallengeAudience = "totp_challenge"
+
+	totpAttemptsKeyPrefix = "totp_attempts:" // totp_attempts:<challenge id> -> number of verify attempts
+	totpUsedKeyPrefix     = "totp_used:"     // totp_used:<username>:<code> -> code already used
+
+	maxTOTPAttempts = 5
+	// код действителен в текущем и соседних 30-секундных окнах
+	totpUsedTTL = 90 * time.Second
+
+	recoveryCodesCount  = 10
+	recoveryCodeSize    = 5 // bytes, 8 base32 characters
+	recoveryCodeDivider = 4
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func (s *authService) EnrollTOTP(ctx context.Context, username string) (TOTPEnrollment, error) {
+	user, err := s.userRepo.GetUserByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return TOTPEnrollment{}, ErrUserNotFound
+		}
+		log.Errorf("%s/EnrollTOTP error find user: %s", authServicePrefixLog, err)
+		return TOTPEnrollment{}, ErrCannotUpdateTOTP
+	}
+	if user.TOTPEnabled {
+		return TOTPEnrollment{}, ErrTOTPAlreadyEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		log.Errorf("%s/EnrollTOTP error generate secret: %s", authServicePrefixLog, err)
+		return TOTPEnrollment{}, ErrCannotUpdateTOTP
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		log.Errorf("%s/EnrollTOTP error generate recovery codes: %s", authServicePrefixLog, err)
+		return TOTPEnrollment{}, ErrCannotUpdateTOTP
+	}
+	if err = s.userRepo.SetTOTP(ctx, username, secret, hashes); err != nil {
+		log.Errorf("%s/EnrollTOTP error save secret: %s", authServicePrefixLog, err)
+		return TOTPEnrollment{}, ErrCannotUpdateTOTP
+	}
+	return TOTPEnrollment{
+		Secret:        secret,
+		URI:           totp.URI(s.totpIssuer, username, secret),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+func (s *authService) ConfirmTOTP(ctx context.Context, input TOTPConfirmInput) error {
+	user, err := s.userRepo.GetUserByUsername(ctx, input.Username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		log.Errorf("%s/ConfirmTOTP error find user: %s", authServicePrefixLog, err)
+		return ErrCannotUpdateTOTP
+	}
+	if user.TOTPEnabled {
+		return ErrTOTPAlreadyEnabled
+	}
+	if user.TOTPSecret == nil {
+		return ErrTOTPNotEnrolled
+	}
+	ok, err := s.useTOTPCode(ctx, input.Username, *user.TOTPSecret, input.Code)
+	if err != nil {
+		log.Errorf("%s/ConfirmTOTP error check code: %s", authServicePrefixLog, err)
+		return ErrCannotUpdateTOTP
+	}
+	if !ok {
+		return ErrInvalidTOTPCode
+	}
+	if err = s.userRepo.EnableTOTP(ctx, input.Username); err != nil {
+		log.Errorf("%s/ConfirmTOTP error enable totp: %s", authServicePrefixLog, err)
+		return ErrCannotUpdateTOTP
+	}
+	return nil
+}
+
+func (s *authService) DisableTOTP(ctx context.Context, input TOTPDisableInput) error {
+	user, err := s.verifyPassword(ctx, input.Username, input.Password, input.IP)
+	if err != nil {
+		return err
+	}
+	if user.TOTPSecret == nil {
+		return ErrTOTPNotEnabled
+	}
+	if err = s.userRepo.DisableTOTP(ctx, input.Username); err != nil {
+		log.Errorf("%s/DisableTOTP error disable totp: %s", authServicePrefixLog, err)
+		return ErrCannotUpdateTOTP
+	}
+	return nil
+}
+
+// VerifyTOTP finishes sign-in started by CreateToken. Challenge allows limited number of attempts,
+// invalid codes are also counted by sign-in lockout
+func (s *authService) VerifyTOTP(ctx context.Context, input TOTPVerifyInput) (Tokens, error) {
+	claims, err := s.parseChallengeToken(input.ChallengeToken)
+	if err != nil {
+		return Tokens{}, err
+	}
+	if err = s.checkLockout(ctx, claims.Subject, input.IP); err != nil {
+		return Tokens{}, err
+	}
+	attemptsKey := totpAttemptsKeyPrefix + claims.Id
+	attempts, err := s.redis.Pool.Incr(ctx, attemptsKey).Result()
+	if err != nil {
+		log.Errorf("%s/VerifyTOTP error count attempts: %s", authServicePrefixLog, err)
+		return Tokens{}, ErrCannotCreateToken
+	}
+	if attempts == 1 {
+		if err = s.redis.Pool.Expire(ctx, attemptsKey, s.challengeTTL).Err(); err != nil {
+			log.Errorf("%s/VerifyTOTP error set attempts ttl: %s", authServicePrefixLog, err)
+		}
+	}
+	if attempts > maxTOTPAttempts {
+		return Tokens{}, ErrTooManyTOTPAttempts
+	}
+
+	user, err := s.userRepo.GetUserByUsername(ctx, claims.Subject)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return Tokens{}, ErrInvalidToken
+		}
+		log.Errorf("%s/VerifyTOTP error find user: %s", authServicePrefixLog, err)
+		return Tokens{}, ErrCannotCreateToken
+	}
+	if !user.TOTPEnabled || user.TOTPSecret == nil {
+		return Tokens{}, ErrInvalidToken
+	}
+	if user.SuspendedAt != nil {
+		return Tokens{}, ErrUserSuspended
+	}
+	if ok, err := s.checkSecondFactor(ctx, user.Username, *user.TOTPSecret, input.Code); err != nil || !ok {
+		if err != nil {
+			log.Errorf("%s/VerifyTOTP error check code: %s", authServicePrefixLog, err)
+			return Tokens{}, ErrCannotCreateToken
+		}
+		s.registerFailure(ctx, user.Username, input.IP)
+		return Tokens{}, ErrInvalidTOTPCode
+	}
+	s.resetFailures(ctx, user.Username)
+	// challenge одноразовый: после успеха попытки исчерпаны
+	if err = s.redis.Pool.Set(ctx, attemptsKey, maxTOTPAttempts, goredis.KeepTTL).Err(); err != nil {
+		log.Errorf("%s/VerifyTOTP error close challenge: %s", authServicePrefixLog, err)
+		return Tokens{}, ErrCannotCreateToken
+	}
+	return s.issueTokens(ctx, newSession(user.Username, input.Device, input.IP), user.Role)
+}
+
+// checkSecondFactor accepts authenticator code once or unused recovery code
+func (s *authService) checkSecondFactor(ctx context.Context, username, secret, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if ok, err := s.useTOTPCode(ctx, username, secret, code); ok || err != nil {
+		return ok, err
+	}
+	err := s.userRepo.UseRecoveryCode(ctx, username, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// useTOTPCode validates authenticator code. Same code cannot be used twice while it is valid
+func (s *authService) useTOTPCode(ctx context.Context, username, secret, code string) (bool, error) {
+	if !totp.Validate(secret, code, time.Now()) {
+		return false, nil
+	}
+	return s.redis.Pool.SetNX(ctx, totpUsedKeyPrefix+username+":"+code, 1, totpUsedTTL).Result()
+}
+
+func (s *authService) newChallengeToken(username string) (string, error) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.StandardClaims{
+		Audience:  totpChallengeAudience,
+		Subject:   username,
+		Id:        uuid.NewString(),
+		ExpiresAt: time.Now().Add(s.challengeTTL).Unix(),
+		IssuedAt:  time.Now().Unix(),
+	}).SignedString([]byte(s.signKey))
+	if err != nil {
+		log.Errorf("%s/newChallengeToken error sign claims: %s", authServicePrefixLog, err)
+		return "", ErrCannotCreateToken
+	}
+	return token, nil
+}
+
+func (s *authService) parseChallengeToken(token string) (*jwt.StandardClaims, error) {
+	t, err := jwt.ParseWithClaims(token, &jwt.StandardClaims{}, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %s", t.Header["alg"])
+		}
+		return []byte(s.signKey), nil
+	})
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	claims, ok := t.Claims.(*jwt.StandardClaims)
+	if !ok || !t.Valid || !claims.VerifyAudience(totpChallengeAudience, true) || claims.Id == "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// newRecoveryCodes returns codes for user in form "abcd-efgh" and their hashes for storage
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+	b := make([]byte, recoveryCodeSize)
+	for i := 0; i < recoveryCodesCount; i++ {
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+		codes = append(codes, code[:recoveryCodeDivider]+"-"+code[recoveryCodeDivider:])
+		hashes = append(hashes, hashToken(code))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(code, "-", ""))
+}
@@ -44,3 +44,22 @@ func (s *userService) GetUserByUsername(ctx context.Context, username string) (p
 	}
 	return user, nil
 }
+
+func (s *userService) UpdateRole(ctx context.Context, input UserUpdateRoleInput) error {
+	if !IsValidRole(input.Role) {
+		return ErrInvalidRole
+	}
+	err := s.userRepo.UpdateRole(ctx, input.Username, input.Role)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		log.Errorf("%s/UpdateRole error update user role: %s", userServicePrefixLog, err)
+		return ErrCannotUpdateUser
+	}
+	return nil
+}
+
+func IsValidRole(role string) bool {
+	return role == RoleUser || role == RoleModerator || role == RoleAdmin
+}
@@ -0,0 +1,2 @@
+alter table public.user
+    add column if not exists suspended_at timestamptz;
@@ -0,0 +1,13 @@
+create table if not exists public.access_token
+(
+    id           serial primary key,
+    token_id     varchar unique not null,
+    username     varchar        not null references public.user (username) on delete cascade on update cascade,
+    name         varchar        not null,
+    token_hash   varchar unique not null,
+    scopes       varchar[]      not null,
+    expires_at   timestamptz,
+    last_used_at timestamptz,
+    created_at   timestamptz    not null default now()
+);
+create index if not exists access_token_username_idx on public.access_token (username);
@@ -0,0 +1,4 @@
+alter table public.reaction
+    drop constraint if exists reaction_post_id_username_key;
+alter table public.reaction
+    add constraint reaction_post_id_username_reaction_key unique (post_id, username, reaction);
@@ -0,0 +1,10 @@
+-- у пользователя остается только последняя реакция на пост
+delete from public.reaction r
+    using public.reaction newer
+where r.post_id = newer.post_id
+  and r.username = newer.username
+  and r.id < newer.id;
+alter table public.reaction
+    drop constraint if exists reaction_post_id_username_reaction_key;
+alter table public.reaction
+    add constraint reaction_post_id_username_key unique (post_id, username);
@@ -0,0 +1,4 @@
+alter table public.reaction
+    drop constraint if exists reaction_post_id_username_reaction_key;
+alter table public.reaction
+    drop column if exists username;
\ No newline at end of file
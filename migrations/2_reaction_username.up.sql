@@ -0,0 +1,4 @@
+alter table public.reaction
+    add column if not exists username varchar references public.user (username) on delete cascade on update cascade;
+alter table public.reaction
+    add constraint reaction_post_id_username_reaction_key unique (post_id, username, reaction);
\ No newline at end of file
@@ -0,0 +1,2 @@
+alter table public.comment
+    drop column if exists parent_id;
\ No newline at end of file
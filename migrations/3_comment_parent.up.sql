@@ -0,0 +1,2 @@
+alter table public.comment
+    add column if not exists parent_id varchar references public.comment (comment_id) on delete cascade;
\ No newline at end of file
@@ -0,0 +1,12 @@
+alter table public.user
+    drop column if exists created_at,
+    drop column if exists updated_at;
+alter table public.post
+    drop column if exists created_at,
+    drop column if exists updated_at;
+alter table public.comment
+    drop column if exists created_at,
+    drop column if exists updated_at;
+alter table public.reaction
+    drop column if exists created_at,
+    drop column if exists updated_at;
\ No newline at end of file
@@ -0,0 +1 @@
+drop table if exists public.follow;
\ No newline at end of file
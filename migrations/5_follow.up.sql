@@ -0,0 +1,10 @@
+create table if not exists public.follow
+(
+    id         serial primary key,
+    follower   varchar     not null references public.user (username) on delete cascade on update cascade,
+    followee   varchar     not null references public.user (username) on delete cascade on update cascade,
+    created_at timestamptz not null default now(),
+    unique (follower, followee),
+    check (follower <> followee)
+);
+create index if not exists follow_followee_idx on public.follow (followee);
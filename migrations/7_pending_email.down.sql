@@ -0,0 +1,2 @@
+alter table public.user
+    drop column if exists pending_email;
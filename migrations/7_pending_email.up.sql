@@ -0,0 +1,2 @@
+alter table public.user
+    add column if not exists pending_email varchar;
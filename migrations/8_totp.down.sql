@@ -0,0 +1,4 @@
+alter table public.user
+    drop column if exists totp_secret,
+    drop column if exists totp_enabled,
+    drop column if exists totp_recovery_codes;
@@ -0,0 +1,4 @@
+alter table public.user
+    add column if not exists totp_secret         varchar,
+    add column if not exists totp_enabled        boolean   not null default false,
+    add column if not exists totp_recovery_codes varchar[] not null default '{}';
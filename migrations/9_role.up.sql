@@ -0,0 +1,4 @@
+-- первого администратора назначают вручную: update public.user set role = 'admin' where username = ...
+alter table public.user
+    add column if not exists role varchar not null default 'user'
+        constraint user_role_check check (role in ('user', 'moderator', 'admin'));
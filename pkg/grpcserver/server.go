@@ -3,10 +3,12 @@ package grpcserver
 import (
 	"google.golang.org/grpc"
 	"net"
+	"time"
 )
 
 const (
-	defaultAddr = ":44044"
+	defaultAddr            = ":44044"
+	defaultShutdownTimeout = 5 * time.Second
 )
 
 type Server struct {
@@ -40,6 +42,16 @@ func (s *Server) Notify() <-chan error {
 	return s.notify
 }
 
+// Shutdown waits for running calls, streams which are still open after timeout are closed
 func (s *Server) Shutdown() {
-	s.server.GracefulStop()
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(defaultShutdownTimeout):
+		s.server.Stop()
+	}
 }
@@ -0,0 +1,116 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"strings"
+)
+
+const (
+	argon2Prefix = "$argon2id$"
+
+	defaultArgon2Memory      = 64 * 1024 // KiB
+	defaultArgon2Iterations  = 3
+	defaultArgon2Parallelism = 2
+	defaultArgon2SaltLength  = 16
+	defaultArgon2KeyLength   = 32
+)
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// Argon2Hasher hashes passwords with argon2id in PHC string format:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>. Legacy sha256 hashes are still verified
+type Argon2Hasher struct {
+	secret string
+	params argon2Params
+	legacy *Hasher
+}
+
+func NewArgon2Hasher(secret string, opts ...Argon2Option) *Argon2Hasher {
+	h := &Argon2Hasher{
+		secret: secret,
+		params: argon2Params{
+			memory:      defaultArgon2Memory,
+			iterations:  defaultArgon2Iterations,
+			parallelism: defaultArgon2Parallelism,
+		},
+		legacy: NewHasher(secret),
+	}
+	for _, option := range opts {
+		option(h)
+	}
+	return h
+}
+
+func (h *Argon2Hasher) Hash(password string) (string, error) {
+	salt := make([]byte, defaultArgon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("hasher: read random salt: %w", err)
+	}
+	key := h.key(password, salt, h.params, defaultArgon2KeyLength)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2Prefix,
+		argon2.Version,
+		h.params.memory,
+		h.params.iterations,
+		h.params.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *Argon2Hasher) Verify(password, hashedPassword string) bool {
+	if !strings.HasPrefix(hashedPassword, argon2Prefix) {
+		return h.legacy.Verify(password, hashedPassword)
+	}
+	params, salt, key, err := decodeArgon2(hashedPassword)
+	if err != nil {
+		return false
+	}
+	res := h.key(password, salt, params, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, res) == 1
+}
+
+func (h *Argon2Hasher) NeedsRehash(hashedPassword string) bool {
+	if !strings.HasPrefix(hashedPassword, argon2Prefix) {
+		return true
+	}
+	params, _, _, err := decodeArgon2(hashedPassword)
+	return err != nil || params != h.params
+}
+
+func (h *Argon2Hasher) key(password string, salt []byte, p argon2Params, keyLen uint32) []byte {
+	return argon2.IDKey([]byte(h.secret+password), salt, p.iterations, p.memory, p.parallelism, keyLen)
+}
+
+func decodeArgon2(hashedPassword string) (argon2Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 {
+		return argon2Params{}, nil, nil, fmt.Errorf("invalid argon2 hash format")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return argon2Params{}, nil, nil, fmt.Errorf("unsupported argon2 version")
+	}
+	var p argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism); err != nil {
+		return argon2Params{}, nil, nil, fmt.Errorf("invalid argon2 params: %w", err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return argon2Params{}, nil, nil, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return argon2Params{}, nil, nil, fmt.Errorf("invalid argon2 hash: %w", err)
+	}
+	return p, salt, key, nil
+}
@@ -0,0 +1,90 @@
+package hasher
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// дешевые параметры, чтобы тесты не тратили 64 MiB на каждый хэш
+func newTestArgon2Hasher(secret string, opts ...Argon2Option) *Argon2Hasher {
+	return NewArgon2Hasher(secret, append([]Argon2Option{Argon2Memory(1024), Argon2Iterations(1), Argon2Parallelism(1)}, opts...)...)
+}
+
+func TestArgon2Hasher_HashVerify(t *testing.T) {
+	h := newTestArgon2Hasher("secret")
+
+	hashed, err := h.Hash("password")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hashed, "$argon2id$v=19$m=1024,t=1,p=1$"))
+	assert.True(t, h.Verify("password", hashed))
+	assert.False(t, h.Verify("wrong", hashed))
+	assert.False(t, newTestArgon2Hasher("other secret").Verify("password", hashed))
+	assert.False(t, h.NeedsRehash(hashed))
+
+	// соль случайная, поэтому хэши одного пароля различаются
+	other, err := h.Hash("password")
+	assert.NoError(t, err)
+	assert.NotEqual(t, hashed, other)
+}
+
+func TestArgon2Hasher_VerifyInvalid(t *testing.T) {
+	h := newTestArgon2Hasher("secret")
+	hashed, err := h.Hash("password")
+	assert.NoError(t, err)
+	parts := strings.Split(hashed, "$")
+
+	testCases := []struct {
+		testName string
+		hashed   string
+	}{
+		{testName: "empty", hashed: ""},
+		{testName: "missing hash", hashed: strings.Join(parts[:5], "$")},
+		{testName: "unsupported version", hashed: strings.Join([]string{"", parts[1], "v=16", parts[3], parts[4], parts[5]}, "$")},
+		{testName: "invalid params", hashed: strings.Join([]string{"", parts[1], parts[2], "m=x", parts[4], parts[5]}, "$")},
+		{testName: "invalid salt", hashed: strings.Join([]string{"", parts[1], parts[2], parts[3], "!!!", parts[5]}, "$")},
+		{testName: "invalid key", hashed: strings.Join([]string{"", parts[1], parts[2], parts[3], parts[4], "!!!"}, "$")},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			assert.False(t, h.Verify("password", tc.hashed))
+			assert.True(t, h.NeedsRehash(tc.hashed))
+		})
+	}
+}
+
+func TestArgon2Hasher_VerifyLegacy(t *testing.T) {
+	legacy, err := NewHasher("secret").Hash("password")
+	assert.NoError(t, err)
+
+	h := newTestArgon2Hasher("secret")
+	assert.True(t, h.Verify("password", legacy))
+	assert.False(t, h.Verify("wrong", legacy))
+	assert.False(t, newTestArgon2Hasher("other secret").Verify("password", legacy))
+	assert.False(t, h.Verify("password", "no salt"))
+	// старый хэш заменяется при следующем входе
+	assert.True(t, h.NeedsRehash(legacy))
+}
+
+func TestArgon2Hasher_NeedsRehash(t *testing.T) {
+	hashed, err := newTestArgon2Hasher("secret").Hash("password")
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		testName string
+		hasher   *Argon2Hasher
+		expect   bool
+	}{
+		{testName: "same params", hasher: newTestArgon2Hasher("secret"), expect: false},
+		{testName: "memory changed", hasher: newTestArgon2Hasher("secret", Argon2Memory(2048)), expect: true},
+		{testName: "iterations changed", hasher: newTestArgon2Hasher("secret", Argon2Iterations(2)), expect: true},
+		{testName: "parallelism changed", hasher: newTestArgon2Hasher("secret", Argon2Parallelism(2)), expect: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			assert.Equal(t, tc.expect, tc.hasher.NeedsRehash(hashed))
+			// старые параметры берутся из хэша, поэтому проверка пароля работает до замены
+			assert.True(t, tc.hasher.Verify("password", hashed))
+		})
+	}
+}
@@ -2,6 +2,7 @@ package hasher
 
 import (
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"fmt"
 	"github.com/google/uuid"
@@ -9,10 +10,13 @@ import (
 )
 
 type PasswordHasher interface {
-	Hash(password string) string
+	Hash(password string) (string, error)
 	Verify(password, hashedPassword string) bool
+	// NeedsRehash reports that hashed password was made by outdated algorithm or params and should be replaced with Hash result
+	NeedsRehash(hashedPassword string) bool
 }
 
+// Hasher is a legacy sha256 hasher with format <hash>:<salt>. Use Argon2Hasher for new passwords
 type Hasher struct {
 	secret string
 }
@@ -21,15 +25,26 @@ func NewHasher(secret string) *Hasher {
 	return &Hasher{secret: secret}
 }
 
-func (h *Hasher) Hash(password string) string {
-	salt := hex.EncodeToString([]byte(uuid.NewString()))
+func (h *Hasher) Hash(password string) (string, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", fmt.Errorf("hasher: generate salt: %w", err)
+	}
+	salt := hex.EncodeToString([]byte(id.String()))
 	res := sha256.Sum256([]byte(salt + h.secret + password))
-	return fmt.Sprintf("%x:%s", res, salt)
+	return fmt.Sprintf("%x:%s", res, salt), nil
 }
 
 func (h *Hasher) Verify(password, hashedPassword string) bool {
 	data := strings.Split(hashedPassword, ":")
+	if len(data) != 2 {
+		return false
+	}
 	key, salt := data[0], data[1]
 	res := sha256.Sum256([]byte(salt + h.secret + password))
-	return key == fmt.Sprintf("%x", res)
+	return subtle.ConstantTimeCompare([]byte(key), []byte(fmt.Sprintf("%x", res))) == 1
+}
+
+func (h *Hasher) NeedsRehash(hashedPassword string) bool {
+	return false
 }
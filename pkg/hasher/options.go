@@ -0,0 +1,22 @@
+package hasher
+
+type Argon2Option func(h *Argon2Hasher)
+
+// Argon2Memory sets memory cost in KiB
+func Argon2Memory(memory uint32) Argon2Option {
+	return func(h *Argon2Hasher) {
+		h.params.memory = memory
+	}
+}
+
+func Argon2Iterations(iterations uint32) Argon2Option {
+	return func(h *Argon2Hasher) {
+		h.params.iterations = iterations
+	}
+}
+
+func Argon2Parallelism(parallelism uint8) Argon2Option {
+	return func(h *Argon2Hasher) {
+		h.params.parallelism = parallelism
+	}
+}
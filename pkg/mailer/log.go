@@ -0,0 +1,45 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// LogMailer writes messages to writer (file or stdout) instead of sending them. Useful for local development
+type LogMailer struct {
+	mu   sync.Mutex
+	from string
+	w    io.Writer
+}
+
+func NewLogMailer(w io.Writer, opts ...Option) *LogMailer {
+	m := &LogMailer{
+		from: defaultFrom,
+		w:    w,
+	}
+	for _, option := range opts {
+		option(m)
+	}
+	return m
+}
+
+// NewFileMailer appends messages to file by path, creating it if needed
+func NewFileMailer(path string, opts ...Option) (*LogMailer, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open mail file: %w", err)
+	}
+	return NewLogMailer(f, opts...), nil
+}
+
+func (m *LogMailer) Send(_ context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := fmt.Fprintf(m.w, "Date: %s\nFrom: %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC1123Z), m.from, msg.To, msg.Subject, msg.Body)
+	return err
+}
@@ -0,0 +1,11 @@
+package mailer
+
+const defaultFrom = "no-reply@localhost"
+
+type Option func(m *LogMailer)
+
+func From(from string) Option {
+	return func(m *LogMailer) {
+		m.from = from
+	}
+}
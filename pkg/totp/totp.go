@@ -0,0 +1,82 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Parameters are fixed to authenticator apps defaults: SHA1, 6 digits, 30 seconds period (RFC 6238)
+const (
+	digits     = 6
+	period     = 30
+	secretSize = 20
+	// codes of neighbour periods are accepted because of clock drift
+	defaultSkew = 1
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns random base32 encoded secret
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return b32.EncodeToString(b), nil
+}
+
+// Code returns code for the secret at time t
+func Code(secret string, t time.Time) (string, error) {
+	key, err := b32.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("decode totp secret: %w", err)
+	}
+	return hotp(key, uint64(t.Unix()/period)), nil
+}
+
+// Validate checks code for the secret at time t
+func Validate(secret, code string, t time.Time) bool {
+	key, err := b32.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != digits {
+		return false
+	}
+	counter := t.Unix() / period
+	for i := -defaultSkew; i <= defaultSkew; i++ {
+		if hmac.Equal([]byte(hotp(key, uint64(counter+int64(i)))), []byte(code)) {
+			return true
+		}
+	}
+	return false
+}
+
+// URI returns otpauth uri for authenticator apps (usually shown as qr code)
+func URI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(digits))
+	params.Set("period", fmt.Sprint(period))
+	// authenticator apps do not decode "+" as space
+	return "otpauth://totp/" + label + "?" + strings.ReplaceAll(params.Encode(), "+", "%20")
+}
+
+// hotp is RFC 4226 code with dynamic truncation
+func hotp(key []byte, counter uint64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", digits, value%1000000)
+}
@@ -0,0 +1,97 @@
+package totp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// base32 of ASCII "12345678901234567890" - SHA1 seed of RFC 6238 test vectors
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// RFC 6238 appendix B, SHA1. Коды из 6 цифр - последние 6 цифр кодов RFC
+	testCases := []struct {
+		unix   int64
+		expect string
+	}{
+		{unix: 59, expect: "287082"},
+		{unix: 1111111109, expect: "081804"},
+		{unix: 1111111111, expect: "050471"},
+		{unix: 1234567890, expect: "005924"},
+		{unix: 2000000000, expect: "279037"},
+		{unix: 20000000000, expect: "353130"},
+	}
+	for _, tc := range testCases {
+		t.Run(time.Unix(tc.unix, 0).UTC().Format(time.RFC3339), func(t *testing.T) {
+			code, err := Code(rfcSecret, time.Unix(tc.unix, 0))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expect, code)
+			assert.True(t, Validate(rfcSecret, tc.expect, time.Unix(tc.unix, 0)))
+		})
+	}
+}
+
+func TestHotp(t *testing.T) {
+	// RFC 4226 appendix D
+	expect := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, code := range expect {
+		assert.Equal(t, code, hotp([]byte("12345678901234567890"), uint64(counter)))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, err := Code(rfcSecret, now)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		testName string
+		secret   string
+		code     string
+		t        time.Time
+		expect   bool
+	}{
+		{testName: "same period", secret: rfcSecret, code: code, t: now, expect: true},
+		{testName: "previous period", secret: rfcSecret, code: code, t: now.Add(period * time.Second), expect: true},
+		{testName: "next period", secret: rfcSecret, code: code, t: now.Add(-period * time.Second), expect: true},
+		{testName: "two periods later", secret: rfcSecret, code: code, t: now.Add(2 * period * time.Second), expect: false},
+		{testName: "two periods earlier", secret: rfcSecret, code: code, t: now.Add(-2 * period * time.Second), expect: false},
+		{testName: "lower case secret", secret: "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", code: code, t: now, expect: true},
+		{testName: "wrong code", secret: rfcSecret, code: "000000", t: now, expect: false},
+		{testName: "short code", secret: rfcSecret, code: code[:5], t: now, expect: false},
+		{testName: "invalid secret", secret: "not base32!", code: code, t: now, expect: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			assert.Equal(t, tc.expect, Validate(tc.secret, tc.code, tc.t))
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.NoError(t, err)
+	key, err := b32.DecodeString(secret)
+	assert.NoError(t, err)
+	assert.Len(t, key, secretSize)
+
+	other, err := GenerateSecret()
+	assert.NoError(t, err)
+	assert.NotEqual(t, secret, other)
+}
+
+func TestURI(t *testing.T) {
+	u, err := url.Parse(URI("API for SN", "vasek", rfcSecret))
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/API for SN:vasek", u.Path)
+	assert.NotContains(t, u.RawQuery, "+")
+	query := u.Query()
+	assert.Equal(t, rfcSecret, query.Get("secret"))
+	assert.Equal(t, "API for SN", query.Get("issuer"))
+	assert.Equal(t, "6", query.Get("digits"))
+	assert.Equal(t, "30", query.Get("period"))
+}
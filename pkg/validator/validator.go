@@ -4,11 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"github.com/go-playground/validator/v10"
-	"net"
-	"net/smtp"
 	"reflect"
 	"regexp"
-	"strings"
 )
 
 const (
@@ -54,7 +51,7 @@ func validateError(err validator.FieldError) error {
 	case "username":
 		return errors.New("field username can only consist of lower Latin characters, numbers and underscore symbol. Min length is 3, max: 32")
 	case "email":
-		return errors.New("field email is incorrect. Make sure that you entered the email correctly and it exists")
+		return errors.New("field email is incorrect. Make sure that you entered the email correctly")
 	case "reaction":
 		return errors.New("field reaction can only consist of lower Latin characters. Min length is 1, max: 16")
 	default:
@@ -70,38 +67,9 @@ func reactionValidate(fl validator.FieldLevel) bool {
 	return validate(fl, reactionRegex)
 }
 
-// Проверяем почту сначала через регулярку (похожа на настоящую), потом ее реальное существование через smtp
+// Проверяем только синтаксис почты, реальное существование подтверждается письмом со ссылкой
 func emailValidate(fl validator.FieldLevel) bool {
-	if fl.Field().Kind() != reflect.String {
-		return false
-	}
-	email := fl.Field().String()
-
-	if !emailRegex.MatchString(email) {
-		return false
-	}
-
-	domain := email[strings.LastIndex(email, "@")+1:]
-	mxRecords, err := net.LookupMX(domain)
-	if err != nil || len(mxRecords) == 0 {
-		return false
-	}
-	smtpServer := mxRecords[0].Host + ":25"
-	client, err := smtp.Dial(smtpServer)
-	if err != nil {
-		return false
-	}
-	defer func() { _ = client.Close() }()
-	if err = client.Hello("example.com"); err != nil {
-		return false
-	}
-	if err = client.Mail("test@example.com"); err != nil {
-		return false
-	}
-	if err = client.Rcpt(email); err != nil {
-		return false
-	}
-	return true
+	return validate(fl, emailRegex)
 }
 
 func validate(fl validator.FieldLevel, reg *regexp.Regexp) bool {
@@ -110,3 +78,8 @@ func validate(fl validator.FieldLevel, reg *regexp.Regexp) bool {
 	}
 	return reg.MatchString(fl.Field().String())
 }
+
+// IsValidReaction checks reaction the same way as "reaction" tag, for inputs which are not validated by struct tags
+func IsValidReaction(reaction string) bool {
+	return reactionRegex.MatchString(reaction)
+}
@@ -0,0 +1,262 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             v5.27.1
+// source: reaction/reaction.proto
+
+package reaction
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	Reaction_CreateReaction_FullMethodName     = "/reaction.Reaction/CreateReaction"
+	Reaction_GetReaction_FullMethodName        = "/reaction.Reaction/GetReaction"
+	Reaction_ListReactions_FullMethodName      = "/reaction.Reaction/ListReactions"
+	Reaction_GetReactionSummary_FullMethodName = "/reaction.Reaction/GetReactionSummary"
+	Reaction_DeleteReaction_FullMethodName     = "/reaction.Reaction/DeleteReaction"
+)
+
+// ReactionClient is the client API for Reaction service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ReactionClient interface {
+	CreateReaction(ctx context.Context, in *CreateReactionRequest, opts ...grpc.CallOption) (*CreateReactionResponse, error)
+	GetReaction(ctx context.Context, in *GetReactionRequest, opts ...grpc.CallOption) (*GetReactionResponse, error)
+	ListReactions(ctx context.Context, in *ListReactionsRequest, opts ...grpc.CallOption) (*ListReactionsResponse, error)
+	GetReactionSummary(ctx context.Context, in *GetReactionSummaryRequest, opts ...grpc.CallOption) (*GetReactionSummaryResponse, error)
+	DeleteReaction(ctx context.Context, in *DeleteReactionRequest, opts ...grpc.CallOption) (*DeleteReactionResponse, error)
+}
+
+type reactionClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewReactionClient(cc grpc.ClientConnInterface) ReactionClient {
+	return &reactionClient{cc}
+}
+
+func (c *reactionClient) CreateReaction(ctx context.Context, in *CreateReactionRequest, opts ...grpc.CallOption) (*CreateReactionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateReactionResponse)
+	err := c.cc.Invoke(ctx, Reaction_CreateReaction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reactionClient) GetReaction(ctx context.Context, in *GetReactionRequest, opts ...grpc.CallOption) (*GetReactionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetReactionResponse)
+	err := c.cc.Invoke(ctx, Reaction_GetReaction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reactionClient) ListReactions(ctx context.Context, in *ListReactionsRequest, opts ...grpc.CallOption) (*ListReactionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListReactionsResponse)
+	err := c.cc.Invoke(ctx, Reaction_ListReactions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reactionClient) GetReactionSummary(ctx context.Context, in *GetReactionSummaryRequest, opts ...grpc.CallOption) (*GetReactionSummaryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetReactionSummaryResponse)
+	err := c.cc.Invoke(ctx, Reaction_GetReactionSummary_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reactionClient) DeleteReaction(ctx context.Context, in *DeleteReactionRequest, opts ...grpc.CallOption) (*DeleteReactionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteReactionResponse)
+	err := c.cc.Invoke(ctx, Reaction_DeleteReaction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReactionServer is the server API for Reaction service.
+// All implementations must embed UnimplementedReactionServer
+// for forward compatibility
+type ReactionServer interface {
+	CreateReaction(context.Context, *CreateReactionRequest) (*CreateReactionResponse, error)
+	GetReaction(context.Context, *GetReactionRequest) (*GetReactionResponse, error)
+	ListReactions(context.Context, *ListReactionsRequest) (*ListReactionsResponse, error)
+	GetReactionSummary(context.Context, *GetReactionSummaryRequest) (*GetReactionSummaryResponse, error)
+	DeleteReaction(context.Context, *DeleteReactionRequest) (*DeleteReactionResponse, error)
+	mustEmbedUnimplementedReactionServer()
+}
+
+// UnimplementedReactionServer must be embedded to have forward compatible implementations.
+type UnimplementedReactionServer struct {
+}
+
+func (UnimplementedReactionServer) CreateReaction(context.Context, *CreateReactionRequest) (*CreateReactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateReaction not implemented")
+}
+func (UnimplementedReactionServer) GetReaction(context.Context, *GetReactionRequest) (*GetReactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetReaction not implemented")
+}
+func (UnimplementedReactionServer) ListReactions(context.Context, *ListReactionsRequest) (*ListReactionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListReactions not implemented")
+}
+func (UnimplementedReactionServer) GetReactionSummary(context.Context, *GetReactionSummaryRequest) (*GetReactionSummaryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetReactionSummary not implemented")
+}
+func (UnimplementedReactionServer) DeleteReaction(context.Context, *DeleteReactionRequest) (*DeleteReactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteReaction not implemented")
+}
+func (UnimplementedReactionServer) mustEmbedUnimplementedReactionServer() {}
+
+// UnsafeReactionServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ReactionServer will
+// result in compilation errors.
+type UnsafeReactionServer interface {
+	mustEmbedUnimplementedReactionServer()
+}
+
+func RegisterReactionServer(s grpc.ServiceRegistrar, srv ReactionServer) {
+	s.RegisterService(&Reaction_ServiceDesc, srv)
+}
+
+func _Reaction_CreateReaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateReactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReactionServer).CreateReaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Reaction_CreateReaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReactionServer).CreateReaction(ctx, req.(*CreateReactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Reaction_GetReaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetReactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReactionServer).GetReaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Reaction_GetReaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReactionServer).GetReaction(ctx, req.(*GetReactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Reaction_ListReactions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListReactionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReactionServer).ListReactions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Reaction_ListReactions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReactionServer).ListReactions(ctx, req.(*ListReactionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Reaction_GetReactionSummary_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetReactionSummaryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReactionServer).GetReactionSummary(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Reaction_GetReactionSummary_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReactionServer).GetReactionSummary(ctx, req.(*GetReactionSummaryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Reaction_DeleteReaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteReactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReactionServer).DeleteReaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Reaction_DeleteReaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReactionServer).DeleteReaction(ctx, req.(*DeleteReactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Reaction_ServiceDesc is the grpc.ServiceDesc for Reaction service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Reaction_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "reaction.Reaction",
+	HandlerType: (*ReactionServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateReaction",
+			Handler:    _Reaction_CreateReaction_Handler,
+		},
+		{
+			MethodName: "GetReaction",
+			Handler:    _Reaction_GetReaction_Handler,
+		},
+		{
+			MethodName: "ListReactions",
+			Handler:    _Reaction_ListReactions_Handler,
+		},
+		{
+			MethodName: "GetReactionSummary",
+			Handler:    _Reaction_GetReactionSummary_Handler,
+		},
+		{
+			MethodName: "DeleteReaction",
+			Handler:    _Reaction_DeleteReaction_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "reaction/reaction.proto",
+}